@@ -29,6 +29,21 @@ type Store interface {
 	Flush() error
 }
 
+// Stats holds store usage counters
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+	Entries     int
+	Bytes       int64
+}
+
+// Statistics is implemented by stores exposing usage counters
+type Statistics interface {
+	Stats() Stats
+}
+
 const (
 	// DEFAULT stores value according default cache value
 	DEFAULT = time.Duration(0)
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package bounded
+
+import (
+	"context"
+
+	api "github.com/scraly/go.common/pkg/cache"
+)
+
+// EvictionPolicy defines the algorithm used to select entries to evict
+type EvictionPolicy int
+
+const (
+	// LRU evicts the least recently used entry
+	LRU EvictionPolicy = iota
+	// TinyLFU uses a W-TinyLFU admission window in front of a segmented LRU,
+	// evicting entries according to their estimated access frequency
+	TinyLFU
+)
+
+// EvictionReason describes why an entry has been removed from the store
+type EvictionReason int
+
+const (
+	// Capacity is used when the entry has been evicted to respect the store bounds
+	Capacity EvictionReason = iota
+	// Expired is used when the entry has been removed after its expiration
+	Expired
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case Capacity:
+		return "capacity"
+	case Expired:
+		return "expired"
+	}
+	return "unknown"
+}
+
+// EvictionFunc is called each time an entry is evicted from the store
+type EvictionFunc func(key string, reason EvictionReason)
+
+type maxEntriesKey struct{}
+type maxBytesKey struct{}
+type policyKey struct{}
+type onEvictionKey struct{}
+
+// MaxEntries sets the maximum number of entries held by the store
+func MaxEntries(n int) api.Option {
+	return withValue(maxEntriesKey{}, n)
+}
+
+// MaxBytes sets the maximum size of keys and encoded values held by the store
+func MaxBytes(n int64) api.Option {
+	return withValue(maxBytesKey{}, n)
+}
+
+// Policy sets the eviction policy, defaults to LRU
+func Policy(p EvictionPolicy) api.Option {
+	return withValue(policyKey{}, p)
+}
+
+// OnEviction registers a callback invoked for each evicted entry
+func OnEviction(fn EvictionFunc) api.Option {
+	return withValue(onEvictionKey{}, fn)
+}
+
+// -----------------------------------------------------------------------------
+
+func withValue(key, value interface{}) api.Option {
+	return func(o *api.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, key, value)
+	}
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package bounded
+
+import (
+	"container/list"
+	"time"
+)
+
+type segment int
+
+const (
+	window segment = iota
+	probation
+	protected
+)
+
+type entry struct {
+	key       string
+	value     []byte
+	hash      uint64
+	cost      int64
+	expiresAt time.Time
+	segment   segment
+	element   *list.Element
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+func (e *entry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// policy tracks entries and elects the next one to evict
+type policy interface {
+	add(e *entry)
+	access(e *entry)
+	remove(e *entry)
+	victim() *entry
+	clear()
+}
+
+// -----------------------------------------------------------------------------
+
+type lruPolicy struct {
+	ll *list.List
+}
+
+func newLRUPolicy() policy {
+	return &lruPolicy{
+		ll: list.New(),
+	}
+}
+
+func (p *lruPolicy) add(e *entry) {
+	e.element = p.ll.PushFront(e)
+}
+
+func (p *lruPolicy) access(e *entry) {
+	p.ll.MoveToFront(e.element)
+}
+
+func (p *lruPolicy) remove(e *entry) {
+	p.ll.Remove(e.element)
+}
+
+func (p *lruPolicy) victim() *entry {
+	if el := p.ll.Back(); el != nil {
+		return el.Value.(*entry)
+	}
+	return nil
+}
+
+func (p *lruPolicy) clear() {
+	p.ll.Init()
+}
+
+// -----------------------------------------------------------------------------
+
+// tinyLFUPolicy implements W-TinyLFU: new entries land in a small LRU window,
+// then compete on estimated frequency to enter a segmented LRU main space
+type tinyLFUPolicy struct {
+	sketch       *countMinSketch
+	lists        [3]*list.List
+	costs        [3]int64
+	windowMax    int64
+	protectedMax int64
+	// candidate is the last entry moved from the window to the main space,
+	// it must win against the main victim to be kept
+	candidate *entry
+}
+
+func newTinyLFUPolicy(capacity int64, sketchWidth int) policy {
+	windowMax := capacity / 100
+	if windowMax < 1 {
+		windowMax = 1
+	}
+
+	p := &tinyLFUPolicy{
+		sketch:       newCountMinSketch(sketchWidth),
+		windowMax:    windowMax,
+		protectedMax: (capacity - windowMax) * 8 / 10,
+	}
+	for i := range p.lists {
+		p.lists[i] = list.New()
+	}
+
+	return p
+}
+
+func (p *tinyLFUPolicy) add(e *entry) {
+	p.sketch.increment(e.hash)
+	p.push(window, e)
+
+	// Overflowing window entries are moved to the probation segment
+	for p.costs[window] > p.windowMax && p.lists[window].Len() > 1 {
+		c := p.back(window)
+		p.move(c, probation)
+		p.candidate = c
+	}
+}
+
+func (p *tinyLFUPolicy) access(e *entry) {
+	p.sketch.increment(e.hash)
+
+	switch e.segment {
+	case window, protected:
+		p.lists[e.segment].MoveToFront(e.element)
+	case probation:
+		if p.candidate == e {
+			p.candidate = nil
+		}
+		p.move(e, protected)
+
+		// Demote least recently used protected entries
+		for p.costs[protected] > p.protectedMax && p.lists[protected].Len() > 1 {
+			p.move(p.back(protected), probation)
+		}
+	}
+}
+
+func (p *tinyLFUPolicy) remove(e *entry) {
+	if p.candidate == e {
+		p.candidate = nil
+	}
+	p.lists[e.segment].Remove(e.element)
+	p.costs[e.segment] -= e.cost
+}
+
+func (p *tinyLFUPolicy) victim() *entry {
+	v := p.back(probation)
+	if v == nil {
+		v = p.back(protected)
+	}
+	if v == nil {
+		return p.back(window)
+	}
+
+	// Admission: the candidate is kept only if it is used more often than the victim
+	if c := p.candidate; c != nil {
+		p.candidate = nil
+		if c != v && p.sketch.estimate(c.hash) <= p.sketch.estimate(v.hash) {
+			return c
+		}
+	}
+
+	return v
+}
+
+func (p *tinyLFUPolicy) clear() {
+	for i := range p.lists {
+		p.lists[i].Init()
+		p.costs[i] = 0
+	}
+	p.candidate = nil
+}
+
+func (p *tinyLFUPolicy) push(s segment, e *entry) {
+	e.segment = s
+	e.element = p.lists[s].PushFront(e)
+	p.costs[s] += e.cost
+}
+
+func (p *tinyLFUPolicy) move(e *entry, s segment) {
+	p.lists[e.segment].Remove(e.element)
+	p.costs[e.segment] -= e.cost
+	p.push(s, e)
+}
+
+func (p *tinyLFUPolicy) back(s segment) *entry {
+	if el := p.lists[s].Back(); el != nil {
+		return el.Value.(*entry)
+	}
+	return nil
+}
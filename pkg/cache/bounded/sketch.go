@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package bounded
+
+const (
+	sketchDepth    = 4
+	sketchMinWidth = 16
+	sketchMaxWidth = 1 << 22
+	// sketchRatio is the number of counters per row for each tracked entry
+	sketchRatio = 4
+	counterMax  = 15
+)
+
+var sketchSeeds = [sketchDepth]uint64{
+	0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325,
+}
+
+// countMinSketch is a probabilistic frequency estimator with 4-bit saturating
+// counters, periodically halved to let old popularity decay
+type countMinSketch struct {
+	rows      [sketchDepth][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+func newCountMinSketch(capacity int) *countMinSketch {
+	width := sketchMinWidth
+	for width < sketchRatio*capacity && width < sketchMaxWidth {
+		width <<= 1
+	}
+
+	s := &countMinSketch{
+		mask:    uint64(width - 1),
+		resetAt: 10 * width / sketchRatio,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+
+	return s
+}
+
+func (s *countMinSketch) increment(h uint64) {
+	for i := range s.rows {
+		idx := s.index(h, i)
+		if s.rows[i][idx] < counterMax {
+			s.rows[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+func (s *countMinSketch) estimate(h uint64) uint8 {
+	min := uint8(counterMax)
+	for i := range s.rows {
+		if v := s.rows[i][s.index(h, i)]; v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+func (s *countMinSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+func (s *countMinSketch) index(h uint64, row int) uint64 {
+	h ^= sketchSeeds[row]
+	h *= 0x9e3779b97f4a7c15
+	h ^= h >> 32
+	return h & s.mask
+}
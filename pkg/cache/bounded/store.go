@@ -0,0 +1,296 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package bounded
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	api "github.com/scraly/go.common/pkg/cache"
+	"github.com/scraly/go.common/pkg/storage/codec/msgpack"
+)
+
+const (
+	// DefaultMaxEntries is used when neither MaxEntries nor MaxBytes is set
+	DefaultMaxEntries = 10000
+
+	// averageEntrySize is used to size the frequency sketch of byte bounded stores
+	averageEntrySize = 64
+)
+
+type boundedStore struct {
+	sync.Mutex
+
+	opts       api.Options
+	maxEntries int
+	maxBytes   int64
+	policyType EvictionPolicy
+	onEviction EvictionFunc
+
+	items  map[string]*entry
+	policy policy
+	bytes  int64
+	stats  api.Stats
+}
+
+// NewCacheStore initializes a bounded in-memory cache
+func NewCacheStore(opts ...api.Option) api.Store {
+	options := api.Options{
+		// default to msgpack codec
+		Codec:   msgpack.NewCodec(),
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	s := &boundedStore{
+		opts: options,
+	}
+	if v, ok := options.Context.Value(maxEntriesKey{}).(int); ok {
+		s.maxEntries = v
+	}
+	if v, ok := options.Context.Value(maxBytesKey{}).(int64); ok {
+		s.maxBytes = v
+	}
+	if v, ok := options.Context.Value(policyKey{}).(EvictionPolicy); ok {
+		s.policyType = v
+	}
+	if v, ok := options.Context.Value(onEvictionKey{}).(EvictionFunc); ok {
+		s.onEviction = v
+	}
+	if s.maxEntries <= 0 && s.maxBytes <= 0 {
+		s.maxEntries = DefaultMaxEntries
+	}
+
+	return s
+}
+
+func init() {
+	api.Register("bounded", NewCacheStore)
+}
+
+// -----------------------------------------------------------------------------
+func (s *boundedStore) Name() string {
+	return "bounded"
+}
+
+func (s *boundedStore) Connect() error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.items = map[string]*entry{}
+
+	switch s.policyType {
+	case TinyLFU:
+		if s.maxBytes > 0 {
+			s.policy = newTinyLFUPolicy(s.maxBytes, int(s.maxBytes/averageEntrySize))
+		} else {
+			s.policy = newTinyLFUPolicy(int64(s.maxEntries), s.maxEntries)
+		}
+	default:
+		s.policy = newLRUPolicy()
+	}
+
+	// Return no error
+	return nil
+}
+
+func (s *boundedStore) Get(key string, value interface{}) error {
+	s.Lock()
+	e, ok := s.items[key]
+	if !ok {
+		s.stats.Misses++
+		s.Unlock()
+		return api.ErrCacheMiss
+	}
+	if e.expired(time.Now()) {
+		s.removeEntry(e)
+		s.stats.Expirations++
+		s.stats.Misses++
+		s.Unlock()
+		s.notify(e.key, Expired)
+		return api.ErrCacheMiss
+	}
+	s.policy.access(e)
+	s.stats.Hits++
+	payload := e.value
+	s.Unlock()
+
+	// Defer to codec for unmarshalling responsibility
+	return s.opts.Unmarshal(key, payload, value)
+}
+
+func (s *boundedStore) Set(key string, value interface{}, expires time.Duration) error {
+	return s.invoke(key, value, expires, func(*entry) error {
+		return nil
+	})
+}
+
+func (s *boundedStore) Add(key string, value interface{}, expires time.Duration) error {
+	return s.invoke(key, value, expires, func(existing *entry) error {
+		if existing != nil {
+			return api.ErrNotStored
+		}
+		return nil
+	})
+}
+
+func (s *boundedStore) Replace(key string, value interface{}, expires time.Duration) error {
+	return s.invoke(key, value, expires, func(existing *entry) error {
+		if existing == nil {
+			return api.ErrNotStored
+		}
+		return nil
+	})
+}
+
+func (s *boundedStore) Delete(key string) error {
+	s.Lock()
+	e, ok := s.items[key]
+	if !ok {
+		s.Unlock()
+		return api.ErrCacheMiss
+	}
+	s.removeEntry(e)
+	if e.expired(time.Now()) {
+		s.stats.Expirations++
+		s.Unlock()
+		s.notify(e.key, Expired)
+		return api.ErrCacheMiss
+	}
+	s.Unlock()
+
+	return nil
+}
+
+func (s *boundedStore) Flush() error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.items = map[string]*entry{}
+	s.policy.clear()
+	s.bytes = 0
+
+	return nil
+}
+
+// Stats returns a snapshot of the store usage counters
+func (s *boundedStore) Stats() api.Stats {
+	s.Lock()
+	defer s.Unlock()
+
+	stats := s.stats
+	stats.Entries = len(s.items)
+	stats.Bytes = s.bytes
+
+	return stats
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *boundedStore) invoke(key string, value interface{}, expires time.Duration, check func(*entry) error) error {
+	switch expires {
+	case api.DEFAULT:
+		expires = s.opts.DefaultExpiration
+	case api.FOREVER:
+		expires = time.Duration(0)
+	}
+
+	b, err := s.opts.Marshal(key, value)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	e := &entry{
+		key:   key,
+		value: b,
+		hash:  hashKey(key),
+		cost:  1,
+	}
+	if expires > 0 {
+		e.expiresAt = now.Add(expires)
+	}
+	if s.maxBytes > 0 {
+		e.cost = e.size()
+		if e.cost > s.maxBytes {
+			return api.ErrNotStored
+		}
+	}
+
+	s.Lock()
+	existing := s.items[key]
+	if existing != nil && existing.expired(now) {
+		existing = nil
+	}
+	if errCheck := check(existing); errCheck != nil {
+		s.Unlock()
+		return errCheck
+	}
+	if old, ok := s.items[key]; ok {
+		s.removeEntry(old)
+	}
+
+	s.items[key] = e
+	s.bytes += e.size()
+	s.policy.add(e)
+
+	// Evict until the store fits its bounds
+	var evicted []*entry
+	for s.overCapacity() {
+		v := s.policy.victim()
+		if v == nil {
+			break
+		}
+		s.removeEntry(v)
+		s.stats.Evictions++
+		evicted = append(evicted, v)
+	}
+	s.Unlock()
+
+	for _, v := range evicted {
+		if v.expired(now) {
+			s.notify(v.key, Expired)
+		} else {
+			s.notify(v.key, Capacity)
+		}
+	}
+
+	return nil
+}
+
+func (s *boundedStore) overCapacity() bool {
+	if s.maxEntries > 0 && len(s.items) > s.maxEntries {
+		return true
+	}
+	return s.maxBytes > 0 && s.bytes > s.maxBytes
+}
+
+func (s *boundedStore) removeEntry(e *entry) {
+	s.policy.remove(e)
+	delete(s.items, e.key)
+	s.bytes -= e.size()
+}
+
+func (s *boundedStore) notify(key string, reason EvictionReason) {
+	if s.onEviction != nil {
+		s.onEviction(key, reason)
+	}
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package bounded_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	api "github.com/scraly/go.common/pkg/cache"
+	"github.com/scraly/go.common/pkg/cache/bounded"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newStore(t *testing.T, opts ...api.Option) api.Store {
+	s, err := api.New("bounded", opts...)
+	require.NoError(t, err, "Store initialization should not raise error")
+	return s
+}
+
+func TestStore_Operations(t *testing.T) {
+	s := newStore(t)
+
+	var value string
+	require.Equal(t, api.ErrCacheMiss, s.Get("key", &value), "Unknown key should be a miss")
+	require.Equal(t, api.ErrNotStored, s.Replace("key", "value", api.DEFAULT), "Replace should fail on unknown key")
+	require.NoError(t, s.Add("key", "value", api.DEFAULT))
+	require.Equal(t, api.ErrNotStored, s.Add("key", "other", api.DEFAULT), "Add should fail on existing key")
+	require.NoError(t, s.Replace("key", "replaced", api.DEFAULT))
+
+	require.NoError(t, s.Get("key", &value))
+	require.Equal(t, "replaced", value)
+
+	require.NoError(t, s.Delete("key"))
+	require.Equal(t, api.ErrCacheMiss, s.Delete("key"), "Delete should fail on unknown key")
+
+	require.NoError(t, s.Set("key", "value", api.FOREVER))
+	require.NoError(t, s.Flush())
+	require.Equal(t, api.ErrCacheMiss, s.Get("key", &value), "Store should be empty after flush")
+}
+
+func TestStore_Expiration(t *testing.T) {
+	var evicted []string
+	s := newStore(t, bounded.OnEviction(func(key string, reason bounded.EvictionReason) {
+		require.Equal(t, bounded.Expired, reason)
+		evicted = append(evicted, key)
+	}))
+
+	require.NoError(t, s.Set("key", "value", 10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+
+	var value string
+	require.Equal(t, api.ErrCacheMiss, s.Get("key", &value), "Expired key should be a miss")
+	require.Equal(t, []string{"key"}, evicted)
+	require.NoError(t, s.Add("key", "value", api.DEFAULT), "Add should succeed on expired key")
+}
+
+func TestStore_DeleteExpired(t *testing.T) {
+	var evicted []string
+	s := newStore(t, bounded.OnEviction(func(key string, reason bounded.EvictionReason) {
+		require.Equal(t, bounded.Expired, reason)
+		evicted = append(evicted, key)
+	}))
+
+	require.NoError(t, s.Set("key", "value", 10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+
+	require.Equal(t, api.ErrCacheMiss, s.Delete("key"), "Expired key should be a miss")
+	require.Equal(t, []string{"key"}, evicted)
+
+	stats := s.(api.Statistics).Stats()
+	require.Equal(t, 0, stats.Entries, "Expired key should be evicted")
+	require.Equal(t, int64(0), stats.Bytes)
+	require.Equal(t, uint64(1), stats.Expirations)
+}
+
+func TestStore_InstrumentedStats(t *testing.T) {
+	s := newStore(t, api.Instrumented(true))
+	require.NoError(t, s.Set("key", "value", api.DEFAULT))
+
+	stats, ok := s.(api.Statistics)
+	require.True(t, ok, "Instrumented store should expose usage counters")
+	require.Equal(t, 1, stats.Stats().Entries)
+}
+
+func TestStore_LRU(t *testing.T) {
+	var evicted []string
+	s := newStore(t, bounded.MaxEntries(3), bounded.OnEviction(func(key string, reason bounded.EvictionReason) {
+		require.Equal(t, bounded.Capacity, reason)
+		evicted = append(evicted, key)
+	}))
+
+	var value int
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Set(fmt.Sprintf("key-%d", i), i, api.DEFAULT))
+	}
+	require.NoError(t, s.Get("key-0", &value), "Refresh key-0")
+	require.NoError(t, s.Set("key-3", 3, api.DEFAULT))
+
+	require.Equal(t, []string{"key-1"}, evicted, "Least recently used key should be evicted")
+	require.Equal(t, api.ErrCacheMiss, s.Get("key-1", &value))
+	require.NoError(t, s.Get("key-0", &value))
+
+	stats := s.(api.Statistics).Stats()
+	require.Equal(t, 3, stats.Entries)
+	require.Equal(t, uint64(1), stats.Evictions)
+	require.Equal(t, uint64(2), stats.Hits)
+	require.Equal(t, uint64(1), stats.Misses)
+}
+
+func TestStore_MaxBytes(t *testing.T) {
+	s := newStore(t, bounded.MaxBytes(64))
+
+	require.Equal(t, api.ErrNotStored, s.Set("big", make([]byte, 128), api.DEFAULT), "Oversized value should be rejected")
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.Set(fmt.Sprintf("key-%d", i), []byte("0123456789"), api.DEFAULT))
+	}
+
+	stats := s.(api.Statistics).Stats()
+	require.True(t, stats.Bytes <= 64, "Store should respect its byte bound")
+	require.True(t, stats.Evictions > 0, "Entries should have been evicted")
+}
+
+func TestStore_TinyLFU(t *testing.T) {
+	s := newStore(t, bounded.MaxEntries(100), bounded.Policy(bounded.TinyLFU))
+
+	var value int
+	for i := 0; i < 100; i++ {
+		require.NoError(t, s.Set(fmt.Sprintf("hot-%d", i), i, api.DEFAULT))
+	}
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 100; i++ {
+			require.NoError(t, s.Get(fmt.Sprintf("hot-%d", i), &value))
+		}
+	}
+
+	// A scan of one-hit keys should not flush frequently used ones
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, s.Set(fmt.Sprintf("scan-%d", i), i, api.DEFAULT))
+	}
+
+	hits := 0
+	for i := 0; i < 100; i++ {
+		if s.Get(fmt.Sprintf("hot-%d", i), &value) == nil {
+			hits++
+		}
+	}
+	require.True(t, hits >= 90, "Frequent keys should survive a scan, got %d hits", hits)
+
+	stats := s.(api.Statistics).Stats()
+	require.Equal(t, 100, stats.Entries)
+}
@@ -39,7 +39,15 @@ func Register(name string, constructor FactoryFunc) {
 // New returns a broker instance
 func New(name string, opts ...Option) (Store, error) {
 	if builder, ok := managers[name]; ok {
+		options := Options{}
+		for _, o := range opts {
+			o(&options)
+		}
+
 		b := builder(opts...)
+		if options.Instrumented {
+			b = NewInstrumentedStore(b)
+		}
 		return b, b.Connect()
 	}
 	return nil, ErrInvalidCacheFactory
@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package cache
+
+import (
+	"time"
+
+	"github.com/scraly/go.common/pkg/tracer"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	cacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cache",
+		Name:      "hits_total",
+		Help:      "Number of cache lookups that found a value.",
+	}, []string{"store"})
+
+	cacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cache",
+		Name:      "misses_total",
+		Help:      "Number of cache lookups that did not find a value.",
+	}, []string{"store"})
+
+	cacheErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cache",
+		Name:      "errors_total",
+		Help:      "Number of cache operations that failed.",
+	}, []string{"store", "operation"})
+
+	cacheDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "cache",
+		Name:      "operation_duration_seconds",
+		Help:      "Latency of cache operations.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"store", "operation"})
+)
+
+func init() {
+	prometheus.MustRegister(cacheHits, cacheMisses, cacheErrors, cacheDuration)
+}
+
+type instrumentedStore struct {
+	store Store
+}
+
+// NewInstrumentedStore decorates the given store to record prometheus metrics
+// and opentracing spans for each operation
+func NewInstrumentedStore(store Store) Store {
+	s := &instrumentedStore{
+		store: store,
+	}
+
+	// Keep usage counters reachable through the decorator
+	if _, ok := store.(Statistics); ok {
+		return &instrumentedStatistics{s}
+	}
+
+	return s
+}
+
+// -----------------------------------------------------------------------------
+func (s *instrumentedStore) Name() string {
+	return s.store.Name()
+}
+
+func (s *instrumentedStore) Connect() error {
+	return s.instrument("connect", s.store.Connect)
+}
+
+func (s *instrumentedStore) Get(key string, value interface{}) error {
+	err := s.instrument("get", func() error {
+		return s.store.Get(key, value)
+	})
+
+	switch err {
+	case nil:
+		cacheHits.WithLabelValues(s.Name()).Inc()
+	case ErrCacheMiss:
+		cacheMisses.WithLabelValues(s.Name()).Inc()
+	}
+
+	return err
+}
+
+func (s *instrumentedStore) Set(key string, value interface{}, expires time.Duration) error {
+	return s.instrument("set", func() error {
+		return s.store.Set(key, value, expires)
+	})
+}
+
+func (s *instrumentedStore) Add(key string, value interface{}, expires time.Duration) error {
+	return s.instrument("add", func() error {
+		return s.store.Add(key, value, expires)
+	})
+}
+
+func (s *instrumentedStore) Replace(key string, value interface{}, expires time.Duration) error {
+	return s.instrument("replace", func() error {
+		return s.store.Replace(key, value, expires)
+	})
+}
+
+func (s *instrumentedStore) Delete(key string) error {
+	return s.instrument("delete", func() error {
+		return s.store.Delete(key)
+	})
+}
+
+func (s *instrumentedStore) Flush() error {
+	return s.instrument("flush", s.store.Flush)
+}
+
+// -----------------------------------------------------------------------------
+
+// instrumentedStatistics forwards the usage counters of the decorated store
+type instrumentedStatistics struct {
+	*instrumentedStore
+}
+
+func (s *instrumentedStatistics) Stats() Stats {
+	return s.store.(Statistics).Stats()
+}
+
+// -----------------------------------------------------------------------------
+
+// instrument traces and measures the operation. Keys are not tagged on the
+// span, they may hold personal data.
+func (s *instrumentedStore) instrument(operation string, fn func() error) error {
+	name := s.Name()
+
+	span := tracer.Tracer().StartSpan("cache."+operation, opentracing.Tags{
+		"cache.store": name,
+	})
+	defer span.Finish()
+	ext.Component.Set(span, "cache")
+
+	start := time.Now()
+	err := fn()
+	cacheDuration.WithLabelValues(name, operation).Observe(time.Since(start).Seconds())
+
+	switch err {
+	case nil:
+	case ErrCacheMiss, ErrNotStored:
+		// Expected outcomes, not failures
+		span.SetTag("cache.result", err.Error())
+	default:
+		cacheErrors.WithLabelValues(name, operation).Inc()
+		ext.Error.Set(span, true)
+		span.LogFields(otlog.Error(err))
+	}
+
+	return err
+}
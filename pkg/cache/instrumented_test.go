@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package cache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/scraly/go.common/pkg/cache/mocks"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	require.NoError(t, c.Write(m), "Metric should be readable")
+	return m.GetCounter().GetValue()
+}
+
+func TestInstrumentedStore_Get(t *testing.T) {
+	backend := &mocks.Store{}
+	backend.On("Name").Return("instrumented-get")
+	backend.On("Get", "hit", mock.Anything).Return(nil)
+	backend.On("Get", "miss", mock.Anything).Return(ErrCacheMiss)
+	backend.On("Get", "fail", mock.Anything).Return(errors.New("connection refused"))
+
+	s := NewInstrumentedStore(backend)
+	require.Equal(t, "instrumented-get", s.Name(), "Name should be delegated")
+
+	var value string
+	require.NoError(t, s.Get("hit", &value))
+	require.Equal(t, ErrCacheMiss, s.Get("miss", &value))
+	require.Error(t, s.Get("fail", &value))
+
+	require.Equal(t, 1.0, counterValue(t, cacheHits.WithLabelValues("instrumented-get")))
+	require.Equal(t, 1.0, counterValue(t, cacheMisses.WithLabelValues("instrumented-get")))
+	require.Equal(t, 1.0, counterValue(t, cacheErrors.WithLabelValues("instrumented-get", "get")))
+	backend.AssertExpectations(t)
+}
+
+func TestInstrumentedStore_ExpectedErrors(t *testing.T) {
+	backend := &mocks.Store{}
+	backend.On("Name").Return("instrumented-add")
+	backend.On("Add", "key", "value", DEFAULT).Return(ErrNotStored)
+	backend.On("Delete", "key").Return(ErrCacheMiss)
+
+	s := NewInstrumentedStore(backend)
+	require.Equal(t, ErrNotStored, s.Add("key", "value", DEFAULT))
+	require.Equal(t, ErrCacheMiss, s.Delete("key"))
+
+	require.Equal(t, 0.0, counterValue(t, cacheErrors.WithLabelValues("instrumented-add", "add")))
+	require.Equal(t, 0.0, counterValue(t, cacheErrors.WithLabelValues("instrumented-add", "delete")))
+	backend.AssertExpectations(t)
+}
+
+type statisticsStore struct {
+	mocks.Store
+}
+
+func (s *statisticsStore) Stats() Stats {
+	return Stats{Hits: 42}
+}
+
+func TestInstrumentedStore_Statistics(t *testing.T) {
+	_, ok := NewInstrumentedStore(&mocks.Store{}).(Statistics)
+	require.False(t, ok, "Statistics should not be exposed when the store has none")
+
+	stats, ok := NewInstrumentedStore(&statisticsStore{}).(Statistics)
+	require.True(t, ok, "Statistics should be forwarded")
+	require.Equal(t, uint64(42), stats.Stats().Hits)
+}
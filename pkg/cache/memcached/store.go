@@ -26,11 +26,14 @@ type memcachedStore struct {
 }
 
 // NewCacheStore initializes a memcached cache
-func NewCacheStore(...api.Option) api.Store {
+func NewCacheStore(opts ...api.Option) api.Store {
 	options := api.Options{
 		// default to msgpack codec
 		Codec: msgpack.NewCodec(),
 	}
+	for _, o := range opts {
+		o(&options)
+	}
 
 	return &memcachedStore{
 		opts: options,
@@ -58,7 +61,7 @@ func (s *memcachedStore) Get(key string, value interface{}) error {
 	if err != nil {
 		return convertMemcacheError(err)
 	}
-	return s.opts.Codec.Unmarshal(item.Value, value)
+	return s.opts.Unmarshal(key, item.Value, value)
 }
 
 func (s *memcachedStore) Set(key string, value interface{}, expires time.Duration) error {
@@ -93,7 +96,7 @@ func (s *memcachedStore) invoke(storeFn func(*memcache.Client, *memcache.Item) e
 		expire = time.Duration(0)
 	}
 
-	b, err := s.opts.Codec.Marshal(value)
+	b, err := s.opts.Marshal(key, value)
 	if err != nil {
 		return err
 	}
@@ -25,12 +25,17 @@ type memoryStore struct {
 	opts api.Options
 }
 
-// NewCacheStore initializes a memcached cache
-func NewCacheStore(...api.Option) api.Store {
+// NewCacheStore initializes a memory cache. Values are held as-is, unless a
+// transformer is configured: they are then encoded with the codec and
+// transformed, so that they are not kept in clear in memory.
+func NewCacheStore(opts ...api.Option) api.Store {
 	options := api.Options{
 		// default to msgpack codec
 		Codec: msgpack.NewCodec(),
 	}
+	for _, o := range opts {
+		o(&options)
+	}
 
 	return &memoryStore{
 		opts: options,
@@ -58,6 +63,15 @@ func (s *memoryStore) Get(key string, value interface{}) error {
 		return api.ErrCacheMiss
 	}
 
+	if s.opts.Transformer != nil {
+		data, ok := val.([]byte)
+		if !ok {
+			// Not written through the transformer, unreadable
+			return api.ErrCacheMiss
+		}
+		return s.opts.Unmarshal(key, data, value)
+	}
+
 	v := reflect.ValueOf(value)
 	if v.Type().Kind() == reflect.Ptr && v.Elem().CanSet() {
 		v.Elem().Set(reflect.ValueOf(val))
@@ -68,12 +82,22 @@ func (s *memoryStore) Get(key string, value interface{}) error {
 }
 
 func (s *memoryStore) Set(key string, value interface{}, expires time.Duration) error {
-	s.Cache.Set(key, value, expires)
+	val, err := s.encode(key, value)
+	if err != nil {
+		return err
+	}
+
+	s.Cache.Set(key, val, expires)
 	return nil
 }
 
 func (s *memoryStore) Add(key string, value interface{}, expires time.Duration) error {
-	err := s.Cache.Add(key, value, expires)
+	val, err := s.encode(key, value)
+	if err != nil {
+		return err
+	}
+
+	err = s.Cache.Add(key, val, expires)
 	if err == cache.ErrKeyExists {
 		return api.ErrNotStored
 	}
@@ -81,7 +105,12 @@ func (s *memoryStore) Add(key string, value interface{}, expires time.Duration)
 }
 
 func (s *memoryStore) Replace(key string, value interface{}, expires time.Duration) error {
-	if err := s.Cache.Replace(key, value, expires); err != nil {
+	val, err := s.encode(key, value)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Cache.Replace(key, val, expires); err != nil {
 		return api.ErrNotStored
 	}
 	return nil
@@ -98,3 +127,13 @@ func (s *memoryStore) Flush() error {
 	s.Cache.Flush()
 	return nil
 }
+
+// -----------------------------------------------------------------------------
+
+// encode returns the value to hold, transformed when a transformer is configured
+func (s *memoryStore) encode(key string, value interface{}) (interface{}, error) {
+	if s.opts.Transformer == nil {
+		return value, nil
+	}
+	return s.opts.Marshal(key, value)
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package memory
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+
+	api "github.com/scraly/go.common/pkg/cache"
+	aestransformer "github.com/scraly/go.common/pkg/storage/value/encrypt/aes"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Transformer(t *testing.T) {
+	block, err := aes.NewCipher([]byte("0123456789abcdef"))
+	require.NoError(t, err)
+
+	s := NewCacheStore(api.Transformer(aestransformer.NewGCMTransformer(block))).(*memoryStore)
+	require.NoError(t, s.Connect())
+
+	require.NoError(t, s.Set("user:1", "john.doe@example.com", api.DEFAULT))
+
+	raw, found := s.Cache.Get("user:1")
+	require.True(t, found)
+	require.IsType(t, []byte{}, raw, "Value should be held encoded")
+	require.False(t, bytes.Contains(raw.([]byte), []byte("john.doe")), "Value should not be held in clear")
+
+	var result string
+	require.NoError(t, s.Get("user:1", &result))
+	require.Equal(t, "john.doe@example.com", result)
+
+	require.Equal(t, api.ErrNotStored, s.Add("user:1", "jane.doe@example.com", api.DEFAULT))
+	require.NoError(t, s.Replace("user:1", "jane.doe@example.com", api.DEFAULT))
+	require.NoError(t, s.Get("user:1", &result))
+	require.Equal(t, "jane.doe@example.com", result)
+
+	s.Cache.Set("user:2", "john.doe@example.com", api.DEFAULT)
+	require.Equal(t, api.ErrCacheMiss, s.Get("user:2", &result), "Value held in clear should be a miss")
+}
+
+func TestStore_NoTransformer(t *testing.T) {
+	s := NewCacheStore().(*memoryStore)
+	require.NoError(t, s.Connect())
+
+	require.NoError(t, s.Set("key", 42, api.DEFAULT))
+
+	raw, _ := s.Cache.Get("key")
+	require.Equal(t, 42, raw, "Value should be held as-is")
+
+	var result int
+	require.NoError(t, s.Get("key", &result))
+	require.Equal(t, 42, result)
+}
@@ -11,10 +11,12 @@
 package cache
 
 import (
+	"context"
 	"crypto/tls"
 	"time"
 
 	"github.com/scraly/go.common/pkg/storage/codec"
+	"github.com/scraly/go.common/pkg/storage/value"
 )
 
 // Options is connection option holder
@@ -22,10 +24,15 @@ type Options struct {
 	Addrs             []string
 	Secure            bool
 	Codec             codec.Codec
+	Transformer       value.Transformer
 	TLSConfig         *tls.Config
 	Username          string
 	Password          string
 	DefaultExpiration time.Duration
+	Instrumented      bool
+	// Other options for implementations of the interface
+	// can be stored in a context
+	Context context.Context
 }
 
 // Option represents default option function
@@ -45,6 +52,15 @@ func Codec(c codec.Codec) Option {
 	}
 }
 
+// Transformer sets the transformer applied to encoded values before they are
+// written to the cache, such as an encryption layer. The cache key is used as
+// authenticated data.
+func Transformer(t value.Transformer) Option {
+	return func(o *Options) {
+		o.Transformer = t
+	}
+}
+
 // Secure communication with the cache manager
 func Secure(b bool) Option {
 	return func(o *Options) {
@@ -79,3 +95,40 @@ func DefaultExpiration(value time.Duration) Option {
 		o.DefaultExpiration = value
 	}
 }
+
+// Instrumented enables metrics and tracing for all store operations
+func Instrumented(b bool) Option {
+	return func(o *Options) {
+		o.Instrumented = b
+	}
+}
+
+// Marshal encodes the value using the codec, then transforms it for storage
+// using the cache key as authenticated data
+func (o Options) Marshal(key string, v interface{}) ([]byte, error) {
+	b, err := o.Codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Transformer == nil {
+		return b, nil
+	}
+
+	return o.Transformer.TransformToStorage(b, value.DefaultContext(key))
+}
+
+// Unmarshal reverts the storage transformation using the cache key as
+// authenticated data, then decodes the value using the codec
+func (o Options) Unmarshal(key string, data []byte, v interface{}) error {
+	if o.Transformer != nil {
+		// Stale values are still readable, they will be rewritten on next update
+		b, _, err := o.Transformer.TransformFromStorage(data, value.DefaultContext(key))
+		if err != nil {
+			return err
+		}
+		data = b
+	}
+
+	return o.Codec.Unmarshal(data, v)
+}
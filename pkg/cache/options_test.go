@@ -0,0 +1,42 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package cache
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+
+	"github.com/scraly/go.common/pkg/storage/codec/json"
+	aestransformer "github.com/scraly/go.common/pkg/storage/value/encrypt/aes"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_MarshalTransformer(t *testing.T) {
+	block, err := aes.NewCipher([]byte("0123456789abcdef"))
+	require.NoError(t, err)
+
+	opts := Options{}
+	for _, o := range []Option{Codec(json.NewCodec()), Transformer(aestransformer.NewGCMTransformer(block))} {
+		o(&opts)
+	}
+
+	payload, err := opts.Marshal("user:1", "john.doe@example.com")
+	require.NoError(t, err, "Encoding should not raise error")
+	require.False(t, bytes.Contains(payload, []byte("john.doe")), "Payload should not be stored in clear")
+
+	var result string
+	require.NoError(t, opts.Unmarshal("user:1", payload, &result), "Decoding should not raise error")
+	require.Equal(t, "john.doe@example.com", result)
+
+	require.Error(t, opts.Unmarshal("user:2", payload, &result), "Payload should be bound to its cache key")
+}
@@ -27,11 +27,14 @@ type redisStore struct {
 }
 
 // NewCacheStore initializes a redis cache
-func NewCacheStore(...api.Option) api.Store {
+func NewCacheStore(opts ...api.Option) api.Store {
 	options := api.Options{
 		// default to msgpack codec
 		Codec: msgpack.NewCodec(),
 	}
+	for _, o := range opts {
+		o(&options)
+	}
 
 	return &redisStore{
 		opts: options,
@@ -102,7 +105,7 @@ func (s *redisStore) Get(key string, value interface{}) error {
 	}
 
 	// Defer to codec for unmarshalling responsibility
-	return s.opts.Codec.Unmarshal(item, value)
+	return s.opts.Unmarshal(key, item, value)
 }
 
 func (s *redisStore) Set(key string, value interface{}, expires time.Duration) error {
@@ -192,7 +195,7 @@ func (s *redisStore) invoke(f func(string, ...interface{}) (interface{}, error),
 		expires = time.Duration(0)
 	}
 
-	b, err := s.opts.Codec.Marshal(value)
+	b, err := s.opts.Marshal(key, value)
 	if err != nil {
 		return err
 	}
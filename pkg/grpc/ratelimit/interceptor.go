@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/scraly/go.common/pkg/ern"
+	"github.com/scraly/go.common/pkg/log"
+	"github.com/scraly/go.common/pkg/ratelimit"
+	"go.uber.org/zap"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// KeyFunc extracts the rate limiting key from the call
+type KeyFunc func(ctx context.Context, fullMethod string) (string, error)
+
+// PeerIP uses the remote peer IP as key
+func PeerIP(ctx context.Context, _ string) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "", fmt.Errorf("ratelimit: Unable to identify remote peer")
+	}
+
+	addr := p.Addr.String()
+	if ip, _, err := net.SplitHostPort(addr); err == nil {
+		addr = ip
+	}
+
+	return fmt.Sprintf("ip:%s", addr), nil
+}
+
+// TenantFromMetadata uses the tenant of the ERN given in the incoming metadata as key
+func TenantFromMetadata(name string) KeyFunc {
+	return func(ctx context.Context, _ string) (string, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md[strings.ToLower(name)]
+		if len(values) == 0 {
+			return "", fmt.Errorf("ratelimit: Missing %s metadata", name)
+		}
+
+		id, err := ern.Parse(values[0])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("tenant:%s", id.Tenant), nil
+	}
+}
+
+// UnaryServerInterceptor limits the unary call rate per key
+func UnaryServerInterceptor(limiter ratelimit.Limiter, keyFunc KeyFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := limit(ctx, limiter, keyFunc, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor limits the stream opening rate per key
+func StreamServerInterceptor(limiter ratelimit.Limiter, keyFunc KeyFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := limit(ss.Context(), limiter, keyFunc, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func limit(ctx context.Context, limiter ratelimit.Limiter, keyFunc KeyFunc, fullMethod string) error {
+	key, err := keyFunc(ctx, fullMethod)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	res, err := limiter.Allow(ctx, key)
+	if err != nil {
+		// Calls are let through when the limiter is unavailable
+		log.For(ctx).Error("Unable to check call rate", zap.String("key", key), zap.Error(err))
+		return nil
+	}
+
+	if !res.Allowed {
+		retryAfter := strconv.FormatInt(int64(math.Ceil(res.RetryAfter.Seconds())), 10)
+		if errHeader := grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter)); errHeader != nil {
+			log.For(ctx).Warn("Unable to set retry-after header", zap.Error(errHeader))
+		}
+		return status.Errorf(codes.ResourceExhausted, "ratelimit: Rate limit exceeded, retry after %ss", retryAfter)
+	}
+
+	return nil
+}
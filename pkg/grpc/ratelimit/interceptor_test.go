@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/scraly/go.common/pkg/ratelimit"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type transportStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("unavailable")
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	limiter, err := ratelimit.NewTokenBucket(ratelimit.PerMinute(1))
+	require.NoError(t, err)
+
+	interceptor := UnaryServerInterceptor(limiter, TenantFromMetadata("X-Tenant"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	call := func(tenant string) (*transportStream, interface{}, error) {
+		stream := &transportStream{}
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-tenant", tenant))
+		resp, err := interceptor(ctx, nil, info, handler)
+		return stream, resp, err
+	}
+
+	_, resp, err := call("ern:12:account:123")
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+
+	stream, _, err := call("ern:12:account:456")
+	require.Equal(t, codes.ResourceExhausted, status.Code(err), "Tenant should be limited")
+	require.Equal(t, []string{"60"}, stream.header.Get("retry-after"))
+
+	_, _, err = call("ern:13:account:123")
+	require.NoError(t, err, "Tenants should be independent")
+
+	_, _, err = call("invalid")
+	require.Equal(t, codes.InvalidArgument, status.Code(err), "Invalid ERN should be rejected")
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	limiter, err := ratelimit.NewSlidingWindow(ratelimit.PerMinute(1))
+	require.NoError(t, err)
+
+	interceptor := StreamServerInterceptor(limiter, PeerIP)
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		return nil
+	}
+
+	call := func(ip string) error {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234}})
+		return interceptor(nil, &serverStream{ctx: ctx}, info, handler)
+	}
+
+	require.NoError(t, call("10.0.0.1"))
+	require.Equal(t, codes.ResourceExhausted, status.Code(call("10.0.0.1")), "Peer should be limited")
+	require.NoError(t, call("10.0.0.2"), "Peers should be independent")
+
+	err = interceptor(nil, &serverStream{ctx: context.Background()}, info, handler)
+	require.Equal(t, codes.InvalidArgument, status.Code(err), "Unknown peer should be rejected")
+}
+
+func TestLimiterUnavailable(t *testing.T) {
+	interceptor := UnaryServerInterceptor(failingLimiter{}, PeerIP)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1")}})
+
+	resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	require.NoError(t, err, "Calls should be let through when the limiter is unavailable")
+	require.Equal(t, "ok", resp)
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
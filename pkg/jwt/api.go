@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package jwt
+
+import (
+	"context"
+	"errors"
+
+	"github.com/scraly/go.common/pkg/keystore/key"
+)
+
+var (
+	// ErrMalformedToken is raised when the token is not a compact JWS
+	ErrMalformedToken = errors.New("jwt: Malformed token")
+	// ErrUnsupportedAlgorithm is raised when the token algorithm is not accepted
+	ErrUnsupportedAlgorithm = errors.New("jwt: Unsupported algorithm")
+	// ErrMissingKeyID is raised when the token header has no kid
+	ErrMissingKeyID = errors.New("jwt: Missing key identifier")
+	// ErrUnknownKey is raised when the token kid is not in the keystore
+	ErrUnknownKey = errors.New("jwt: Unknown signing key")
+	// ErrInvalidSignature is raised when the token signature does not match
+	ErrInvalidSignature = errors.New("jwt: Invalid signature")
+	// ErrMissingExpiry is raised when the token has no exp claim
+	ErrMissingExpiry = errors.New("jwt: Token has no expiration")
+	// ErrTokenExpired is raised when the token exp claim is in the past
+	ErrTokenExpired = errors.New("jwt: Token is expired")
+	// ErrTokenNotYetValid is raised when the token nbf or iat claim is in the future
+	ErrTokenNotYetValid = errors.New("jwt: Token is not valid yet")
+	// ErrInvalidIssuer is raised when the token iss claim is not accepted
+	ErrInvalidIssuer = errors.New("jwt: Invalid issuer")
+	// ErrInvalidAudience is raised when the token aud claim does not contain the expected audience
+	ErrInvalidAudience = errors.New("jwt: Invalid audience")
+	// ErrNoSigningKey is raised when the key source has no private key to sign with
+	ErrNoSigningKey = errors.New("jwt: No signing key available")
+)
+
+// Signer issues compact JWS tokens
+type Signer interface {
+	// Sign serializes the claims as JSON and returns the signed token
+	Sign(ctx context.Context, claims interface{}) (string, error)
+}
+
+// Verifier checks compact JWS tokens
+type Verifier interface {
+	// Verify checks the token signature and registered claims, then decodes the
+	// payload into claims if not nil
+	Verify(ctx context.Context, token string, claims interface{}) error
+}
+
+// KeySource provides the key used to sign tokens
+type KeySource interface {
+	SigningKey(ctx context.Context) (key.Key, error)
+}
+
+// KeySourceFunc adapts a function to the KeySource interface
+type KeySourceFunc func(ctx context.Context) (key.Key, error)
+
+// SigningKey calls f(ctx)
+func (f KeySourceFunc) SigningKey(ctx context.Context) (key.Key, error) {
+	return f(ctx)
+}
+
+// StaticKey returns a key source always returning the given key
+func StaticKey(k key.Key) KeySource {
+	return KeySourceFunc(func(context.Context) (key.Key, error) {
+		return k, nil
+	})
+}
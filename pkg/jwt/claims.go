@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package jwt
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Claims holds the registered claims: RFC 7519 Section 4.1.
+// Embed it in a struct to add private claims.
+type Claims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	Expiry    int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+}
+
+// NewClaims returns claims issued now and valid for the given duration
+func NewClaims(issuer, subject string, ttl time.Duration, audience ...string) Claims {
+	now := time.Now().UTC()
+	return Claims{
+		Issuer:    issuer,
+		Subject:   subject,
+		Audience:  Audience(audience),
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		Expiry:    now.Add(ttl).Unix(),
+	}
+}
+
+// validate checks time based and expected claims
+func (c *Claims) validate(now time.Time, opts *Options) error {
+	leeway := int64(opts.Leeway / time.Second)
+	ts := now.Unix()
+
+	if c.Expiry == 0 && !opts.AllowMissingExpiry {
+		return ErrMissingExpiry
+	}
+	if c.Expiry != 0 && ts > c.Expiry+leeway {
+		return ErrTokenExpired
+	}
+	if c.NotBefore != 0 && ts < c.NotBefore-leeway {
+		return ErrTokenNotYetValid
+	}
+	if c.IssuedAt != 0 && ts < c.IssuedAt-leeway {
+		return ErrTokenNotYetValid
+	}
+
+	if len(opts.Issuers) > 0 && !contains(opts.Issuers, c.Issuer) {
+		return ErrInvalidIssuer
+	}
+	if opts.Audience != "" && !contains(c.Audience, opts.Audience) {
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// Audience is the aud claim, encoded as a string when it holds a single value
+type Audience []string
+
+// MarshalJSON implements json.Marshaler
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var multi []string
+	if err := json.Unmarshal(b, &multi); err != nil {
+		return err
+	}
+	*a = Audience(multi)
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// header is the JOSE header: RFC 7515 Section 4
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// token is a decoded compact JWS
+type token struct {
+	header       header
+	payload      []byte
+	signingInput []byte
+	signature    []byte
+}
+
+func encodeSegment(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func parse(raw string) (*token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	h, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	t := &token{
+		signingInput: []byte(raw[:len(parts[0])+len(parts[1])+1]),
+	}
+	if err := json.Unmarshal(h, &t.header); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if t.payload, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if t.signature, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	return t, nil
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package jwt_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/elliptic"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/scraly/go.common/pkg/jwt"
+	"github.com/scraly/go.common/pkg/keystore"
+	"github.com/scraly/go.common/pkg/keystore/backends/inmemory"
+	"github.com/scraly/go.common/pkg/keystore/key"
+
+	"github.com/stretchr/testify/require"
+)
+
+type customClaims struct {
+	jwt.Claims
+	Scope string `json:"scope"`
+}
+
+func setup(t *testing.T, generator key.Generator) (key.Key, keystore.KeyStore) {
+	ctx := context.Background()
+
+	backend, err := inmemory.New()
+	require.NoError(t, err)
+	ks, err := keystore.New(backend)
+	require.NoError(t, err)
+
+	k, err := ks.Generate(ctx, generator)
+	require.NoError(t, err)
+	require.NoError(t, ks.Add(ctx, k))
+
+	return k, ks
+}
+
+func TestSignAndVerify(t *testing.T) {
+	generators := map[string]key.Generator{
+		"ed25519":        key.Ed25519,
+		"ecp256-sha256":  key.ECDSA(elliptic.P256(), crypto.SHA256),
+		"ecp521-sha512":  key.ECDSA(elliptic.P521(), crypto.SHA512),
+		"rsa2048-sha256": key.RSA(2048, crypto.SHA256),
+	}
+
+	for name, generator := range generators {
+		t.Run(fmt.Sprintf("case=%s", name), func(t *testing.T) {
+			ctx := context.Background()
+			k, ks := setup(t, generator)
+
+			claims := &customClaims{
+				Claims: jwt.NewClaims("issuer", "subject", time.Minute, "api"),
+				Scope:  "read",
+			}
+			token, err := jwt.NewSigner(jwt.StaticKey(k)).Sign(ctx, claims)
+			require.NoError(t, err, "Error should not be raised on signature")
+
+			decoded := &customClaims{}
+			err = jwt.NewVerifier(ks, jwt.WithIssuer("issuer"), jwt.WithAudience("api")).Verify(ctx, token, decoded)
+			require.NoError(t, err, "Error should not be raised on verification")
+			require.Equal(t, claims, decoded, "Claims should be decoded")
+		})
+	}
+}
+
+func TestVerify_Claims(t *testing.T) {
+	ctx := context.Background()
+	k, ks := setup(t, key.Ed25519)
+	signer := jwt.NewSigner(jwt.StaticKey(k))
+	now := time.Now()
+	exp := now.Add(time.Minute).Unix()
+
+	testCases := []struct {
+		name   string
+		claims jwt.Claims
+		opts   []jwt.Option
+		err    error
+	}{
+		{"expired", jwt.Claims{Expiry: now.Add(-2 * time.Minute).Unix()}, nil, jwt.ErrTokenExpired},
+		{"expired within leeway", jwt.Claims{Expiry: now.Add(-30 * time.Second).Unix()}, nil, nil},
+		{"expired without leeway", jwt.Claims{Expiry: now.Add(-30 * time.Second).Unix()}, []jwt.Option{jwt.WithLeeway(0)}, jwt.ErrTokenExpired},
+		{"missing expiry", jwt.Claims{}, nil, jwt.ErrMissingExpiry},
+		{"missing expiry allowed", jwt.Claims{}, []jwt.Option{jwt.AllowMissingExpiry()}, nil},
+		{"not before", jwt.Claims{Expiry: exp, NotBefore: now.Add(2 * time.Minute).Unix()}, nil, jwt.ErrTokenNotYetValid},
+		{"issued in future", jwt.Claims{Expiry: exp, IssuedAt: now.Add(2 * time.Minute).Unix()}, nil, jwt.ErrTokenNotYetValid},
+		{"issuer", jwt.Claims{Expiry: exp, Issuer: "other"}, []jwt.Option{jwt.WithIssuer("issuer")}, jwt.ErrInvalidIssuer},
+		{"audience", jwt.Claims{Expiry: exp, Audience: jwt.Audience{"a", "b"}}, []jwt.Option{jwt.WithAudience("c")}, jwt.ErrInvalidAudience},
+		{"audience list", jwt.Claims{Expiry: exp, Audience: jwt.Audience{"a", "b"}}, []jwt.Option{jwt.WithAudience("b")}, nil},
+		{"algorithms", jwt.Claims{Expiry: exp}, []jwt.Option{jwt.WithAlgorithms("ES256")}, jwt.ErrUnsupportedAlgorithm},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := signer.Sign(ctx, tc.claims)
+			require.NoError(t, err)
+			require.Equal(t, tc.err, jwt.NewVerifier(ks, tc.opts...).Verify(ctx, token, nil))
+		})
+	}
+}
+
+func TestVerify_Rejected(t *testing.T) {
+	ctx := context.Background()
+	k, ks := setup(t, key.ECDSA(elliptic.P256(), crypto.SHA256))
+	verifier := jwt.NewVerifier(ks)
+
+	token, err := jwt.NewSigner(jwt.StaticKey(k)).Sign(ctx, jwt.Claims{Subject: "subject"})
+	require.NoError(t, err)
+	parts := strings.Split(token, ".")
+
+	// Unknown key
+	other, err := key.Ed25519(ctx)
+	require.NoError(t, err)
+	unknown, err := jwt.NewSigner(jwt.StaticKey(other)).Sign(ctx, jwt.Claims{})
+	require.NoError(t, err)
+	require.Equal(t, jwt.ErrUnknownKey, verifier.Verify(ctx, unknown, nil))
+
+	// Tampered payload
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin"}`))
+	require.Equal(t, jwt.ErrInvalidSignature, verifier.Verify(ctx, parts[0]+"."+payload+"."+parts[2], nil))
+
+	// Unsecured token
+	none := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"alg":"none","kid":"%s"}`, k.ID())))
+	require.Equal(t, jwt.ErrUnsupportedAlgorithm, verifier.Verify(ctx, none+"."+parts[1]+".", nil))
+
+	// Algorithm substitution
+	hs := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"alg":"HS256","kid":"%s"}`, k.ID())))
+	require.Equal(t, jwt.ErrUnsupportedAlgorithm, verifier.Verify(ctx, hs+"."+parts[1]+"."+parts[2], nil))
+
+	// Missing kid
+	nokid := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256"}`))
+	require.Equal(t, jwt.ErrMissingKeyID, verifier.Verify(ctx, nokid+"."+parts[1]+"."+parts[2], nil))
+
+	// Malformed
+	require.Equal(t, jwt.ErrMalformedToken, verifier.Verify(ctx, "a.b", nil))
+
+	// Public key can't sign
+	_, err = jwt.NewSigner(jwt.StaticKey(k.Public())).Sign(ctx, jwt.Claims{})
+	require.Equal(t, jwt.ErrNoSigningKey, err)
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package jwt
+
+import "time"
+
+// DefaultLeeway is the default clock skew tolerance
+const DefaultLeeway = time.Minute
+
+// Options contains verifier settings
+type Options struct {
+	// Issuers lists accepted iss claims, any issuer is accepted if empty
+	Issuers []string
+	// Audience must be contained in the aud claim if not empty
+	Audience string
+	// Leeway is the clock skew tolerance applied to exp, nbf and iat
+	Leeway time.Duration
+	// Algorithms lists accepted JWS algorithms, any key algorithm is accepted if empty
+	Algorithms []string
+	// Now returns the current time
+	Now func() time.Time
+	// AllowMissingExpiry accepts tokens without exp claim, which never expire
+	AllowMissingExpiry bool
+}
+
+// Option configures the verifier
+type Option func(*Options)
+
+// WithIssuer adds an accepted issuer
+func WithIssuer(issuers ...string) Option {
+	return func(o *Options) {
+		o.Issuers = append(o.Issuers, issuers...)
+	}
+}
+
+// WithAudience sets the expected audience
+func WithAudience(audience string) Option {
+	return func(o *Options) {
+		o.Audience = audience
+	}
+}
+
+// WithLeeway sets the clock skew tolerance
+func WithLeeway(leeway time.Duration) Option {
+	return func(o *Options) {
+		o.Leeway = leeway
+	}
+}
+
+// WithAlgorithms restricts the accepted JWS algorithms
+func WithAlgorithms(algs ...string) Option {
+	return func(o *Options) {
+		o.Algorithms = append(o.Algorithms, algs...)
+	}
+}
+
+// WithClock sets the time source used for claims validation
+func WithClock(now func() time.Time) Option {
+	return func(o *Options) {
+		o.Now = now
+	}
+}
+
+// AllowMissingExpiry accepts tokens without exp claim. They remain valid
+// forever, only use it for tokens revoked by other means.
+func AllowMissingExpiry() Option {
+	return func(o *Options) {
+		o.AllowMissingExpiry = true
+	}
+}
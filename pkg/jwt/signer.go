@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package jwt
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+)
+
+type defaultSigner struct {
+	source KeySource
+}
+
+// NewSigner returns a signer using the key provided by the source
+func NewSigner(source KeySource) Signer {
+	return &defaultSigner{
+		source: source,
+	}
+}
+
+// -----------------------------------------------------------------------------
+func (s *defaultSigner) Sign(ctx context.Context, claims interface{}) (string, error) {
+	k, err := s.source.SigningKey(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "jwt: Unable to retrieve signing key")
+	}
+	if k == nil || !k.HasPrivate() {
+		return "", ErrNoSigningKey
+	}
+
+	h, err := encodeSegment(&header{
+		Algorithm: k.Algorithm(),
+		KeyID:     k.ID(),
+		Type:      "JWT",
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "jwt: Unable to encode header")
+	}
+	p, err := encodeSegment(claims)
+	if err != nil {
+		return "", errors.Wrap(err, "jwt: Unable to encode claims")
+	}
+
+	signingInput := h + "." + p
+	sig, err := k.Sign([]byte(signingInput))
+	if err != nil {
+		return "", errors.Wrap(err, "jwt: Unable to sign token")
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
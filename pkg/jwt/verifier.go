@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/scraly/go.common/pkg/keystore"
+
+	"github.com/pkg/errors"
+)
+
+type defaultVerifier struct {
+	keys  keystore.KeyStore
+	dopts *Options
+}
+
+// NewVerifier returns a verifier resolving token kid headers in the keystore
+func NewVerifier(keys keystore.KeyStore, opts ...Option) Verifier {
+	// Default Options
+	options := &Options{
+		Leeway: DefaultLeeway,
+		Now:    time.Now,
+	}
+
+	// Overrides with option
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &defaultVerifier{
+		keys:  keys,
+		dopts: options,
+	}
+}
+
+// -----------------------------------------------------------------------------
+func (v *defaultVerifier) Verify(ctx context.Context, raw string, claims interface{}) error {
+	t, err := parse(raw)
+	if err != nil {
+		return err
+	}
+
+	// Never accept unsecured tokens
+	if t.header.Algorithm == "" || t.header.Algorithm == "none" {
+		return ErrUnsupportedAlgorithm
+	}
+	if len(v.dopts.Algorithms) > 0 && !contains(v.dopts.Algorithms, t.header.Algorithm) {
+		return ErrUnsupportedAlgorithm
+	}
+	if t.header.KeyID == "" {
+		return ErrMissingKeyID
+	}
+
+	k, err := v.keys.Get(ctx, t.header.KeyID)
+	switch {
+	case err == keystore.ErrKeyNotFound:
+		return ErrUnknownKey
+	case err != nil:
+		return errors.Wrap(err, "jwt: Unable to retrieve verification key")
+	}
+
+	// The key decides the algorithm, the header must not override it
+	if k.Algorithm() != t.header.Algorithm {
+		return ErrUnsupportedAlgorithm
+	}
+	if err := k.Verify(t.signingInput, t.signature); err != nil {
+		return ErrInvalidSignature
+	}
+
+	registered := &Claims{}
+	if err := json.Unmarshal(t.payload, registered); err != nil {
+		return ErrMalformedToken
+	}
+	if err := registered.validate(v.dopts.Now(), v.dopts); err != nil {
+		return err
+	}
+
+	if claims != nil {
+		if err := json.Unmarshal(t.payload, claims); err != nil {
+			return errors.Wrap(err, "jwt: Unable to decode claims")
+		}
+	}
+
+	return nil
+}
@@ -26,6 +26,12 @@ type KeyStore interface {
 	Close()
 }
 
+// Expirer is implemented by keystores supporting key expiration
+type Expirer interface {
+	// Expire sets the key expiration date, the key is dropped once expired
+	Expire(ctx context.Context, id string, date time.Time) error
+}
+
 // -----------------------------------------------------------------------------
 
 // keyHolder is the pointer to the current key
@@ -33,11 +39,13 @@ type keyHolder struct {
 	Data       []byte `json:"data"`
 	IssuedAt   int64  `json:"iat"`
 	Expiration int64  `json:"exp"`
+	// Private is the transformed private JWK, Data always holds the public one
+	Private []byte `json:"private,omitempty"`
 }
 
 // IsExpired returns expiration status of the owned key
 func (kh *keyHolder) IsExpired() bool {
-	return time.Unix(kh.Expiration, 0).After(time.Now())
+	return kh.Expiration > 0 && !time.Unix(kh.Expiration, 0).After(time.Now())
 }
 
 // ExpiresOn sets the expiration date of the holded key
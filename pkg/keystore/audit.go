@@ -0,0 +1,119 @@
+package keystore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/scraly/go.common/pkg/keystore/backends"
+	"github.com/scraly/go.common/pkg/util/contexthelper"
+)
+
+// AuditAction is an audited keystore operation
+type AuditAction string
+
+const (
+	// AuditGenerated records a key generation
+	AuditGenerated AuditAction = "generated"
+	// AuditAdded records a key addition
+	AuditAdded AuditAction = "added"
+	// AuditRemoved records a key removal
+	AuditRemoved AuditAction = "removed"
+	// AuditExpired records a key expiration date change
+	AuditExpired AuditAction = "expired"
+)
+
+// AuditRecord is an entry of the keystore audit trail
+type AuditRecord struct {
+	Action AuditAction `json:"action"`
+	KeyID  string      `json:"kid"`
+	Actor  string      `json:"actor,omitempty"`
+	Time   time.Time   `json:"time"`
+}
+
+// AuditLog is an append-only audit trail of keystore operations
+type AuditLog interface {
+	Append(context.Context, *AuditRecord) error
+	// Records returns the audit trail of the key, oldest first
+	Records(ctx context.Context, kid string) ([]*AuditRecord, error)
+}
+
+var actorCtxKey = contexthelper.DefaultContextKey("KeystoreActor")
+
+// WithActor returns a context identifying who performs keystore operations
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorCtxKey, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorCtxKey).(string)
+	return actor
+}
+
+// -----------------------------------------------------------------------------
+
+type backendAuditLog struct {
+	store backends.Backend
+}
+
+// NewAuditLog returns an audit log persisted in the backend under audit/<kid>.
+// Records are never overwritten when the backend supports conditional writes.
+func NewAuditLog(backend backends.Backend) AuditLog {
+	return &backendAuditLog{
+		store: backend,
+	}
+}
+
+func (l *backendAuditLog) Append(ctx context.Context, record *AuditRecord) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("keystore: Unable to marshal audit record as JSON: %v", err)
+	}
+
+	// Zero padded timestamp keeps records sorted by name
+	name := fmt.Sprintf("audit/%s/%020d-%s", record.KeyID, record.Time.UnixNano(), record.Action)
+
+	if atomic, ok := l.store.(backends.AtomicBackend); ok {
+		created, err := atomic.CompareAndSwap(ctx, name, nil, payload)
+		switch {
+		case err != nil:
+			return fmt.Errorf("keystore: Unable to save audit record: %v", err)
+		case !created:
+			return fmt.Errorf("keystore: Audit record %s already exists", name)
+		}
+		return nil
+	}
+
+	if err := l.store.Set(ctx, name, payload); err != nil {
+		return fmt.Errorf("keystore: Unable to save audit record: %v", err)
+	}
+
+	return nil
+}
+
+func (l *backendAuditLog) Records(ctx context.Context, kid string) ([]*AuditRecord, error) {
+	names, err := l.store.List(ctx, fmt.Sprintf("audit/%s", kid))
+	if err != nil {
+		return nil, fmt.Errorf("keystore: Unable to list audit records: %v", err)
+	}
+	sort.Strings(names)
+
+	var result []*AuditRecord
+	for _, name := range names {
+		payload, err := l.store.Get(ctx, fmt.Sprintf("audit/%s/%s", kid, name))
+		if err != nil {
+			return nil, fmt.Errorf("keystore: Unable to read audit record: %v", err)
+		}
+
+		record := &AuditRecord{}
+		if err := json.Unmarshal(payload, record); err != nil {
+			return nil, fmt.Errorf("keystore: Unable to decode audit record: %v", err)
+		}
+		result = append(result, record)
+	}
+
+	return result, nil
+}
@@ -12,3 +12,13 @@ type Backend interface {
 	WatchPrefix(ctx context.Context, prefix string, opts ...WatchOption) (uint64, error)
 	Close(ctx context.Context) error
 }
+
+// AtomicBackend is implemented by backends supporting conditional writes
+type AtomicBackend interface {
+	Backend
+
+	// CompareAndSwap sets the value only if the current one equals old, a nil
+	// old value means that the key must not exist
+	CompareAndSwap(ctx context.Context, key string, old, value []byte) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
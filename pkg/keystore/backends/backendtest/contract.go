@@ -0,0 +1,154 @@
+// Package backendtest provides the contract tests shared by backend implementations.
+package backendtest
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/scraly/go.common/pkg/keystore/backends"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Run checks the backend behavior against the backends.Backend contract. The
+// backend must be empty. Atomic and watch operations are checked when supported.
+func Run(t *testing.T, backend backends.Backend) {
+	t.Run("GetSet", func(t *testing.T) { testGetSet(t, backend) })
+	t.Run("List", func(t *testing.T) { testList(t, backend) })
+
+	if atomic, ok := backend.(backends.AtomicBackend); ok {
+		t.Run("CompareAndSwap", func(t *testing.T) { testCompareAndSwap(t, atomic) })
+		t.Run("Delete", func(t *testing.T) { testDelete(t, atomic) })
+	}
+
+	t.Run("WatchPrefix", func(t *testing.T) { testWatchPrefix(t, backend) })
+}
+
+func testGetSet(t *testing.T, backend backends.Backend) {
+	ctx := context.Background()
+
+	_, err := backend.Get(ctx, "contract/getset/missing")
+	require.Equal(t, backends.ErrKeyNotFound, err, "Missing key should raise ErrKeyNotFound")
+
+	require.NoError(t, backend.Set(ctx, "contract/getset/key", []byte("value")))
+	value, err := backend.Get(ctx, "contract/getset/key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+
+	require.NoError(t, backend.Set(ctx, "contract/getset/key", []byte("updated")))
+	value, err = backend.Get(ctx, "contract/getset/key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("updated"), value, "Set should overwrite value")
+}
+
+func testList(t *testing.T, backend backends.Backend) {
+	ctx := context.Background()
+
+	keys, err := backend.List(ctx, "contract/list")
+	require.NoError(t, err)
+	require.Empty(t, keys, "Empty prefix should list no key")
+
+	require.NoError(t, backend.Set(ctx, "contract/list/a", []byte("a")))
+	require.NoError(t, backend.Set(ctx, "contract/list/b", []byte("b")))
+	require.NoError(t, backend.Set(ctx, "contract/listing/c", []byte("c")))
+
+	keys, err = backend.List(ctx, "contract/list")
+	require.NoError(t, err)
+	sort.Strings(keys)
+	require.Equal(t, []string{"a", "b"}, keys, "List should return relative names of prefix children")
+}
+
+func testCompareAndSwap(t *testing.T, backend backends.AtomicBackend) {
+	ctx := context.Background()
+
+	ok, err := backend.CompareAndSwap(ctx, "contract/cas/key", nil, []byte("v1"))
+	require.NoError(t, err)
+	require.True(t, ok, "Absent key should be created")
+
+	ok, err = backend.CompareAndSwap(ctx, "contract/cas/key", nil, []byte("v2"))
+	require.NoError(t, err)
+	require.False(t, ok, "Existing key should not be created")
+
+	ok, err = backend.CompareAndSwap(ctx, "contract/cas/key", []byte("other"), []byte("v2"))
+	require.NoError(t, err)
+	require.False(t, ok, "Mismatching value should not be swapped")
+
+	ok, err = backend.CompareAndSwap(ctx, "contract/cas/key", []byte("v1"), []byte("v2"))
+	require.NoError(t, err)
+	require.True(t, ok, "Matching value should be swapped")
+
+	value, err := backend.Get(ctx, "contract/cas/key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), value)
+}
+
+func testDelete(t *testing.T, backend backends.AtomicBackend) {
+	ctx := context.Background()
+
+	require.Equal(t, backends.ErrKeyNotFound, backend.Delete(ctx, "contract/delete/missing"))
+
+	require.NoError(t, backend.Set(ctx, "contract/delete/key", []byte("value")))
+	require.NoError(t, backend.Delete(ctx, "contract/delete/key"))
+
+	_, err := backend.Get(ctx, "contract/delete/key")
+	require.Equal(t, backends.ErrKeyNotFound, err, "Deleted key should not be found")
+}
+
+func testWatchPrefix(t *testing.T, backend backends.Backend) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	index, err := backend.WatchPrefix(ctx, "contract/watch")
+	if err == backends.ErrWatchNotSupported {
+		t.Skip("Backend does not support watch")
+	}
+	require.NoError(t, err, "Initial watch should return current index")
+
+	// Some backends report a global index for empty prefixes
+	require.NoError(t, backend.Set(ctx, "contract/watch/initial", []byte("value")))
+	index, err = backend.WatchPrefix(ctx, "contract/watch")
+	require.NoError(t, err, "Initial watch should return current index")
+
+	type result struct {
+		index uint64
+		err   error
+	}
+	results := make(chan result, 1)
+	go func() {
+		next, err := backend.WatchPrefix(ctx, "contract/watch", backends.WithWaitIndex(index))
+		results <- result{next, err}
+	}()
+
+	// Give the watcher time to block, siblings sharing the prefix name are not watched
+	require.NoError(t, backend.Set(ctx, "contract/watching/key", []byte("value")))
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-results:
+		t.Fatal("Watch should block until a change")
+	default:
+	}
+
+	require.NoError(t, backend.Set(ctx, "contract/watch/key", []byte("value")))
+
+	select {
+	case r := <-results:
+		require.NoError(t, r.err)
+		require.NotEqual(t, index, r.index, "Watch should return a new index")
+	case <-ctx.Done():
+		t.Fatal("Watch should return after a change")
+	}
+
+	// Canceled watch returns
+	index, err = backend.WatchPrefix(ctx, "contract/watch")
+	require.NoError(t, err)
+
+	watchCtx, watchCancel := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		watchCancel()
+	}()
+	_, err = backend.WatchPrefix(watchCtx, "contract/watch", backends.WithWaitIndex(index))
+	require.Error(t, err, "Canceled watch should raise an error")
+}
@@ -0,0 +1,246 @@
+package consul
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/scraly/go.common/pkg/keystore/backends"
+
+	"github.com/hashicorp/consul/api"
+)
+
+type consulBackend struct {
+	kv   *api.KV
+	root string
+}
+
+// New initializes a Consul KV backend instance, keys are stored under root
+func New(config *api.Config, root string) (backends.AtomicBackend, error) {
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("consul: Unable to create client: %v", err)
+	}
+
+	return &consulBackend{
+		kv:   client.KV(),
+		root: strings.Trim(root, "/"),
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+func (b *consulBackend) Name() string {
+	return "consul"
+}
+
+func (b *consulBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	pair, _, err := b.kv.Get(b.path(key), b.query(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if pair == nil {
+		return nil, backends.ErrKeyNotFound
+	}
+
+	return pair.Value, nil
+}
+
+func (b *consulBackend) Set(ctx context.Context, key string, value []byte) error {
+	_, err := b.kv.Put(&api.KVPair{
+		Key:   b.path(key),
+		Value: value,
+	}, b.write(ctx))
+	return err
+}
+
+func (b *consulBackend) List(ctx context.Context, key string) ([]string, error) {
+	prefix := b.path(key) + "/"
+
+	keys, _, err := b.kv.Keys(prefix, "", b.query(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, k := range keys {
+		result = append(result, strings.TrimPrefix(k, prefix))
+	}
+
+	return result, nil
+}
+
+func (b *consulBackend) CompareAndSwap(ctx context.Context, key string, old, value []byte) (bool, error) {
+	p := b.path(key)
+
+	// A zero modify index only succeeds if the key does not exist
+	var index uint64
+	if old != nil {
+		pair, _, err := b.kv.Get(p, b.query(ctx))
+		if err != nil {
+			return false, err
+		}
+		if pair == nil || !bytes.Equal(pair.Value, old) {
+			return false, nil
+		}
+		index = pair.ModifyIndex
+	}
+
+	ok, _, err := b.kv.CAS(&api.KVPair{
+		Key:         p,
+		Value:       value,
+		ModifyIndex: index,
+	}, b.write(ctx))
+
+	return ok, err
+}
+
+func (b *consulBackend) Delete(ctx context.Context, key string) error {
+	p := b.path(key)
+
+	pair, _, err := b.kv.Get(p, b.query(ctx))
+	if err != nil {
+		return err
+	}
+	if pair == nil {
+		return backends.ErrKeyNotFound
+	}
+
+	_, err = b.kv.Delete(p, b.write(ctx))
+	return err
+}
+
+// WatchPrefix blocks until the prefix index moves past the wait index, and
+// returns the index to wait from on next call. A zero wait index returns the
+// current index immediately.
+//
+// Consul blocking queries do not report which key changed. When the Keys
+// option is set, the index of each key prefix is checked once the prefix
+// index moved, it includes deletions.
+func (b *consulBackend) WatchPrefix(ctx context.Context, prefix string, opts ...backends.WatchOption) (uint64, error) {
+	options := &backends.WatchOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	waitIndex := options.WaitIndex
+	for {
+		lastIndex, err := b.index(ctx, prefix, waitIndex)
+		if err != nil {
+			return options.WaitIndex, err
+		}
+
+		switch {
+		case lastIndex < waitIndex:
+			// Index went backwards (snapshot restore), resynchronize
+			return lastIndex, nil
+		case lastIndex == waitIndex:
+			// Blocking query timed out, wait again
+			continue
+		}
+
+		changed, err := b.changed(ctx, options.Keys, options.WaitIndex)
+		if err != nil {
+			return options.WaitIndex, err
+		}
+		if changed || options.WaitIndex == 0 {
+			return lastIndex, nil
+		}
+
+		// Only other keys changed
+		waitIndex = lastIndex
+	}
+}
+
+func (b *consulBackend) Close(ctx context.Context) error {
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (b *consulBackend) path(key string) string {
+	return strings.TrimPrefix(path.Join(b.root, key), "/")
+}
+
+// index blocks until the index of keys under prefix moves past the wait index,
+// or the blocking query times out
+func (b *consulBackend) index(ctx context.Context, prefix string, waitIndex uint64) (uint64, error) {
+	q := b.query(ctx)
+	q.WaitIndex = waitIndex
+
+	// Trailing separator excludes siblings sharing the prefix name
+	_, meta, err := b.kv.Keys(b.path(prefix)+"/", "", q)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, err
+	}
+
+	return meta.LastIndex, nil
+}
+
+// changed returns true if one of keys, or a key under it, has been modified
+// or deleted after the given index, any key matches when keys is empty.
+//
+// Consul prefix indexes include siblings sharing the key name, and deleted
+// keys are only visible through them. A missing key is reported as changed
+// when its prefix index moved.
+func (b *consulBackend) changed(ctx context.Context, keys []string, index uint64) (bool, error) {
+	if len(keys) == 0 {
+		return true, nil
+	}
+
+	for _, k := range keys {
+		p := b.path(k)
+
+		pairs, meta, err := b.kv.List(p, b.query(ctx))
+		if err != nil {
+			return false, err
+		}
+		found := false
+		var newest uint64
+		for _, pair := range pairs {
+			if pair.ModifyIndex > newest {
+				newest = pair.ModifyIndex
+			}
+			if pair.Key != p && !strings.HasPrefix(pair.Key, p+"/") {
+				continue
+			}
+			if pair.Key == p {
+				found = true
+			}
+			if pair.ModifyIndex > index {
+				return true, nil
+			}
+		}
+		if !found && meta.LastIndex > index {
+			return true, nil
+		}
+
+		// Deletions under the key. Without entry nor deletion under the
+		// prefix, Consul reports the whole store index, which is never below
+		// the key prefix one and is ignored when the latest change of the
+		// key prefix is not a deletion.
+		children, childMeta, err := b.kv.Keys(p+"/", "", b.query(ctx))
+		if err != nil {
+			return false, err
+		}
+		deletions := len(children) > 0 || childMeta.LastIndex < meta.LastIndex ||
+			childMeta.LastIndex == meta.LastIndex && newest < meta.LastIndex
+		if deletions && childMeta.LastIndex > index {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (b *consulBackend) query(ctx context.Context) *api.QueryOptions {
+	return (&api.QueryOptions{}).WithContext(ctx)
+}
+
+func (b *consulBackend) write(ctx context.Context) *api.WriteOptions {
+	return (&api.WriteOptions{}).WithContext(ctx)
+}
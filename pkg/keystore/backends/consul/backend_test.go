@@ -0,0 +1,310 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scraly/go.common/pkg/keystore"
+	"github.com/scraly/go.common/pkg/keystore/backends"
+	"github.com/scraly/go.common/pkg/keystore/backends/backendtest"
+	"github.com/scraly/go.common/pkg/keystore/key"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConsul serves the subset of the Consul KV HTTP API used by the backend,
+// with blocking queries and prefix indexes including deletions
+type fakeConsul struct {
+	sync.Mutex
+
+	index      uint64
+	pairs      map[string]*api.KVPair
+	tombstones map[string]uint64
+}
+
+func newFakeConsul() *httptest.Server {
+	f := &fakeConsul{
+		index:      1,
+		pairs:      map[string]*api.KVPair{},
+		tombstones: map[string]uint64{},
+	}
+	return httptest.NewServer(f)
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	params := r.URL.Query()
+
+	switch r.Method {
+	case http.MethodGet:
+		f.get(w, r, key, params)
+	case http.MethodPut:
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, f.put(key, body, params))
+	case http.MethodDelete:
+		fmt.Fprint(w, f.delete(key))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeConsul) get(w http.ResponseWriter, r *http.Request, key string, params map[string][]string) {
+	_, keys := params["keys"]
+	_, recurse := params["recurse"]
+	list := keys || recurse
+
+	// Blocking query
+	if values := params["index"]; len(values) > 0 {
+		wait, _ := strconv.ParseUint(values[0], 10, 64)
+		deadline := time.Now().Add(5 * time.Second)
+		for f.prefixIndex(key, list) <= wait && time.Now().Before(deadline) {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.indexLocked(key, list), 10))
+
+	var result []*api.KVPair
+	for k, pair := range f.pairs {
+		if k == key || list && strings.HasPrefix(k, key) {
+			result = append(result, pair)
+		}
+	}
+	if len(result) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+
+	if keys {
+		names := make([]string, 0, len(result))
+		for _, pair := range result {
+			names = append(names, pair.Key)
+		}
+		_ = json.NewEncoder(w).Encode(names)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeConsul) put(key string, value []byte, params map[string][]string) bool {
+	f.Lock()
+	defer f.Unlock()
+
+	current := f.pairs[key]
+	if values := params["cas"]; len(values) > 0 {
+		cas, _ := strconv.ParseUint(values[0], 10, 64)
+		switch {
+		case cas == 0 && current != nil:
+			return false
+		case cas != 0 && (current == nil || current.ModifyIndex != cas):
+			return false
+		}
+	}
+
+	f.index++
+	pair := &api.KVPair{Key: key, Value: value, CreateIndex: f.index, ModifyIndex: f.index}
+	if current != nil {
+		pair.CreateIndex = current.CreateIndex
+	}
+	f.pairs[key] = pair
+	delete(f.tombstones, key)
+
+	return true
+}
+
+func (f *fakeConsul) delete(key string) bool {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.pairs[key]; ok {
+		f.index++
+		delete(f.pairs, key)
+		f.tombstones[key] = f.index
+	}
+
+	return true
+}
+
+func (f *fakeConsul) prefixIndex(key string, list bool) uint64 {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.indexLocked(key, list)
+}
+
+// indexLocked mimics Consul: the highest modify index of matching entries and
+// tombstones, or the whole store index when none matches
+func (f *fakeConsul) indexLocked(key string, list bool) uint64 {
+	var index uint64
+	found := false
+	for k, pair := range f.pairs {
+		if k == key || list && strings.HasPrefix(k, key) {
+			found = true
+			if pair.ModifyIndex > index {
+				index = pair.ModifyIndex
+			}
+		}
+	}
+	for k, tombstone := range f.tombstones {
+		if (k == key || list && strings.HasPrefix(k, key)) && tombstone > index {
+			found = true
+			index = tombstone
+		}
+	}
+
+	if !found {
+		return f.index
+	}
+	return index
+}
+
+// -----------------------------------------------------------------------------
+
+func newBackend(t *testing.T, server *httptest.Server, root string) backends.AtomicBackend {
+	config := api.DefaultConfig()
+	config.Address = server.URL
+
+	backend, err := New(config, root)
+	require.NoError(t, err)
+	return backend
+}
+
+func TestBackend(t *testing.T) {
+	server := newFakeConsul()
+	defer server.Close()
+
+	backendtest.Run(t, newBackend(t, server, "contract"))
+}
+
+func TestBackend_WatchKeys(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server := newFakeConsul()
+	defer server.Close()
+	backend := newBackend(t, server, "root")
+
+	require.NoError(t, backend.Set(ctx, "watch/a", []byte("a")))
+	index, err := backend.WatchPrefix(ctx, "watch")
+	require.NoError(t, err)
+
+	watch := func(index uint64) chan uint64 {
+		results := make(chan uint64, 1)
+		go func() {
+			next, err := backend.WatchPrefix(ctx, "watch", backends.WithWaitIndex(index), backends.WithKeys([]string{"watch/a"}))
+			if err == nil {
+				results <- next
+			}
+		}()
+		return results
+	}
+
+	results := watch(index)
+	require.NoError(t, backend.Set(ctx, "watch/b", []byte("b")))
+	require.NoError(t, backend.Set(ctx, "watch/ab", []byte("ab")))
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-results:
+		t.Fatal("Change of another key should not end the watch")
+	default:
+	}
+
+	require.NoError(t, backend.Set(ctx, "watch/a", []byte("updated")))
+	select {
+	case index = <-results:
+	case <-ctx.Done():
+		t.Fatal("Change of a watched key should end the watch")
+	}
+
+	results = watch(index)
+	require.NoError(t, backend.Delete(ctx, "watch/a"))
+	select {
+	case index = <-results:
+	case <-ctx.Done():
+		t.Fatal("Deletion of a watched key should end the watch")
+	}
+
+	results = watch(index)
+	require.NoError(t, backend.Set(ctx, "watch/a/child", []byte("child")))
+	select {
+	case <-results:
+	case <-ctx.Done():
+		t.Fatal("Change of a key under a watched key should end the watch")
+	}
+}
+
+func TestBackend_ChangedDeletion(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeConsul()
+	defer server.Close()
+	backend := newBackend(t, server, "root").(*consulBackend)
+
+	require.NoError(t, backend.Set(ctx, "watch/a", []byte("a")))
+	require.NoError(t, backend.Set(ctx, "watch/a/child", []byte("child")))
+	index, err := backend.WatchPrefix(ctx, "watch")
+	require.NoError(t, err)
+
+	// Deletion under the key followed by a sibling change
+	require.NoError(t, backend.Delete(ctx, "watch/a/child"))
+	require.NoError(t, backend.Set(ctx, "watch/ab", []byte("ab")))
+	changed, err := backend.changed(ctx, []string{"watch/a"}, index)
+	require.NoError(t, err)
+	require.True(t, changed, "Deletion under a watched key should be detected")
+
+	index, err = backend.WatchPrefix(ctx, "watch")
+	require.NoError(t, err)
+	require.NoError(t, backend.Set(ctx, "watch/ab", []byte("updated")))
+	changed, err = backend.changed(ctx, []string{"watch/a"}, index)
+	require.NoError(t, err)
+	require.False(t, changed, "Change of a sibling should not be detected")
+}
+
+func TestKeyStoreWatch(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeConsul()
+	defer server.Close()
+
+	writer, err := keystore.New(newBackend(t, server, "keystore"))
+	require.NoError(t, err)
+
+	// Polling interval is far longer than the test
+	reader, err := keystore.New(newBackend(t, server, "keystore"), keystore.EnableWatch(), keystore.WithInterval(3600))
+	require.NoError(t, err)
+	reader.StartMonitor(ctx)
+	defer reader.Close()
+
+	k, err := writer.Generate(ctx, key.Ed25519)
+	require.NoError(t, err)
+	require.NoError(t, writer.Add(ctx, k))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err = reader.Get(ctx, k.ID()); err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err, "Watching keystore should synchronize new keys")
+}
@@ -0,0 +1,296 @@
+package directory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/scraly/go.common/pkg/keystore/backends"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ErrInvalidKey is raised when a key does not resolve to a path under the root directory
+var ErrInvalidKey = errors.New("directory: Key must be a relative path under root directory")
+
+type directoryBackend struct {
+	// mutex serializes writes, conditional writes are only atomic within the process
+	sync.Mutex
+
+	root string
+}
+
+// New initializes a directory backend instance, each key is a file under root.
+// Hidden entries are ignored, so that mounted Kubernetes secret directories can be used.
+func New(root string) (backends.AtomicBackend, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("directory: Unable to open root directory: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("directory: %s is not a directory", root)
+	}
+
+	return &directoryBackend{
+		root: root,
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+func (b *directoryBackend) Name() string {
+	return "directory"
+}
+
+func (b *directoryBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	p, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, backends.ErrKeyNotFound
+	}
+
+	return value, err
+}
+
+func (b *directoryBackend) Set(ctx context.Context, key string, value []byte) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	return b.write(p, value)
+}
+
+func (b *directoryBackend) List(ctx context.Context, key string) ([]string, error) {
+	dir, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	switch {
+	case os.IsNotExist(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var result []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		// Follow symlinks, Kubernetes projects files through them
+		info, err := os.Stat(filepath.Join(dir, entry.Name()))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		result = append(result, entry.Name())
+	}
+
+	return result, nil
+}
+
+func (b *directoryBackend) CompareAndSwap(ctx context.Context, key string, old, value []byte) (bool, error) {
+	p, err := b.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	current, err := ioutil.ReadFile(p)
+	switch {
+	case os.IsNotExist(err):
+		if old != nil {
+			return false, nil
+		}
+	case err != nil:
+		return false, err
+	case old == nil || !bytes.Equal(current, old):
+		return false, nil
+	}
+
+	if err := b.write(p, value); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (b *directoryBackend) Delete(ctx context.Context, key string) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	err = os.Remove(p)
+	if os.IsNotExist(err) {
+		return backends.ErrKeyNotFound
+	}
+
+	return err
+}
+
+// WatchPrefix blocks until a file under prefix changes. The returned index is
+// the last modification time of the prefix directory content, a zero wait
+// index returns the current one immediately.
+//
+// Keys option is ignored, any change under prefix wakes the watcher.
+func (b *directoryBackend) WatchPrefix(ctx context.Context, prefix string, opts ...backends.WatchOption) (uint64, error) {
+	options := &backends.WatchOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	dir, err := b.path(prefix)
+	if err != nil {
+		return options.WaitIndex, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return options.WaitIndex, fmt.Errorf("directory: Unable to create watcher: %v", err)
+	}
+	defer watcher.Close()
+
+	// Watch root too, Kubernetes updates mounts by swapping a symlink there
+	if err := watcher.Add(b.root); err != nil {
+		return options.WaitIndex, fmt.Errorf("directory: Unable to watch root directory: %v", err)
+	}
+	watching := dir == b.root
+
+	for {
+		// Prefix directory may not exist yet, watch its deepest existing parent
+		for p := dir; !watching && len(p) > len(b.root); p = filepath.Dir(p) {
+			err := watcher.Add(p)
+			if err == nil {
+				watching = p == dir
+				break
+			}
+			if !os.IsNotExist(err) {
+				return options.WaitIndex, fmt.Errorf("directory: Unable to watch directory: %v", err)
+			}
+		}
+
+		// Watcher is registered before computing the index, no change is missed
+		index, err := b.index(dir)
+		if err != nil {
+			return options.WaitIndex, err
+		}
+		if index != options.WaitIndex {
+			return index, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return options.WaitIndex, ctx.Err()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return options.WaitIndex, backends.ErrWatchCanceled
+			}
+			return options.WaitIndex, err
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return options.WaitIndex, backends.ErrWatchCanceled
+			}
+		}
+	}
+}
+
+func (b *directoryBackend) Close(ctx context.Context) error {
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// path returns the file of the key, keys must not escape the root directory
+func (b *directoryBackend) path(key string) (string, error) {
+	name := filepath.FromSlash(key)
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+
+	p := filepath.Join(b.root, name)
+	if p != b.root && !strings.HasPrefix(p, b.root+string(filepath.Separator)) {
+		return "", ErrInvalidKey
+	}
+
+	return p, nil
+}
+
+// write replaces the file atomically using a rename
+func (b *directoryBackend) write(p string, value []byte) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(p), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+// index returns the latest modification time of the directory and its files
+func (b *directoryBackend) index(dir string) (uint64, error) {
+	var latest int64
+
+	info, err := os.Stat(dir)
+	switch {
+	case os.IsNotExist(err):
+		return 1, nil
+	case err != nil:
+		return 0, err
+	}
+	latest = info.ModTime().UnixNano()
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if info, err := os.Stat(filepath.Join(dir, entry.Name())); err == nil && info.ModTime().UnixNano() > latest {
+			latest = info.ModTime().UnixNano()
+		}
+	}
+
+	return uint64(latest), nil
+}
@@ -0,0 +1,77 @@
+package directory
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/scraly/go.common/pkg/keystore/backends/backendtest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend(t *testing.T) {
+	root, err := ioutil.TempDir("", "keystore")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	backend, err := New(root)
+	require.NoError(t, err)
+
+	backendtest.Run(t, backend)
+}
+
+func TestBackend_HiddenEntries(t *testing.T) {
+	ctx := context.Background()
+	root, err := ioutil.TempDir("", "keystore")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	// Kubernetes secret mount layout
+	data := filepath.Join(root, "jwk", "..data")
+	require.NoError(t, os.MkdirAll(data, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(data, "kid"), []byte("value"), 0600))
+	require.NoError(t, os.Symlink(filepath.Join("..data", "kid"), filepath.Join(root, "jwk", "kid")))
+
+	backend, err := New(root)
+	require.NoError(t, err)
+
+	keys, err := backend.List(ctx, "jwk")
+	require.NoError(t, err)
+	require.Equal(t, []string{"kid"}, keys, "Hidden entries should be ignored")
+
+	value, err := backend.Get(ctx, "jwk/kid")
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+}
+
+func TestBackend_InvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	parent, err := ioutil.TempDir("", "keystore")
+	require.NoError(t, err)
+	defer os.RemoveAll(parent)
+
+	root := filepath.Join(parent, "root")
+	require.NoError(t, os.Mkdir(root, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(parent, "secret"), []byte("value"), 0600))
+
+	backend, err := New(root)
+	require.NoError(t, err)
+
+	for _, key := range []string{"../secret", "jwk/../../secret", "..", "/etc/passwd"} {
+		_, err := backend.Get(ctx, key)
+		require.Equal(t, ErrInvalidKey, err, "Key %q should be rejected", key)
+		require.Equal(t, ErrInvalidKey, backend.Set(ctx, key, []byte("value")), "Key %q should be rejected", key)
+		require.Equal(t, ErrInvalidKey, backend.Delete(ctx, key), "Key %q should be rejected", key)
+		_, err = backend.List(ctx, key)
+		require.Equal(t, ErrInvalidKey, err, "Key %q should be rejected", key)
+		_, err = backend.WatchPrefix(ctx, key)
+		require.Equal(t, ErrInvalidKey, err, "Key %q should be rejected", key)
+	}
+
+	value, err := ioutil.ReadFile(filepath.Join(parent, "secret"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value, "Files outside root should not be modified")
+}
@@ -3,6 +3,8 @@ package backends
 import "errors"
 
 var (
+	// ErrKeyNotFound is raised when trying to access an inexistant key
+	ErrKeyNotFound = errors.New("backend: Key not found")
 	// ErrWatchNotSupported is raised when trying to access watch feature from a watch disabled backend
 	ErrWatchNotSupported = errors.New("backend: Watch prefix not supported for this backend")
 	// ErrWatchCanceled is raised when trying to shutdown the backend storage
@@ -0,0 +1,166 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/scraly/go.common/pkg/keystore/backends"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type etcdBackend struct {
+	client *clientv3.Client
+	root   string
+}
+
+// New initializes an etcd v3 backend instance, keys are stored under root
+func New(config clientv3.Config, root string) (backends.AtomicBackend, error) {
+	client, err := clientv3.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("etcd: Unable to connect to cluster: %v", err)
+	}
+
+	return &etcdBackend{
+		client: client,
+		root:   path.Join("/", root),
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+func (b *etcdBackend) Name() string {
+	return "etcd"
+}
+
+func (b *etcdBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := b.client.Get(ctx, b.path(key))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, backends.ErrKeyNotFound
+	}
+
+	return resp.Kvs[0].Value, nil
+}
+
+func (b *etcdBackend) Set(ctx context.Context, key string, value []byte) error {
+	_, err := b.client.Put(ctx, b.path(key), string(value))
+	return err
+}
+
+func (b *etcdBackend) List(ctx context.Context, key string) ([]string, error) {
+	prefix := b.path(key) + "/"
+
+	resp, err := b.client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, kv := range resp.Kvs {
+		result = append(result, strings.TrimPrefix(string(kv.Key), prefix))
+	}
+
+	return result, nil
+}
+
+func (b *etcdBackend) CompareAndSwap(ctx context.Context, key string, old, value []byte) (bool, error) {
+	p := b.path(key)
+
+	cmp := clientv3.Compare(clientv3.Value(p), "=", string(old))
+	if old == nil {
+		cmp = clientv3.Compare(clientv3.CreateRevision(p), "=", 0)
+	}
+
+	resp, err := b.client.Txn(ctx).If(cmp).Then(clientv3.OpPut(p, string(value))).Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return resp.Succeeded, nil
+}
+
+func (b *etcdBackend) Delete(ctx context.Context, key string) error {
+	resp, err := b.client.Delete(ctx, b.path(key))
+	if err != nil {
+		return err
+	}
+	if resp.Deleted == 0 {
+		return backends.ErrKeyNotFound
+	}
+
+	return nil
+}
+
+// WatchPrefix blocks until a key under prefix is modified after the wait
+// index, and returns the store revision to wait from on next call. A zero
+// wait index returns the current revision immediately.
+func (b *etcdBackend) WatchPrefix(ctx context.Context, prefix string, opts ...backends.WatchOption) (uint64, error) {
+	options := &backends.WatchOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	// Trailing separator excludes siblings sharing the prefix name
+	p := b.path(prefix) + "/"
+
+	if options.WaitIndex == 0 {
+		resp, err := b.client.Get(ctx, p, clientv3.WithPrefix(), clientv3.WithCountOnly())
+		if err != nil {
+			return 0, err
+		}
+		return uint64(resp.Header.Revision), nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	watcher := b.client.Watch(ctx, p, clientv3.WithPrefix(), clientv3.WithRev(int64(options.WaitIndex)+1))
+	for resp := range watcher {
+		if resp.CompactRevision != 0 {
+			// Missed events have been compacted, force a full resynchronization
+			return uint64(resp.CompactRevision), nil
+		}
+		if err := resp.Err(); err != nil {
+			return options.WaitIndex, err
+		}
+
+		for _, event := range resp.Events {
+			if b.matches(string(event.Kv.Key), options.Keys) {
+				return uint64(resp.Header.Revision), nil
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return options.WaitIndex, err
+	}
+	return options.WaitIndex, backends.ErrWatchCanceled
+}
+
+func (b *etcdBackend) Close(ctx context.Context) error {
+	return b.client.Close()
+}
+
+// -----------------------------------------------------------------------------
+
+func (b *etcdBackend) path(key string) string {
+	return path.Join(b.root, key)
+}
+
+// matches returns true if key is one of keys or a key under it, any key
+// matches when keys is empty
+func (b *etcdBackend) matches(key string, keys []string) bool {
+	if len(keys) == 0 {
+		return true
+	}
+	for _, k := range keys {
+		if p := b.path(k); key == p || strings.HasPrefix(key, p+"/") {
+			return true
+		}
+	}
+	return false
+}
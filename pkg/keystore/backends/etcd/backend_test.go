@@ -0,0 +1,129 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/scraly/go.common/pkg/keystore"
+	"github.com/scraly/go.common/pkg/keystore/backends/backendtest"
+	"github.com/scraly/go.common/pkg/keystore/key"
+
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+func freeURL(t *testing.T) url.URL {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	u, err := url.Parse(fmt.Sprintf("http://%s", l.Addr().String()))
+	require.NoError(t, err)
+	return *u
+}
+
+// startEtcd runs a single node embedded etcd server
+func startEtcd(t *testing.T) (clientv3.Config, func()) {
+	dir, err := ioutil.TempDir("", "etcd")
+	require.NoError(t, err)
+
+	cfg := embed.NewConfig()
+	cfg.Dir = dir
+	cfg.LogLevel = "error"
+	client, peer := freeURL(t), freeURL(t)
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{client}, []url.URL{client}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{peer}, []url.URL{peer}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	server, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		server.Close()
+		t.Fatal("Embedded etcd should start")
+	}
+
+	config := clientv3.Config{
+		Endpoints:   []string{client.String()},
+		DialTimeout: 5 * time.Second,
+	}
+
+	return config, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestBackend(t *testing.T) {
+	config, stop := startEtcd(t)
+	defer stop()
+
+	backend, err := New(config, "contract")
+	require.NoError(t, err)
+	defer backend.Close(context.Background())
+
+	backendtest.Run(t, backend)
+}
+
+func TestBackend_Matches(t *testing.T) {
+	b := &etcdBackend{root: "root"}
+
+	require.True(t, b.matches("root/watch/ab", nil), "Any key should match without filter")
+	require.True(t, b.matches("root/watch/a", []string{"watch/a"}))
+	require.True(t, b.matches("root/watch/a/child", []string{"watch/a"}))
+	require.False(t, b.matches("root/watch/ab", []string{"watch/a"}), "Sibling sharing the key name should not match")
+}
+
+func TestKeyStoreWatch(t *testing.T) {
+	ctx := context.Background()
+	config, stop := startEtcd(t)
+	defer stop()
+
+	b1, err := New(config, "keystore")
+	require.NoError(t, err)
+	defer b1.Close(ctx)
+	b2, err := New(config, "keystore")
+	require.NoError(t, err)
+	defer b2.Close(ctx)
+
+	writer, err := keystore.New(b1)
+	require.NoError(t, err)
+
+	// Polling interval is far longer than the test
+	reader, err := keystore.New(b2, keystore.EnableWatch(), keystore.WithInterval(3600))
+	require.NoError(t, err)
+	reader.StartMonitor(ctx)
+	defer reader.Close()
+
+	k, err := writer.Generate(ctx, key.Ed25519)
+	require.NoError(t, err)
+	require.NoError(t, writer.Add(ctx, k))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err = reader.Get(ctx, k.ID()); err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err, "Watching keystore should synchronize new keys")
+
+	require.NoError(t, writer.Remove(ctx, k.ID()))
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		if _, err = reader.Get(ctx, k.ID()); err != nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, keystore.ErrKeyNotFound, err, "Watching keystore should synchronize removed keys")
+}
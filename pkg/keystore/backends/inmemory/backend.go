@@ -1,6 +1,7 @@
 package inmemory
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"strings"
@@ -16,7 +17,7 @@ type inMemoryBackend struct {
 }
 
 // New initializes a inmem backend instance
-func New() (backends.Backend, error) {
+func New() (backends.AtomicBackend, error) {
 	return &inMemoryBackend{
 		data: make(map[string]string),
 	}, nil
@@ -35,7 +36,7 @@ func (b *inMemoryBackend) Get(ctx context.Context, key string) ([]byte, error) {
 		return []byte(value), nil
 	}
 
-	return nil, fmt.Errorf("inmemory: Key not found")
+	return nil, backends.ErrKeyNotFound
 }
 
 func (b *inMemoryBackend) Set(ctx context.Context, key string, value []byte) error {
@@ -52,13 +53,45 @@ func (b *inMemoryBackend) List(ctx context.Context, key string) ([]string, error
 
 	var result []string
 
+	prefix := fmt.Sprintf("%s/", key)
 	for k := range b.data {
-		result = append(result, strings.TrimPrefix(k, fmt.Sprintf("%s/", key)))
+		if strings.HasPrefix(k, prefix) {
+			result = append(result, strings.TrimPrefix(k, prefix))
+		}
 	}
 
 	return result, nil
 }
 
+func (b *inMemoryBackend) CompareAndSwap(ctx context.Context, key string, old, value []byte) (bool, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	current, ok := b.data[key]
+	if old == nil {
+		if ok {
+			return false, nil
+		}
+	} else if !ok || !bytes.Equal([]byte(current), old) {
+		return false, nil
+	}
+
+	b.data[key] = string(value)
+	return true, nil
+}
+
+func (b *inMemoryBackend) Delete(ctx context.Context, key string) error {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, ok := b.data[key]; !ok {
+		return backends.ErrKeyNotFound
+	}
+
+	delete(b.data, key)
+	return nil
+}
+
 func (b *inMemoryBackend) WatchPrefix(ctx context.Context, prefix string, opts ...backends.WatchOption) (uint64, error) {
 	return 0, backends.ErrWatchNotSupported
 }
@@ -0,0 +1,16 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/scraly/go.common/pkg/keystore/backends/backendtest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend(t *testing.T) {
+	backend, err := New()
+	require.NoError(t, err)
+
+	backendtest.Run(t, backend)
+}
@@ -0,0 +1,201 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/scraly/go.common/pkg/keystore/backends"
+	"github.com/scraly/go.common/pkg/secret"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// casMismatch is the KV v2 error message of a check-and-set version mismatch
+const casMismatch = "check-and-set parameter did not match the current version"
+
+type vaultBackend struct {
+	secret *secret.Secret
+	mount  string
+	root   string
+}
+
+// New initializes a Vault KV v2 backend instance using the secret client, keys
+// are stored under root of the given secret engine mount.
+func New(s *secret.Secret, mount, root string) (backends.AtomicBackend, error) {
+	if s == nil || s.Client == nil {
+		return nil, fmt.Errorf("vault: Secret client must not be nil")
+	}
+
+	return &vaultBackend{
+		secret: s,
+		mount:  strings.Trim(mount, "/"),
+		root:   strings.Trim(root, "/"),
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+func (b *vaultBackend) Name() string {
+	return "vault"
+}
+
+func (b *vaultBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	value, _, err := b.read(ctx, key)
+	return value, err
+}
+
+func (b *vaultBackend) Set(ctx context.Context, key string, value []byte) error {
+	_, err := b.secret.Client.Logical().WriteWithContext(ctx, b.dataPath(key), map[string]interface{}{
+		"data": map[string]interface{}{
+			"value": base64.StdEncoding.EncodeToString(value),
+		},
+	})
+	return err
+}
+
+func (b *vaultBackend) List(ctx context.Context, key string) ([]string, error) {
+	s, err := b.secret.Client.Logical().ListWithContext(ctx, b.metadataPath(key))
+	if err != nil {
+		return nil, err
+	}
+	if s == nil || s.Data == nil {
+		return nil, nil
+	}
+
+	keys, _ := s.Data["keys"].([]interface{})
+
+	var result []string
+	for _, k := range keys {
+		name, ok := k.(string)
+		// Skip sub folders
+		if !ok || strings.HasSuffix(name, "/") {
+			continue
+		}
+		result = append(result, name)
+	}
+
+	return result, nil
+}
+
+func (b *vaultBackend) CompareAndSwap(ctx context.Context, key string, old, value []byte) (bool, error) {
+	// A zero check-and-set version only succeeds if the key does not exist
+	var version int64
+	if old != nil {
+		current, v, err := b.read(ctx, key)
+		switch {
+		case err == backends.ErrKeyNotFound:
+			return false, nil
+		case err != nil:
+			return false, err
+		case string(current) != string(old):
+			return false, nil
+		}
+		version = v
+	}
+
+	_, err := b.secret.Client.Logical().WriteWithContext(ctx, b.dataPath(key), map[string]interface{}{
+		"options": map[string]interface{}{
+			"cas": version,
+		},
+		"data": map[string]interface{}{
+			"value": base64.StdEncoding.EncodeToString(value),
+		},
+	})
+	if err != nil {
+		if isCASMismatch(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (b *vaultBackend) Delete(ctx context.Context, key string) error {
+	s, err := b.secret.Client.Logical().ReadWithContext(ctx, b.metadataPath(key))
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return backends.ErrKeyNotFound
+	}
+
+	// Delete all versions
+	_, err = b.secret.Client.Logical().DeleteWithContext(ctx, b.metadataPath(key))
+	return err
+}
+
+func (b *vaultBackend) WatchPrefix(ctx context.Context, prefix string, opts ...backends.WatchOption) (uint64, error) {
+	return 0, backends.ErrWatchNotSupported
+}
+
+func (b *vaultBackend) Close(ctx context.Context) error {
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (b *vaultBackend) dataPath(key string) string {
+	return path.Join(b.mount, "data", b.root, key)
+}
+
+func (b *vaultBackend) metadataPath(key string) string {
+	return path.Join(b.mount, "metadata", b.root, key)
+}
+
+// read returns the decoded value and its version
+func (b *vaultBackend) read(ctx context.Context, key string) ([]byte, int64, error) {
+	s, err := b.secret.Client.Logical().ReadWithContext(ctx, b.dataPath(key))
+	if err != nil {
+		return nil, 0, err
+	}
+	if s == nil || s.Data == nil {
+		return nil, 0, backends.ErrKeyNotFound
+	}
+
+	// Data is nil when the latest version is deleted
+	data, ok := s.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, 0, backends.ErrKeyNotFound
+	}
+	encoded, ok := data["value"].(string)
+	if !ok {
+		return nil, 0, fmt.Errorf("vault: Invalid value for key %s", key)
+	}
+	value, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, 0, fmt.Errorf("vault: Unable to decode value for key %s: %v", key, err)
+	}
+
+	var version int64
+	if metadata, ok := s.Data["metadata"].(map[string]interface{}); ok {
+		switch v := metadata["version"].(type) {
+		case json.Number:
+			version, _ = v.Int64()
+		case float64:
+			version = int64(v)
+		}
+	}
+
+	return value, version, nil
+}
+
+// isCASMismatch reports whether err is the KV v2 rejection of a check-and-set
+// version mismatch, other bad requests are genuine failures
+func isCASMismatch(err error) bool {
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	for _, msg := range respErr.Errors {
+		if strings.Contains(msg, casMismatch) {
+			return true
+		}
+	}
+	return false
+}
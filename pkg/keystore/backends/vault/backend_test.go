@@ -0,0 +1,209 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/scraly/go.common/pkg/keystore/backends/backendtest"
+	"github.com/scraly/go.common/pkg/secret"
+
+	"github.com/dchest/uniuri"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKV serves the subset of the Vault KV v2 HTTP API used by the backend,
+// mounted at secret
+type fakeKV struct {
+	sync.Mutex
+
+	versions map[string]int64
+	values   map[string]map[string]interface{}
+}
+
+func newFakeKV() *httptest.Server {
+	return httptest.NewServer(&fakeKV{
+		versions: map[string]int64{},
+		values:   map[string]map[string]interface{}{},
+	})
+}
+
+func (f *fakeKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+		f.data(w, r, strings.TrimPrefix(r.URL.Path, "/v1/secret/data/"))
+	case strings.HasPrefix(r.URL.Path, "/v1/secret/metadata/"):
+		f.metadata(w, r, strings.TrimPrefix(r.URL.Path, "/v1/secret/metadata/"))
+	default:
+		reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+	}
+}
+
+func (f *fakeKV) data(w http.ResponseWriter, r *http.Request, key string) {
+	switch r.Method {
+	case http.MethodGet:
+		value, ok := f.values[key]
+		if !ok {
+			reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		reply(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     value,
+				"metadata": map[string]interface{}{"version": f.versions[key]},
+			},
+		})
+	case http.MethodPut, http.MethodPost:
+		var body struct {
+			Options map[string]interface{} `json:"options"`
+			Data    map[string]interface{} `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			reply(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{err.Error()}})
+			return
+		}
+		if cas, ok := body.Options["cas"].(float64); ok && int64(cas) != f.versions[key] {
+			reply(w, http.StatusBadRequest, map[string]interface{}{
+				"errors": []string{"check-and-set parameter did not match the current version"},
+			})
+			return
+		}
+
+		f.versions[key]++
+		f.values[key] = body.Data
+		reply(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"version": f.versions[key]},
+		})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeKV) metadata(w http.ResponseWriter, r *http.Request, key string) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("list") == "true":
+		prefix := strings.TrimSuffix(key, "/") + "/"
+		names := map[string]bool{}
+		for k := range f.values {
+			if !strings.HasPrefix(k, prefix) {
+				continue
+			}
+			name := strings.TrimPrefix(k, prefix)
+			if i := strings.Index(name, "/"); i >= 0 {
+				name = name[:i+1]
+			}
+			names[name] = true
+		}
+		if len(names) == 0 {
+			reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+
+		keys := make([]string, 0, len(names))
+		for name := range names {
+			keys = append(keys, name)
+		}
+		sort.Strings(keys)
+		reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"keys": keys}})
+	case r.Method == http.MethodGet:
+		if _, ok := f.values[key]; !ok {
+			reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		reply(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"current_version": f.versions[key]},
+		})
+	case r.Method == http.MethodDelete:
+		delete(f.values, key)
+		delete(f.versions, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// -----------------------------------------------------------------------------
+
+func TestBackend(t *testing.T) {
+	server := newFakeKV()
+	defer server.Close()
+
+	s, err := secret.New("token", server.URL)
+	require.NoError(t, err)
+
+	backend, err := New(s, "secret", "keystore")
+	require.NoError(t, err)
+
+	backendtest.Run(t, backend)
+}
+
+func TestBackend_Canceled(t *testing.T) {
+	server := newFakeKV()
+	defer server.Close()
+
+	s, err := secret.New("token", server.URL)
+	require.NoError(t, err)
+
+	backend, err := New(s, "secret", "keystore")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = backend.Get(ctx, "key")
+	require.Error(t, err, "Canceled context should abort the request")
+	require.Error(t, backend.Set(ctx, "key", []byte("value")), "Canceled context should abort the request")
+}
+
+func TestBackend_BadRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{"invalid request"}})
+	}))
+	defer server.Close()
+
+	s, err := secret.New("token", server.URL)
+	require.NoError(t, err)
+
+	backend, err := New(s, "secret", "keystore")
+	require.NoError(t, err)
+
+	ok, err := backend.CompareAndSwap(context.Background(), "key", nil, []byte("value"))
+	require.Error(t, err, "Bad request other than a version mismatch should be raised")
+	require.False(t, ok)
+}
+
+// TestBackend_Server runs against the Vault server given by VAULT_ADDR and VAULT_TOKEN,
+// using the KV v2 engine mounted at VAULT_KV_MOUNT (defaults to secret)
+func TestBackend_Server(t *testing.T) {
+	addr, token := os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		t.Skip("VAULT_ADDR and VAULT_TOKEN are required")
+	}
+	mount := os.Getenv("VAULT_KV_MOUNT")
+	if mount == "" {
+		mount = "secret"
+	}
+
+	s, err := secret.New(token, addr)
+	require.NoError(t, err)
+
+	backend, err := New(s, mount, "keystore-test-"+uniuri.NewLen(8))
+	require.NoError(t, err)
+
+	backendtest.Run(t, backend)
+}
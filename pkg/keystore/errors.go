@@ -9,4 +9,6 @@ var (
 	ErrKeyNotFound = errors.New("keystore: Key not found")
 	// ErrGeneratorNeedPositiveValueAboveOne is raised when caller gives a value under 1 as count
 	ErrGeneratorNeedPositiveValueAboveOne = errors.New("keystore: Key generation count needs positive above 1 value as count")
+	// ErrSymmetricKeyNeedsEncryption is raised when adding a shared secret key without private key transformer
+	ErrSymmetricKeyNeedsEncryption = errors.New("keystore: Symmetric key needs private key encryption")
 )
@@ -0,0 +1,88 @@
+package keystore
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// EventTopic is the eventbus topic keystore events are published to
+const EventTopic = "keystore.key"
+
+// EventType describes a keystore change
+type EventType string
+
+const (
+	// KeyAdded is emitted when a key appears in the keystore
+	KeyAdded EventType = "added"
+	// KeyRemoved is emitted when a key is removed from the keystore
+	KeyRemoved EventType = "removed"
+	// KeyExpired is emitted when a key is dropped because it expired
+	KeyExpired EventType = "expired"
+)
+
+// Event is a keystore change notification
+type Event struct {
+	Type  EventType `json:"type"`
+	KeyID string    `json:"kid"`
+	Time  time.Time `json:"time"`
+}
+
+// Observable is implemented by keystores emitting change events
+type Observable interface {
+	// Subscribe registers fn for all future events, the returned function
+	// cancels the subscription
+	Subscribe(fn func(Event)) func()
+}
+
+// -----------------------------------------------------------------------------
+
+// subscribers dispatches events synchronously in subscription order
+type subscribers struct {
+	sync.RWMutex
+
+	next uint64
+	fns  map[uint64]func(Event)
+}
+
+func (s *subscribers) subscribe(fn func(Event)) func() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.fns == nil {
+		s.fns = make(map[uint64]func(Event))
+	}
+	id := s.next
+	s.next++
+	s.fns[id] = fn
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.Lock()
+			delete(s.fns, id)
+			s.Unlock()
+		})
+	}
+}
+
+func (s *subscribers) notify(events []Event) {
+	s.RLock()
+	ids := make([]uint64, 0, len(s.fns))
+	for id := range s.fns {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	fns := make([]func(Event), 0, len(ids))
+	for _, id := range ids {
+		fns = append(fns, s.fns[id])
+	}
+	s.RUnlock()
+
+	// Callbacks run without lock, they may cancel their subscription
+	for _, e := range events {
+		for _, fn := range fns {
+			fn(e)
+		}
+	}
+}
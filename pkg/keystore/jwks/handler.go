@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package jwks
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/scraly/go.common/pkg/keystore"
+	"github.com/scraly/go.common/pkg/keystore/key"
+	"github.com/scraly/go.common/pkg/log"
+	"go.uber.org/zap"
+)
+
+// WellKnownPath is the conventional JWKS document location
+const WellKnownPath = "/.well-known/jwks.json"
+
+// Set is a JWK Set document: RFC 7517 Section 5
+type Set struct {
+	Keys []key.Key `json:"keys"`
+}
+
+// Handler serves the keystore public keys as a JWK Set. Responses carry an
+// ETag and may be cached by clients for maxAge.
+func Handler(ks keystore.KeyStore, maxAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		keys, err := ks.OnlyPublicKeys(r.Context())
+		if err != nil {
+			log.For(r.Context()).Error("Unable to retrieve public keys", zap.Error(err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		// Stable ordering for a stable ETag
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].ID() < keys[j].ID()
+		})
+		if keys == nil {
+			keys = []key.Key{}
+		}
+
+		body, err := json.Marshal(&Set{Keys: keys})
+		if err != nil {
+			log.For(r.Context()).Error("Unable to marshal JWK set", zap.Error(err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		etag := fmt.Sprintf(`"%s"`, base64.RawURLEncoding.EncodeToString(sum[:16]))
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(maxAge/time.Second)))
+		if matchETag(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, err = w.Write(body)
+			log.CheckErr("Unable to write JWK set", err)
+		}
+	})
+}
+
+func matchETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package jwks_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/elliptic"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "crypto/sha256"
+
+	"github.com/scraly/go.common/pkg/keystore"
+	"github.com/scraly/go.common/pkg/keystore/backends/inmemory"
+	"github.com/scraly/go.common/pkg/keystore/jwks"
+	"github.com/scraly/go.common/pkg/keystore/key"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKeyStore(t *testing.T) keystore.KeyStore {
+	backend, err := inmemory.New()
+	require.NoError(t, err)
+	ks, err := keystore.New(backend)
+	require.NoError(t, err)
+	return ks
+}
+
+func addKey(t *testing.T, ks keystore.KeyStore, generator key.Generator) key.Key {
+	ctx := context.Background()
+	k, err := ks.Generate(ctx, generator)
+	require.NoError(t, err)
+	require.NoError(t, ks.Add(ctx, k))
+	return k
+}
+
+func TestHandler(t *testing.T) {
+	ks := newKeyStore(t)
+	addKey(t, ks, key.Ed25519)
+	handler := jwks.Handler(ks, time.Hour)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, jwks.WellKnownPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "public, max-age=3600", rec.Header().Get("Cache-Control"))
+	require.NotEmpty(t, rec.Header().Get("ETag"))
+	require.Contains(t, rec.Body.String(), `"keys":[{`)
+	require.NotContains(t, rec.Body.String(), `"d":`, "Private keys should not be published")
+
+	req := httptest.NewRequest(http.MethodGet, jwks.WellKnownPath, nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotModified, rec.Code)
+	require.Empty(t, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, jwks.WellKnownPath, nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestRemoteKeyStore(t *testing.T) {
+	ctx := context.Background()
+	source := newKeyStore(t)
+	k1 := addKey(t, source, key.Ed25519)
+
+	var fetched, notModified int32
+	handler := jwks.Handler(source, time.Minute)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetched, 1)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if rec.Code == http.StatusNotModified {
+			atomic.AddInt32(&notModified, 1)
+		}
+		for name, values := range rec.Header() {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(rec.Body.Bytes())
+	}))
+	defer server.Close()
+
+	remote, err := jwks.New(server.URL, jwks.WithMinRefreshInterval(time.Millisecond))
+	require.NoError(t, err)
+
+	// Keys are fetched on first use
+	pub, err := remote.Get(ctx, k1.ID())
+	require.NoError(t, err)
+	require.False(t, pub.HasPrivate())
+
+	data := []byte("toto")
+	sig, err := k1.Sign(data)
+	require.NoError(t, err)
+	require.NoError(t, pub.Verify(data, sig), "Remote key should verify signatures")
+
+	// Unknown key triggers a conditional refresh
+	time.Sleep(5 * time.Millisecond)
+	_, err = remote.Get(ctx, "unknown")
+	require.Equal(t, keystore.ErrKeyNotFound, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&fetched))
+	require.Equal(t, int32(1), atomic.LoadInt32(&notModified), "Unchanged set should not be downloaded again")
+
+	// Newly published key is discovered
+	k2 := addKey(t, source, key.ECDSA(elliptic.P256(), crypto.SHA256))
+	time.Sleep(5 * time.Millisecond)
+	_, err = remote.Get(ctx, k2.ID())
+	require.NoError(t, err)
+
+	keys, err := remote.All(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	require.Equal(t, keystore.ErrNotImplemented, remote.Add(ctx, k1))
+}
+
+func TestRemoteKeyStore_Unavailable(t *testing.T) {
+	ctx := context.Background()
+
+	var fetched int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetched, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	remote, err := jwks.New(server.URL, jwks.WithMinRefreshInterval(40*time.Millisecond))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := remote.Get(ctx, "kid")
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), atomic.LoadInt32(&fetched), "Failed fetch should be shared by concurrent callers")
+
+	_, err = remote.All(ctx)
+	require.Error(t, err, "Last error should be returned while throttled")
+	require.Equal(t, int32(1), atomic.LoadInt32(&fetched))
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = remote.All(ctx)
+	require.Error(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&fetched), "Fetch should be retried after the delay")
+
+	// Delay doubles after consecutive failures
+	time.Sleep(60 * time.Millisecond)
+	_, err = remote.All(ctx)
+	require.Error(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&fetched), "Retry delay should back off")
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = remote.All(ctx)
+	require.Error(t, err)
+	require.Equal(t, int32(3), atomic.LoadInt32(&fetched))
+}
+
+func TestRemoteKeyStore_Monitor(t *testing.T) {
+	ctx := context.Background()
+	source := newKeyStore(t)
+	addKey(t, source, key.Ed25519)
+
+	server := httptest.NewServer(jwks.Handler(source, time.Minute))
+	defer server.Close()
+
+	remote, err := jwks.New(server.URL, jwks.WithInterval(10*time.Millisecond), jwks.WithMinRefreshInterval(0))
+	require.NoError(t, err)
+	remote.StartMonitor(ctx)
+	defer remote.Close()
+
+	k2 := addKey(t, source, key.Ed25519)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err = remote.Get(ctx, k2.ID()); err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err, "Monitor should synchronize new keys")
+}
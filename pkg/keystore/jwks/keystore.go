@@ -0,0 +1,327 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package jwks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/scraly/go.common/pkg/keystore"
+	"github.com/scraly/go.common/pkg/keystore/key"
+	"github.com/scraly/go.common/pkg/log"
+	"go.uber.org/zap"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxDocumentSize bounds the accepted JWK set size
+	maxDocumentSize = 1 << 20
+	// defaultRetryDelay is the first retry delay after a failed refresh when
+	// unknown key refreshes are disabled
+	defaultRetryDelay = time.Second
+)
+
+type remoteKeyStore struct {
+	sync.RWMutex
+
+	url   string
+	dopts *Options
+	done  context.CancelFunc
+
+	keys   map[string]key.Key
+	etag   string
+	loaded bool
+
+	// lastAttempt is the time of the last fetch, failures the number of
+	// consecutive failed fetches and lastErr the error of the last one
+	lastAttempt time.Time
+	failures    uint
+	lastErr     error
+
+	// refreshing serializes refreshes
+	refreshing sync.Mutex
+}
+
+// New returns a read-only keystore exposing the keys published at the given
+// JWK set URL. Keys are fetched on first use and refreshed by StartMonitor.
+func New(url string, opts ...Option) (keystore.KeyStore, error) {
+	// Default Options
+	options := &Options{
+		Client:             http.DefaultClient,
+		Interval:           5 * time.Minute,
+		MinRefreshInterval: 10 * time.Second,
+	}
+
+	// Overrides with option
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if url == "" {
+		return nil, errors.New("jwks: URL must not be blank")
+	}
+
+	return &remoteKeyStore{
+		url:   url,
+		dopts: options,
+		keys:  map[string]key.Key{},
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+func (ks *remoteKeyStore) Generate(ctx context.Context, generator key.Generator) (key.Key, error) {
+	k, err := generator(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: Key generation error %v", err)
+	}
+
+	return k, nil
+}
+
+func (ks *remoteKeyStore) All(ctx context.Context) ([]key.Key, error) {
+	if err := ks.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+
+	ks.RLock()
+	defer ks.RUnlock()
+
+	var result []key.Key
+	for _, k := range ks.keys {
+		result = append(result, k)
+	}
+
+	return result, nil
+}
+
+func (ks *remoteKeyStore) OnlyPublicKeys(ctx context.Context) ([]key.Key, error) {
+	keys, err := ks.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, k := range keys {
+		keys[i] = k.Public()
+	}
+
+	return keys, nil
+}
+
+func (ks *remoteKeyStore) Add(context.Context, ...key.Key) error {
+	return keystore.ErrNotImplemented
+}
+
+func (ks *remoteKeyStore) Get(ctx context.Context, id string) (key.Key, error) {
+	if err := ks.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+
+	if k, ok := ks.lookup(id); ok {
+		return k, nil
+	}
+
+	// Unknown key may have been published since last refresh
+	if ks.dopts.MinRefreshInterval > 0 {
+		if throttled, _ := ks.throttled(); !throttled {
+			if err := ks.refresh(ctx, false); err != nil {
+				log.For(ctx).Warn("Unable to refresh JWK set", zap.String("url", ks.url), zap.Error(err))
+			} else if k, ok := ks.lookup(id); ok {
+				return k, nil
+			}
+		}
+	}
+
+	return nil, keystore.ErrKeyNotFound
+}
+
+func (ks *remoteKeyStore) Remove(context.Context, string) error {
+	return keystore.ErrNotImplemented
+}
+
+func (ks *remoteKeyStore) StartMonitor(ctx context.Context) {
+	// Initialize a default context
+	ctx, cancel := context.WithCancel(ctx)
+	ks.done = cancel
+
+	// Fork the monitor process
+	go ks.monitor(ctx)
+}
+
+func (ks *remoteKeyStore) Close() {
+	if ks.done != nil {
+		ks.done()
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func (ks *remoteKeyStore) monitor(ctx context.Context) {
+	for {
+		if err := ks.refresh(ctx, true); err != nil {
+			log.For(ctx).Error("Unable to refresh JWK set", zap.String("url", ks.url), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ks.dopts.Interval):
+		}
+	}
+}
+
+func (ks *remoteKeyStore) lookup(id string) (key.Key, bool) {
+	ks.RLock()
+	defer ks.RUnlock()
+
+	k, ok := ks.keys[id]
+	return k, ok
+}
+
+func (ks *remoteKeyStore) ensureLoaded(ctx context.Context) error {
+	ks.RLock()
+	loaded := ks.loaded
+	ks.RUnlock()
+
+	if loaded {
+		return nil
+	}
+	return ks.refresh(ctx, false)
+}
+
+// throttled returns true if the last fetch is too recent to fetch again, and
+// the error of the last fetch. Delay doubles with each consecutive failure, up
+// to the monitor interval.
+func (ks *remoteKeyStore) throttled() (bool, error) {
+	ks.RLock()
+	defer ks.RUnlock()
+
+	delay := ks.dopts.MinRefreshInterval
+	if ks.failures > 0 {
+		if delay <= 0 {
+			delay = defaultRetryDelay
+		}
+		for i := uint(1); i < ks.failures && delay < ks.dopts.Interval; i++ {
+			delay *= 2
+		}
+		if delay > ks.dopts.Interval {
+			delay = ks.dopts.Interval
+		}
+	}
+
+	return time.Since(ks.lastAttempt) < delay, ks.lastErr
+}
+
+// refresh fetches the JWK set unless throttled, the last error is returned
+// when throttled. Concurrent callers wait for the running fetch and share its
+// outcome.
+func (ks *remoteKeyStore) refresh(ctx context.Context, force bool) error {
+	ks.refreshing.Lock()
+	defer ks.refreshing.Unlock()
+
+	if !force {
+		if throttled, err := ks.throttled(); throttled {
+			return err
+		}
+	}
+
+	err := ks.fetch(ctx)
+
+	ks.Lock()
+	ks.lastAttempt = time.Now()
+	ks.lastErr = err
+	if err != nil {
+		ks.failures++
+	} else {
+		ks.failures = 0
+	}
+	ks.Unlock()
+
+	return err
+}
+
+func (ks *remoteKeyStore) fetch(ctx context.Context) error {
+	ks.RLock()
+	etag := ks.etag
+	ks.RUnlock()
+
+	req, err := http.NewRequest(http.MethodGet, ks.url, nil)
+	if err != nil {
+		return errors.Wrap(err, "jwks: Unable to build request")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := ks.dopts.Client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "jwks: Unable to fetch JWK set")
+	}
+	defer func(body io.ReadCloser) {
+		log.SafeClose(body, "Unable to close response body")
+	}(resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil
+	case http.StatusOK:
+	default:
+		return fmt.Errorf("jwks: Unexpected status code %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxDocumentSize))
+	if err != nil {
+		return errors.Wrap(err, "jwks: Unable to read JWK set")
+	}
+
+	keys, err := decodeSet(ctx, body)
+	if err != nil {
+		return err
+	}
+
+	ks.Lock()
+	ks.keys = keys
+	ks.etag = resp.Header.Get("ETag")
+	ks.loaded = true
+	ks.Unlock()
+
+	return nil
+}
+
+// decodeSet decodes a JWK set, skipping unsupported keys
+func decodeSet(ctx context.Context, body []byte) (map[string]key.Key, error) {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &set); err != nil {
+		return nil, errors.Wrap(err, "jwks: Unable to decode JWK set")
+	}
+
+	keys := map[string]key.Key{}
+	for _, raw := range set.Keys {
+		k, err := key.FromString(raw)
+		if err != nil {
+			log.For(ctx).Warn("Ignoring unsupported JWK", zap.Error(err))
+			continue
+		}
+		keys[k.ID()] = k
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package jwks
+
+import (
+	"net/http"
+	"time"
+)
+
+// Options contains remote keystore settings
+type Options struct {
+	// Client is the HTTP client used to fetch the JWK set
+	Client *http.Client
+	// Interval is the refresh period used by StartMonitor
+	Interval time.Duration
+	// MinRefreshInterval throttles refreshes triggered by unknown key lookups,
+	// and is the first retry delay after a failed refresh
+	MinRefreshInterval time.Duration
+}
+
+// Option configures the remote keystore
+type Option func(*Options)
+
+// WithClient sets the HTTP client
+func WithClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.Client = client
+	}
+}
+
+// WithInterval sets the refresh period
+func WithInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.Interval = interval
+	}
+}
+
+// WithMinRefreshInterval sets the minimum delay between two refreshes
+// triggered by unknown key lookups, zero disables them
+func WithMinRefreshInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.MinRefreshInterval = interval
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
-	"encoding/base64"
 	"encoding/json"
 	"math/big"
 	"time"
@@ -23,35 +22,54 @@ type ecdsaKey struct {
 
 	alg   crypto.Hash
 	curve elliptic.Curve
+	// encryption keys are used for ECDH-ES key agreement only
+	encryption bool
+
+	x509Chain
 }
 
-// ECDSA key holder
+// ECDSA key holder, used for signature only
 func ECDSA(curve elliptic.Curve, alg crypto.Hash) func(context.Context) (Key, error) {
 	return func(ctx context.Context) (Key, error) {
+		return generateECDSA(curve, alg, false)
+	}
+}
 
-		// Generate a RSA keypair
-		privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
-		if err != nil {
-			return nil, err
-		}
-
-		// Extract public key
-		publicKey := privateKey.Public().(*ecdsa.PublicKey)
+// ECDHES key holder, used for ECDH-ES key agreement only
+func ECDHES(curve elliptic.Curve) func(context.Context) (Key, error) {
+	return func(ctx context.Context) (Key, error) {
+		alg, _ := ecdsaHash(curve)
+		return generateECDSA(curve, alg, true)
+	}
+}
 
-		// Create the hasher
-		if !alg.Available() {
-			return nil, ErrAlgorithmNotSupported
-		}
+func generateECDSA(curve elliptic.Curve, alg crypto.Hash, encryption bool) (Key, error) {
+	// Create the hasher
+	if !alg.Available() {
+		return nil, ErrAlgorithmNotSupported
+	}
+	if expected, _ := ecdsaHash(curve); expected != alg {
+		return nil, ErrAlgorithmNotSupported
+	}
 
-		return &ecdsaKey{
-			kid:       uniuri.NewLen(12),
-			timestamp: time.Now().UTC(),
-			priv:      privateKey,
-			pub:       publicKey,
-			alg:       alg,
-			curve:     curve,
-		}, nil
+	// Generate an ECDSA keypair
+	privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
 	}
+
+	// Extract public key
+	publicKey := privateKey.Public().(*ecdsa.PublicKey)
+
+	return &ecdsaKey{
+		kid:        uniuri.NewLen(12),
+		timestamp:  time.Now().UTC(),
+		priv:       privateKey,
+		pub:        publicKey,
+		alg:        alg,
+		curve:      curve,
+		encryption: encryption,
+	}, nil
 }
 
 // -----------------------------------------------------------------------------
@@ -60,6 +78,15 @@ func (k *ecdsaKey) ID() string {
 	return k.kid
 }
 
+func (k *ecdsaKey) Algorithm() string {
+	if k.encryption {
+		return k.KeyAlgorithm()
+	}
+
+	_, name := ecdsaHash(k.curve)
+	return name
+}
+
 func (k *ecdsaKey) HasPrivate() bool {
 	return k.priv != nil
 }
@@ -70,14 +97,22 @@ func (k *ecdsaKey) HasPublic() bool {
 
 func (k *ecdsaKey) Public() Key {
 	return &ecdsaKey{
-		kid: k.ID(),
-		pub: k.pub,
+		timestamp:  k.timestamp,
+		kid:        k.ID(),
+		pub:        k.pub,
+		alg:        k.alg,
+		curve:      k.curve,
+		encryption: k.encryption,
+		x509Chain:  k.x509Chain,
 	}
 }
 
 // -----------------------------------------------------------------------------
 
 func (k *ecdsaKey) Sign(data []byte) ([]byte, error) {
+	if k.encryption {
+		return nil, ErrInvalidOperationKeyUse
+	}
 	if !k.HasPrivate() {
 		return nil, ErrInvalidOperationCouldSignWithoutPrivateKey
 	}
@@ -93,37 +128,40 @@ func (k *ecdsaKey) Sign(data []byte) ([]byte, error) {
 		return nil, errors.Wrap(err, "Unable to hash given data")
 	}
 
-	keysiz := k.alg.Size()
-	curveBits := k.curve.Params().BitSize
-	if curveBits != keysiz*8 {
-		return nil, errors.New("key size does not match curve bit size")
-	}
-
-	// Sign the string and return r, s
+	// Sign the string and return r || s, left-padded to the curve size (RFC 7518 Section 3.4)
 	r, s, err := ecdsa.Sign(rand.Reader, k.priv, hasher.Sum(nil))
 	if err != nil {
 		return nil, err
 	}
+
+	keysiz := curveSize(k.curve)
 	out := make([]byte, keysiz*2)
-	copy(out, r.Bytes())
-	copy(out[keysiz:], s.Bytes())
+	rb, sb := r.Bytes(), s.Bytes()
+	copy(out[keysiz-len(rb):keysiz], rb)
+	copy(out[2*keysiz-len(sb):], sb)
 
 	return out, nil
 }
 
 func (k *ecdsaKey) Verify(data, sig []byte) error {
+	if k.encryption {
+		return ErrInvalidOperationKeyUse
+	}
 	if !k.HasPublic() {
 		return ErrInvalidOperationCouldVerifyWithoutPublicKey
 	}
 
 	// Hash given data
+	if !k.alg.Available() {
+		return ErrAlgorithmNotSupported
+	}
 	hasher := k.alg.New()
 	if _, err := hasher.Write(data); err != nil {
 		return errors.Wrap(err, "Unable to hash given data")
 	}
 
-	curveOrderByteSize := k.pub.Curve.Params().P.BitLen() / 8
-	if len(sig) < curveOrderByteSize {
+	curveOrderByteSize := curveSize(k.pub.Curve)
+	if len(sig) != 2*curveOrderByteSize {
 		return ErrInvalidSignature
 	}
 
@@ -147,15 +185,118 @@ func (k *ecdsaKey) MarshalJSON() ([]byte, error) {
 		KeyID:         k.ID(),
 		KeyType:       "EC",
 		Curve:         k.pub.Params().Name,
-		X:             base64.RawURLEncoding.EncodeToString(k.pub.X.Bytes()),
-		Y:             base64.RawURLEncoding.EncodeToString(k.pub.Y.Bytes()),
-		PublicKeyUse:  "sig",
+		Algorithm:     k.Algorithm(),
+		X:             encodeFixed(k.pub.X, curveSize(k.pub.Curve)),
+		Y:             encodeFixed(k.pub.Y, curveSize(k.pub.Curve)),
+		PublicKeyUse:  useSignature,
 		KeyOperations: []string{"verify"},
 	}
+	if k.encryption {
+		r.PublicKeyUse, r.KeyOperations = useEncryption, nil
+	}
 	if k.HasPrivate() {
-		r.D = base64.RawURLEncoding.EncodeToString(k.priv.D.Bytes())
-		r.KeyOperations = append(r.KeyOperations, "sign")
+		r.D = encodeFixed(k.priv.D, curveSize(k.pub.Curve))
+		if !k.encryption {
+			r.KeyOperations = append(r.KeyOperations, "sign")
+		}
 	}
+	k.x509Chain.marshal(r)
 
 	return json.Marshal(r)
 }
+
+// -----------------------------------------------------------------------------
+
+// ecdsaHash returns the hash and JWA algorithm name associated to the curve
+func ecdsaHash(curve elliptic.Curve) (crypto.Hash, string) {
+	switch curve {
+	case elliptic.P256():
+		return crypto.SHA256, "ES256"
+	case elliptic.P384():
+		return crypto.SHA384, "ES384"
+	case elliptic.P521():
+		return crypto.SHA512, "ES512"
+	}
+	return 0, ""
+}
+
+// curveSize returns the byte length of curve coordinates
+func curveSize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
+
+// -----------------------------------------------------------------------------
+
+func (k *ecdsaKey) KeyAlgorithm() string {
+	return "ECDH-ES"
+}
+
+func (k *ecdsaKey) Encrypt(plaintext []byte) (string, error) {
+	if !k.encryption {
+		return "", ErrInvalidOperationKeyUse
+	}
+	if !k.HasPublic() {
+		return "", ErrInvalidOperationCouldEncryptWithoutPublicKey
+	}
+	return encrypt(k, plaintext)
+}
+
+func (k *ecdsaKey) Decrypt(token string) ([]byte, error) {
+	if !k.encryption {
+		return nil, ErrInvalidOperationKeyUse
+	}
+	return decrypt(k, token)
+}
+
+func (k *ecdsaKey) wrapKey(h *jweHeader, size int) ([]byte, []byte, error) {
+	ephemeral, err := ecdsa.GenerateKey(k.pub.Curve, rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h.EphemeralKey = &rawJWK{
+		KeyType: "EC",
+		Curve:   k.pub.Params().Name,
+		X:       encodeFixed(ephemeral.X, curveSize(k.pub.Curve)),
+		Y:       encodeFixed(ephemeral.Y, curveSize(k.pub.Curve)),
+	}
+
+	// Direct key agreement, no encrypted key
+	z := k.sharedSecret(k.pub.X, k.pub.Y, ephemeral.D)
+	return concatKDF(z, h.Encryption, size), nil, nil
+}
+
+func (k *ecdsaKey) unwrapKey(h *jweHeader, encryptedKey []byte, size int) ([]byte, error) {
+	epk := h.EphemeralKey
+	if len(encryptedKey) != 0 || epk == nil || epk.KeyType != "EC" || epk.Curve != k.pub.Params().Name {
+		return nil, ErrInvalidEncryptedPayload
+	}
+
+	x, err := decodeBigInt(epk.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(epk.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject invalid curve points
+	if !k.pub.Curve.IsOnCurve(x, y) {
+		return nil, ErrInvalidEncryptedPayload
+	}
+
+	z := k.sharedSecret(x, y, k.priv.D)
+	return concatKDF(z, h.Encryption, size), nil
+}
+
+func (k *ecdsaKey) sharedSecret(x, y, d *big.Int) []byte {
+	zx, _ := k.pub.Curve.ScalarMult(x, y, d.Bytes())
+
+	size := curveSize(k.pub.Curve)
+	z := make([]byte, size)
+	b := zx.Bytes()
+	copy(z[size-len(b):], b)
+
+	return z
+}
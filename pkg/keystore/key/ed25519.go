@@ -16,6 +16,8 @@ type ed25519Key struct {
 	kid       string
 	priv      ed25519.PrivateKey
 	pub       ed25519.PublicKey
+
+	x509Chain
 }
 
 // Ed25519 key holder
@@ -40,6 +42,10 @@ func (k *ed25519Key) ID() string {
 	return k.kid
 }
 
+func (k *ed25519Key) Algorithm() string {
+	return "EdDSA"
+}
+
 func (k *ed25519Key) HasPrivate() bool {
 	return len(k.priv) > 0
 }
@@ -50,8 +56,9 @@ func (k *ed25519Key) HasPublic() bool {
 
 func (k *ed25519Key) Public() Key {
 	return &ed25519Key{
-		kid: k.ID(),
-		pub: k.pub,
+		kid:       k.ID(),
+		pub:       k.pub,
+		x509Chain: k.x509Chain,
 	}
 }
 
@@ -82,7 +89,7 @@ func (k *ed25519Key) MarshalJSON() ([]byte, error) {
 	r := &rawJWK{
 		KeyID:         k.ID(),
 		KeyType:       "OKP",
-		Algorithm:     "EC",
+		Algorithm:     k.Algorithm(),
 		Curve:         "Ed25519",
 		X:             base64.RawURLEncoding.EncodeToString(k.pub),
 		PublicKeyUse:  "sig",
@@ -92,6 +99,7 @@ func (k *ed25519Key) MarshalJSON() ([]byte, error) {
 		r.D = base64.RawURLEncoding.EncodeToString(k.priv)
 		r.KeyOperations = append(r.KeyOperations, "sign")
 	}
+	k.x509Chain.marshal(r)
 
 	return json.Marshal(r)
 }
@@ -2,6 +2,9 @@ package key_test
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
 	"testing"
 
 	pkg "github.com/scraly/go.common/pkg/keystore/key"
@@ -82,3 +85,84 @@ func KeyOperationsErrorTest(generator pkg.Generator) func(*testing.T) {
 		require.Empty(t, sig, "Signature should be empty on error")
 	}
 }
+
+func JWKRoundTripTest(generator pkg.Generator) func(*testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		data := []byte("toto")
+
+		key, err := generator(ctx)
+		require.NoError(t, err, "Error should not be raised on generation")
+
+		sig, err := key.Sign(data)
+		require.NoError(t, err, "Error should not be raised on signature")
+
+		raw, err := json.Marshal(key.Public())
+		require.NoError(t, err, "Error should not be raised on encoding")
+
+		pub, err := pkg.FromString(raw)
+		require.NoError(t, err, "Error should not be raised on decoding")
+		require.Equal(t, key.ID(), pub.ID(), "Key identifiers should be equals")
+		require.Equal(t, key.Algorithm(), pub.Algorithm(), "Key algorithms should be equals")
+		require.False(t, pub.HasPrivate(), "Public Key should not have a private key")
+		require.NoError(t, pub.Verify(data, sig), "Decoded public key should verify the signature")
+
+		raw, err = json.Marshal(key)
+		require.NoError(t, err, "Error should not be raised on encoding")
+
+		priv, err := pkg.FromString(raw)
+		require.NoError(t, err, "Error should not be raised on decoding")
+		require.True(t, priv.HasPrivate(), "Decoded key should have a private key")
+
+		sig, err = priv.Sign(data)
+		require.NoError(t, err, "Error should not be raised on signature")
+		require.NoError(t, key.Verify(data, sig), "Decoded private key should sign")
+	}
+}
+
+func EncryptAndDecryptTest(generator pkg.Generator) func(*testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		data := []byte("toto")
+
+		key, err := generator(ctx)
+		require.NoError(t, err, "Error should not be raised on generation")
+
+		enc, ok := key.(pkg.Encrypter)
+		require.True(t, ok, "Key should support encryption")
+
+		// Encrypt with the decoded public JWK, as a partner would do
+		raw, err := json.Marshal(key.Public())
+		require.NoError(t, err, "Error should not be raised on encoding")
+		pub, err := pkg.FromString(raw)
+		require.NoError(t, err, "Error should not be raised on decoding")
+
+		token, err := pub.(pkg.Encrypter).Encrypt(data)
+		require.NoError(t, err, "Error should not be raised on encryption")
+		require.Len(t, strings.Split(token, "."), 5, "Token should be a compact JWE")
+
+		plaintext, err := enc.Decrypt(token)
+		require.NoError(t, err, "Error should not be raised on decryption")
+		require.Equal(t, data, plaintext, "Plaintext should be equals")
+
+		// Decrypt with public key
+		_, err = pub.(pkg.Encrypter).Decrypt(token)
+		require.Equal(t, pkg.ErrInvalidOperationCouldDecryptWithoutPrivateKey, err, "Error should be as expected")
+
+		// Tampered ciphertext
+		parts := strings.Split(token, ".")
+		parts[3] = base64.RawURLEncoding.EncodeToString([]byte("titi"))
+		_, err = enc.Decrypt(strings.Join(parts, "."))
+		require.Equal(t, pkg.ErrInvalidEncryptedPayload, err, "Error should be as expected")
+
+		// Another key of the same type
+		other, err := generator(ctx)
+		require.NoError(t, err, "Error should not be raised on generation")
+		_, err = other.(pkg.Encrypter).Decrypt(token)
+		require.Error(t, err, "Error should be raised when decrypting with another key")
+	}
+}
@@ -0,0 +1,131 @@
+package key
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/dchest/uniuri"
+)
+
+type hmacKey struct {
+	timestamp time.Time
+	kid       string
+	secret    []byte
+
+	alg crypto.Hash
+}
+
+// HMAC shared secret key holder, the secret size is the hash output size
+func HMAC(alg crypto.Hash) func(context.Context) (Key, error) {
+	return func(ctx context.Context) (Key, error) {
+		if _, ok := hmacAlgorithm(alg); !ok || !alg.Available() {
+			return nil, ErrAlgorithmNotSupported
+		}
+
+		// Generate the secret
+		secret := make([]byte, alg.Size())
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+
+		return &hmacKey{
+			kid:       uniuri.NewLen(12),
+			timestamp: time.Now().UTC(),
+			secret:    secret,
+			alg:       alg,
+		}, nil
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func (k *hmacKey) ID() string {
+	return k.kid
+}
+
+func (k *hmacKey) Algorithm() string {
+	name, _ := hmacAlgorithm(k.alg)
+	return name
+}
+
+func (k *hmacKey) HasPrivate() bool {
+	return len(k.secret) > 0
+}
+
+// HasPublic is always false, the secret is needed for both operations
+func (k *hmacKey) HasPublic() bool {
+	return false
+}
+
+// Public returns the key without its secret
+func (k *hmacKey) Public() Key {
+	return &hmacKey{
+		timestamp: k.timestamp,
+		kid:       k.ID(),
+		alg:       k.alg,
+	}
+}
+
+func (k *hmacKey) Sign(data []byte) ([]byte, error) {
+	if !k.HasPrivate() {
+		return nil, ErrInvalidOperationCouldSignWithoutPrivateKey
+	}
+
+	mac := hmac.New(k.alg.New, k.secret)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+func (k *hmacKey) Verify(data, sig []byte) error {
+	if !k.HasPrivate() {
+		return ErrInvalidOperationCouldVerifyWithoutPublicKey
+	}
+
+	mac := hmac.New(k.alg.New, k.secret)
+	mac.Write(data)
+
+	// Constant time comparison
+	if !hmac.Equal(mac.Sum(nil), sig) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (k *hmacKey) symmetric() {}
+
+// -----------------------------------------------------------------------------
+
+func hmacAlgorithm(alg crypto.Hash) (string, bool) {
+	switch alg {
+	case crypto.SHA256:
+		return "HS256", true
+	case crypto.SHA384:
+		return "HS384", true
+	case crypto.SHA512:
+		return "HS512", true
+	}
+	return "", false
+}
+
+// -----------------------------------------------------------------------------
+
+func (k *hmacKey) MarshalJSON() ([]byte, error) {
+	r := &rawJWK{
+		KeyID:        k.ID(),
+		KeyType:      "oct",
+		Algorithm:    k.Algorithm(),
+		PublicKeyUse: "sig",
+	}
+	if k.HasPrivate() {
+		r.K = base64.RawURLEncoding.EncodeToString(k.secret)
+		r.KeyOperations = []string{"sign", "verify"}
+	}
+
+	return json.Marshal(r)
+}
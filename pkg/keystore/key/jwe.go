@@ -0,0 +1,184 @@
+package key
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+)
+
+// contentEncryption is the default JWE content encryption algorithm
+const contentEncryption = "A256GCM"
+
+// jweHeader is the JOSE header of a JWE: RFC 7516 Section 4
+type jweHeader struct {
+	Algorithm    string  `json:"alg"`
+	Encryption   string  `json:"enc"`
+	KeyID        string  `json:"kid,omitempty"`
+	EphemeralKey *rawJWK `json:"epk,omitempty"`
+}
+
+// keyManager implements a JWE key management mode for a key type
+type keyManager interface {
+	Key
+	KeyAlgorithm() string
+	// wrapKey returns a content encryption key of the given size and its
+	// encrypted form, key agreement parameters are set in the header
+	wrapKey(h *jweHeader, size int) (cek, encryptedKey []byte, err error)
+	// unwrapKey returns the content encryption key
+	unwrapKey(h *jweHeader, encryptedKey []byte, size int) ([]byte, error)
+}
+
+// encrypt builds a compact JWE: RFC 7516 Section 7.1
+func encrypt(k keyManager, plaintext []byte) (string, error) {
+	h := &jweHeader{
+		Algorithm:  k.KeyAlgorithm(),
+		Encryption: contentEncryption,
+		KeyID:      k.ID(),
+	}
+
+	cek, encryptedKey, err := k.wrapKey(h, contentKeySize(contentEncryption))
+	if err != nil {
+		return "", err
+	}
+
+	rawHeader, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(rawHeader)
+
+	aead, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+
+	// Protected header is the additional authenticated data
+	sealed := aead.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-aead.Overhead()], sealed[len(sealed)-aead.Overhead():]
+
+	return strings.Join([]string{
+		protected,
+		base64.RawURLEncoding.EncodeToString(encryptedKey),
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+// decrypt opens a compact JWE
+func decrypt(k keyManager, token string) ([]byte, error) {
+	if !k.HasPrivate() {
+		return nil, ErrInvalidOperationCouldDecryptWithoutPrivateKey
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return nil, ErrInvalidEncryptedPayload
+	}
+
+	var segments [5][]byte
+	for i, part := range parts {
+		b, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			return nil, ErrInvalidEncryptedPayload
+		}
+		segments[i] = b
+	}
+
+	h := &jweHeader{}
+	if err := json.Unmarshal(segments[0], h); err != nil {
+		return nil, ErrInvalidEncryptedPayload
+	}
+	if h.Algorithm != k.KeyAlgorithm() {
+		return nil, ErrAlgorithmNotSupported
+	}
+	if h.KeyID != "" && h.KeyID != k.ID() {
+		return nil, ErrInvalidEncryptedPayload
+	}
+	size := contentKeySize(h.Encryption)
+	if size == 0 {
+		return nil, ErrAlgorithmNotSupported
+	}
+
+	cek, err := k.unwrapKey(h, segments[1], size)
+	if err != nil {
+		return nil, ErrInvalidEncryptedPayload
+	}
+
+	aead, err := newGCM(cek)
+	if err != nil {
+		return nil, ErrInvalidEncryptedPayload
+	}
+	if len(segments[2]) != aead.NonceSize() || len(segments[4]) != aead.Overhead() {
+		return nil, ErrInvalidEncryptedPayload
+	}
+
+	sealed := append(segments[3], segments[4]...)
+	plaintext, err := aead.Open(nil, segments[2], sealed, []byte(parts[0]))
+	if err != nil {
+		return nil, ErrInvalidEncryptedPayload
+	}
+
+	return plaintext, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func contentKeySize(enc string) int {
+	switch enc {
+	case "A128GCM":
+		return 16
+	case "A192GCM":
+		return 24
+	case "A256GCM":
+		return 32
+	}
+	return 0
+}
+
+func newGCM(cek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// concatKDF derives the ECDH-ES content encryption key from the shared
+// secret: RFC 7518 Section 4.6.2, with empty party information
+func concatKDF(z []byte, enc string, size int) []byte {
+	otherInfo := lengthPrefixed([]byte(enc))
+	otherInfo = append(otherInfo, lengthPrefixed(nil)...)
+	otherInfo = append(otherInfo, lengthPrefixed(nil)...)
+	suppPubInfo := make([]byte, 4)
+	binary.BigEndian.PutUint32(suppPubInfo, uint32(size*8))
+	otherInfo = append(otherInfo, suppPubInfo...)
+
+	var out []byte
+	counter := make([]byte, 4)
+	for i := uint32(1); len(out) < size; i++ {
+		binary.BigEndian.PutUint32(counter, i)
+		h := sha256.New()
+		h.Write(counter)
+		h.Write(z)
+		h.Write(otherInfo)
+		out = h.Sum(out)
+	}
+
+	return out[:size]
+}
+
+func lengthPrefixed(data []byte) []byte {
+	out := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(out, uint32(len(data)))
+	return append(out, data...)
+}
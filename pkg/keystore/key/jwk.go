@@ -1,8 +1,10 @@
 package key
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/rsa"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -11,6 +13,12 @@ import (
 	"golang.org/x/crypto/ed25519"
 )
 
+// Public key uses: RFC 7517 Section 4.2
+const (
+	useSignature  = "sig"
+	useEncryption = "enc"
+)
+
 // rawJWK implements the internal representation for serialzing/deserializing a JWK: RFC 7517 Section 4
 type rawJWK struct {
 	IssuedAt                 int64    `json:"iat,omitempty"`
@@ -50,6 +58,7 @@ func toEd25519(raw *rawJWK) (Key, error) {
 	}
 
 	k := &ed25519Key{
+		kid: raw.KeyID,
 		pub: x,
 	}
 	if len(raw.D) > 0 {
@@ -66,6 +75,67 @@ func toEd25519(raw *rawJWK) (Key, error) {
 	return k, nil
 }
 
+func toX25519(raw *rawJWK) (Key, error) {
+	x, err := base64.RawURLEncoding.DecodeString(raw.X)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(x) != x25519KeySize {
+		return nil, errors.New("key: invalid x25519 public key size")
+	}
+
+	k := &x25519Key{
+		kid: raw.KeyID,
+		pub: x,
+	}
+	if len(raw.D) > 0 {
+		d, err := base64.RawURLEncoding.DecodeString(raw.D)
+		if err != nil {
+			return nil, err
+		}
+		if len(d) != x25519KeySize {
+			return nil, errors.New("key: invalid x25519 private key size")
+		}
+		k.priv = d
+	}
+
+	return k, nil
+}
+
+func toHMAC(raw *rawJWK) (Key, error) {
+	var alg crypto.Hash
+	switch raw.Algorithm {
+	case "", "HS256":
+		alg = crypto.SHA256
+	case "HS384":
+		alg = crypto.SHA384
+	case "HS512":
+		alg = crypto.SHA512
+	default:
+		return nil, ErrAlgorithmNotSupported
+	}
+
+	k := &hmacKey{
+		kid: raw.KeyID,
+		alg: alg,
+	}
+	if len(raw.K) > 0 {
+		secret, err := base64.RawURLEncoding.DecodeString(raw.K)
+		if err != nil {
+			return nil, err
+		}
+
+		// Secret must be at least the hash output size: RFC 7518 Section 3.2
+		if len(secret) < alg.Size() {
+			return nil, errors.New("key: invalid hmac key size")
+		}
+		k.secret = secret
+	}
+
+	return k, nil
+}
+
 func toECDSA(raw *rawJWK) (Key, error) {
 	if raw.Curve == "" || raw.X == "" || raw.Y == "" {
 		return nil, errors.New("key: malformed JWK EC key")
@@ -103,10 +173,18 @@ func toECDSA(raw *rawJWK) (Key, error) {
 	}
 	pubKey.Y.SetBytes(yBytes)
 
+	encryption, err := isEncryption(raw, "ECDH-ES")
+	if err != nil {
+		return nil, err
+	}
+
+	alg, _ := ecdsaHash(curve)
 	key := &ecdsaKey{
-		curve: curve,
-		kid:   raw.KeyID,
-		pub:   pubKey,
+		curve:      curve,
+		alg:        alg,
+		kid:        raw.KeyID,
+		pub:        pubKey,
+		encryption: encryption,
 	}
 
 	if len(raw.D) > 0 {
@@ -126,3 +204,114 @@ func toECDSA(raw *rawJWK) (Key, error) {
 
 	return key, nil
 }
+
+func toRSA(raw *rawJWK) (Key, error) {
+	if raw.N == "" || raw.E == "" {
+		return nil, errors.New("key: malformed JWK RSA key")
+	}
+
+	alg := crypto.SHA256
+	switch raw.Algorithm {
+	case "", "RS256", "RSA-OAEP-256":
+	case "RS384":
+		alg = crypto.SHA384
+	case "RS512":
+		alg = crypto.SHA512
+	default:
+		return nil, ErrAlgorithmNotSupported
+	}
+	encryption, err := isEncryption(raw, "RSA-OAEP-256")
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := decodeBigInt(raw.N)
+	if err != nil {
+		return nil, fmt.Errorf("key: malformed JWK RSA key, %s", err)
+	}
+	e, err := decodeBigInt(raw.E)
+	if err != nil {
+		return nil, fmt.Errorf("key: malformed JWK RSA key, %s", err)
+	}
+
+	pubKey := &rsa.PublicKey{
+		N: n,
+		E: int(e.Int64()),
+	}
+
+	key := &rsaKey{
+		kid:        raw.KeyID,
+		pub:        pubKey,
+		alg:        alg,
+		encryption: encryption,
+	}
+
+	if len(raw.D) > 0 {
+		d, err := decodeBigInt(raw.D)
+		if err != nil {
+			return nil, fmt.Errorf("key: malformed JWK RSA key, %s", err)
+		}
+		p, err := decodeBigInt(raw.P)
+		if err != nil {
+			return nil, fmt.Errorf("key: malformed JWK RSA key, %s", err)
+		}
+		q, err := decodeBigInt(raw.Q)
+		if err != nil {
+			return nil, fmt.Errorf("key: malformed JWK RSA key, %s", err)
+		}
+
+		privKey := &rsa.PrivateKey{
+			PublicKey: *pubKey,
+			D:         d,
+			Primes:    []*big.Int{p, q},
+		}
+		if err := privKey.Validate(); err != nil {
+			return nil, fmt.Errorf("key: malformed JWK RSA key, %s", err)
+		}
+		privKey.Precompute()
+
+		key.priv = privKey
+	}
+
+	return key, nil
+}
+
+// isEncryption returns true if the JWK is an encryption key, the use is
+// inferred from the key management algorithm when missing
+func isEncryption(raw *rawJWK, keyAlgorithm string) (bool, error) {
+	use := raw.PublicKeyUse
+	if use == "" {
+		use = useSignature
+		if raw.Algorithm == keyAlgorithm {
+			use = useEncryption
+		}
+	}
+
+	switch {
+	case use != useSignature && use != useEncryption:
+		return false, fmt.Errorf("key: unsupported JWK key use %s", use)
+	case raw.Algorithm != "" && (use == useEncryption) != (raw.Algorithm == keyAlgorithm):
+		return false, fmt.Errorf("key: JWK algorithm %s does not match key use %s", raw.Algorithm, use)
+	}
+
+	return use == useEncryption, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// encodeFixed returns the base64url encoding of the value left-padded to size bytes
+func encodeFixed(value *big.Int, size int) string {
+	b := value.Bytes()
+	if len(b) < size {
+		padded := make([]byte, size)
+		copy(padded[size-len(b):], b)
+		b = padded
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
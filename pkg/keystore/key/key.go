@@ -1,10 +1,15 @@
 package key
 
-import "errors"
+import (
+	"crypto/x509"
+	"errors"
+)
 
 // Key contract for key information holder
 type Key interface {
 	ID() string
+	// Algorithm returns the JWA signature algorithm name (RFC 7518 Section 3.1)
+	Algorithm() string
 	HasPrivate() bool
 	HasPublic() bool
 	Public() Key
@@ -12,6 +17,31 @@ type Key interface {
 	Verify(data []byte, sig []byte) error
 }
 
+// Encrypter is implemented by keys supporting JWE key management
+type Encrypter interface {
+	Key
+	// KeyAlgorithm returns the JWE key management algorithm name (RFC 7518 Section 4.1)
+	KeyAlgorithm() string
+	// Encrypt returns the plaintext as a compact JWE for the key owner
+	Encrypt(plaintext []byte) (string, error)
+	// Decrypt returns the plaintext of a compact JWE encrypted for the key
+	Decrypt(token string) ([]byte, error)
+}
+
+// Certified is implemented by keys bound to an X.509 certificate chain
+type Certified interface {
+	Key
+	// Certificates returns the certificate chain, leaf first, nil if the key is not certified
+	Certificates() []*x509.Certificate
+}
+
+// Symmetric is implemented by shared secret keys, they have no public part and
+// must only be stored encrypted
+type Symmetric interface {
+	Key
+	symmetric()
+}
+
 // -----------------------------------------------------------------------------
 
 var (
@@ -23,4 +53,16 @@ var (
 	ErrInvalidOperationCouldVerifyWithoutPublicKey = errors.New("key: invalid operation : could not verify without a public key")
 	// ErrAlgorithmNotSupported is raised when using not load algorithm (missing imports)
 	ErrAlgorithmNotSupported = errors.New("key: Algorithm not supported")
+	// ErrInvalidOperationCouldEncryptWithoutPublicKey is raised when trying to encrypt without the public key
+	ErrInvalidOperationCouldEncryptWithoutPublicKey = errors.New("key: invalid operation : could not encrypt without a public key")
+	// ErrInvalidOperationKeyUse is raised when using a signature key for encryption, or an encryption key for signature
+	ErrInvalidOperationKeyUse = errors.New("key: invalid operation : not allowed by the key use")
+	// ErrInvalidOperationCouldDecryptWithoutPrivateKey is raised when trying to decrypt using the public key
+	ErrInvalidOperationCouldDecryptWithoutPrivateKey = errors.New("key: invalid operation : could not decrypt without a private key")
+	// ErrInvalidEncryptedPayload is raised when the JWE could not be decrypted
+	ErrInvalidEncryptedPayload = errors.New("key: invalid encrypted payload")
+	// ErrNoCertificate is raised when the key has no X.509 certificate chain
+	ErrNoCertificate = errors.New("key: no certificate")
+	// ErrCertificateMismatch is raised when the certificate does not match the key
+	ErrCertificateMismatch = errors.New("key: certificate does not match the key")
 )
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto"
 	"crypto/elliptic"
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -19,7 +20,10 @@ var (
 	keyGenerators = map[string]pkg.Generator{
 		"ed25519":        pkg.Ed25519,
 		"ecp256-sha256":  pkg.ECDSA(elliptic.P256(), crypto.SHA256),
+		"ecp384-sha384":  pkg.ECDSA(elliptic.P384(), crypto.SHA384),
+		"ecp521-sha512":  pkg.ECDSA(elliptic.P521(), crypto.SHA512),
 		"rsa2048-sha256": pkg.RSA(2048, crypto.SHA256),
+		"rsa2048-sha512": pkg.RSA(2048, crypto.SHA512),
 	}
 )
 
@@ -41,6 +45,123 @@ func TestKeyOperationError(t *testing.T) {
 	}
 }
 
+func TestJWKRoundTrip(t *testing.T) {
+	for k, generator := range keyGenerators {
+		t.Run(fmt.Sprintf("case=%s", k), JWKRoundTripTest(generator))
+	}
+}
+
+func TestEncryptAndDecrypt(t *testing.T) {
+	generators := map[string]pkg.Generator{
+		"x25519":       pkg.X25519,
+		"ecdhes-p256":  pkg.ECDHES(elliptic.P256()),
+		"ecdhes-p384":  pkg.ECDHES(elliptic.P384()),
+		"ecdhes-p521":  pkg.ECDHES(elliptic.P521()),
+		"rsaoaep-2048": pkg.RSAOAEP(2048),
+	}
+	for k, generator := range generators {
+		t.Run(fmt.Sprintf("case=%s", k), EncryptAndDecryptTest(generator))
+	}
+}
+
+func TestKeyUse(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("toto")
+
+	for _, generator := range []pkg.Generator{
+		keyGenerators["ecp256-sha256"],
+		keyGenerators["rsa2048-sha256"],
+	} {
+		key, err := generator(ctx)
+		require.NoError(t, err)
+
+		_, err = key.(pkg.Encrypter).Encrypt(data)
+		require.Equal(t, pkg.ErrInvalidOperationKeyUse, err, "Signature keys should not encrypt")
+	}
+
+	for alg, generator := range map[string]pkg.Generator{
+		"ECDH-ES":      pkg.ECDHES(elliptic.P256()),
+		"RSA-OAEP-256": pkg.RSAOAEP(2048),
+	} {
+		key, err := generator(ctx)
+		require.NoError(t, err)
+
+		_, err = key.Sign(data)
+		require.Equal(t, pkg.ErrInvalidOperationKeyUse, err, "Encryption keys should not sign")
+
+		raw, err := json.Marshal(key.Public())
+		require.NoError(t, err)
+		var jwk map[string]interface{}
+		require.NoError(t, json.Unmarshal(raw, &jwk))
+		require.Equal(t, "enc", jwk["use"], "Encryption keys should be exported for encryption")
+		require.Equal(t, alg, jwk["alg"], "Encryption keys should advertise the key management algorithm")
+
+		pub, err := pkg.FromString(raw)
+		require.NoError(t, err)
+		require.Equal(t, alg, pub.Algorithm(), "Decoded key should keep its algorithm")
+		require.Equal(t, pkg.ErrInvalidOperationKeyUse, pub.Verify(data, nil), "Decoded encryption key should not verify")
+	}
+}
+
+func TestX25519Signature(t *testing.T) {
+	key, err := pkg.X25519(context.Background())
+	require.NoError(t, err)
+
+	_, err = key.Sign([]byte("toto"))
+	require.Equal(t, pkg.ErrAlgorithmNotSupported, err, "X25519 keys should not sign")
+}
+
+func TestHMAC(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("toto")
+
+	for name, alg := range map[string]crypto.Hash{"HS256": crypto.SHA256, "HS384": crypto.SHA384, "HS512": crypto.SHA512} {
+		key, err := pkg.HMAC(alg)(ctx)
+		require.NoError(t, err, "Error should not be raised on generation")
+		require.Equal(t, name, key.Algorithm(), "Key algorithm should be as expected")
+		require.True(t, key.HasPrivate(), "Key should have a secret")
+		require.Implements(t, (*pkg.Symmetric)(nil), key, "Key should be symmetric")
+
+		sig, err := key.Sign(data)
+		require.NoError(t, err, "Error should not be raised on signature")
+		require.NoError(t, key.Verify(data, sig), "Error should not be raised on verification")
+		sig[0] ^= 0xff
+		require.Equal(t, pkg.ErrInvalidSignature, key.Verify(data, sig), "Error should be as expected")
+
+		// Public form has no secret
+		pub := key.Public()
+		require.False(t, pub.HasPrivate(), "Public key should not have a secret")
+		require.False(t, pub.HasPublic(), "Symmetric key has no public part")
+		_, err = pub.Sign(data)
+		require.Equal(t, pkg.ErrInvalidOperationCouldSignWithoutPrivateKey, err, "Error should be as expected")
+		raw, err := json.Marshal(pub)
+		require.NoError(t, err, "Error should not be raised on encoding")
+		require.NotContains(t, string(raw), `"k"`, "Public JWK should not contain the secret")
+
+		// JWK round trip
+		raw, err = json.Marshal(key)
+		require.NoError(t, err, "Error should not be raised on encoding")
+		decoded, err := pkg.FromString(raw)
+		require.NoError(t, err, "Error should not be raised on decoding")
+		require.Equal(t, key.ID(), decoded.ID(), "Key identifiers should be equals")
+		require.Equal(t, key.Algorithm(), decoded.Algorithm(), "Key algorithms should be equals")
+		sig, err = decoded.Sign(data)
+		require.NoError(t, err, "Error should not be raised on signature")
+		require.NoError(t, key.Verify(data, sig), "Decoded key should sign")
+	}
+
+	_, err := pkg.HMAC(crypto.SHA1)(ctx)
+	require.Equal(t, pkg.ErrAlgorithmNotSupported, err, "Error should be as expected")
+
+	_, err = pkg.FromString([]byte(`{"kty":"oct","alg":"HS256","k":"dG90bw"}`))
+	require.Error(t, err, "Short secrets should be rejected")
+}
+
+func TestECDSACurveHashMismatch(t *testing.T) {
+	_, err := pkg.ECDSA(elliptic.P256(), crypto.SHA512)(context.Background())
+	require.Equal(t, pkg.ErrAlgorithmNotSupported, err, "Curve and hash should match")
+}
+
 func BenchmarkED25519KeySignature(b *testing.B) {
 	key, err := pkg.Ed25519(context.Background())
 	require.NoError(b, err)
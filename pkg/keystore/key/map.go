@@ -28,10 +28,31 @@ func FromMap(input map[string]interface{}) (Key, error) {
 	return fromRaw(&result)
 }
 
-// fromRaw returns a concrete instance from the rawJWK specification
+// fromRaw returns a concrete instance from the rawJWK specification, bound to
+// its certificate chain when present
 func fromRaw(raw *rawJWK) (Key, error) {
+	k, err := fromRawKey(raw)
+	if err != nil || len(raw.X509CertChain) == 0 {
+		return k, err
+	}
+
+	chain, err := decodeCertificates(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := setCertificates(k, chain); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+func fromRawKey(raw *rawJWK) (Key, error) {
 	switch raw.KeyType {
 	case "RSA":
+		return toRSA(raw)
+	case "oct":
+		return toHMAC(raw)
 	case "EC":
 		switch raw.Curve {
 		case "P-256", "P-384", "P-521":
@@ -41,6 +62,8 @@ func fromRaw(raw *rawJWK) (Key, error) {
 		switch raw.Curve {
 		case "Ed25519":
 			return toEd25519(raw)
+		case "X25519":
+			return toX25519(raw)
 		}
 	default:
 	}
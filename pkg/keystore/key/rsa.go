@@ -5,6 +5,7 @@ import (
 	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"math/big"
@@ -21,30 +22,45 @@ type rsaKey struct {
 	pub       *rsa.PublicKey
 
 	alg crypto.Hash
+	// encryption keys are used for RSA-OAEP-256 key management only
+	encryption bool
+
+	x509Chain
 }
 
-// RSA key holder
+// RSA key holder, used for signature only
 func RSA(size int, alg crypto.Hash) func(context.Context) (Key, error) {
 	return func(ctx context.Context) (Key, error) {
+		return generateRSA(size, alg, false)
+	}
+}
 
-		// Generate a RSA keypair
-		privateKey, err := rsa.GenerateKey(rand.Reader, size)
-		if err != nil {
-			return nil, err
-		}
-		privateKey.Precompute()
-
-		// Extract public key
-		publicKey := privateKey.Public().(*rsa.PublicKey)
+// RSAOAEP key holder, used for RSA-OAEP-256 key management only
+func RSAOAEP(size int) func(context.Context) (Key, error) {
+	return func(ctx context.Context) (Key, error) {
+		return generateRSA(size, crypto.SHA256, true)
+	}
+}
 
-		return &rsaKey{
-			kid:       uniuri.NewLen(12),
-			timestamp: time.Now().UTC(),
-			priv:      privateKey,
-			pub:       publicKey,
-			alg:       alg,
-		}, nil
+func generateRSA(size int, alg crypto.Hash, encryption bool) (Key, error) {
+	// Generate a RSA keypair
+	privateKey, err := rsa.GenerateKey(rand.Reader, size)
+	if err != nil {
+		return nil, err
 	}
+	privateKey.Precompute()
+
+	// Extract public key
+	publicKey := privateKey.Public().(*rsa.PublicKey)
+
+	return &rsaKey{
+		kid:        uniuri.NewLen(12),
+		timestamp:  time.Now().UTC(),
+		priv:       privateKey,
+		pub:        publicKey,
+		alg:        alg,
+		encryption: encryption,
+	}, nil
 }
 
 // -----------------------------------------------------------------------------
@@ -53,6 +69,20 @@ func (k *rsaKey) ID() string {
 	return k.kid
 }
 
+func (k *rsaKey) Algorithm() string {
+	if k.encryption {
+		return k.KeyAlgorithm()
+	}
+
+	switch k.alg {
+	case crypto.SHA384:
+		return "RS384"
+	case crypto.SHA512:
+		return "RS512"
+	}
+	return "RS256"
+}
+
 func (k *rsaKey) HasPrivate() bool {
 	return k.priv != nil
 }
@@ -63,14 +93,21 @@ func (k *rsaKey) HasPublic() bool {
 
 func (k *rsaKey) Public() Key {
 	return &rsaKey{
-		kid: k.ID(),
-		pub: k.pub,
+		timestamp:  k.timestamp,
+		kid:        k.ID(),
+		pub:        k.pub,
+		alg:        k.alg,
+		encryption: k.encryption,
+		x509Chain:  k.x509Chain,
 	}
 }
 
 // -----------------------------------------------------------------------------
 
 func (k *rsaKey) Sign(data []byte) ([]byte, error) {
+	if k.encryption {
+		return nil, ErrInvalidOperationKeyUse
+	}
 	if !k.HasPrivate() {
 		return nil, ErrInvalidOperationCouldSignWithoutPrivateKey
 	}
@@ -90,6 +127,9 @@ func (k *rsaKey) Sign(data []byte) ([]byte, error) {
 }
 
 func (k *rsaKey) Verify(data, sig []byte) error {
+	if k.encryption {
+		return ErrInvalidOperationKeyUse
+	}
 	if !k.HasPublic() {
 		return ErrInvalidOperationCouldVerifyWithoutPublicKey
 	}
@@ -114,15 +154,75 @@ func (k *rsaKey) MarshalJSON() ([]byte, error) {
 	r := &rawJWK{
 		KeyID:         k.ID(),
 		KeyType:       "RSA",
-		N:             base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.pub.E)).Bytes()),
-		E:             base64.RawURLEncoding.EncodeToString(k.pub.N.Bytes()),
-		PublicKeyUse:  "sig",
+		Algorithm:     k.Algorithm(),
+		N:             base64.RawURLEncoding.EncodeToString(k.pub.N.Bytes()),
+		E:             base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.pub.E)).Bytes()),
+		PublicKeyUse:  useSignature,
 		KeyOperations: []string{"verify"},
 	}
+	if k.encryption {
+		r.PublicKeyUse, r.KeyOperations = useEncryption, nil
+	}
 	if k.HasPrivate() {
 		r.D = base64.RawURLEncoding.EncodeToString(k.priv.D.Bytes())
-		r.KeyOperations = append(r.KeyOperations, "sign")
+		if len(k.priv.Primes) == 2 {
+			r.P = base64.RawURLEncoding.EncodeToString(k.priv.Primes[0].Bytes())
+			r.Q = base64.RawURLEncoding.EncodeToString(k.priv.Primes[1].Bytes())
+		}
+		if !k.encryption {
+			r.KeyOperations = append(r.KeyOperations, "sign")
+		}
 	}
+	k.x509Chain.marshal(r)
 
 	return json.Marshal(r)
 }
+
+// -----------------------------------------------------------------------------
+
+func (k *rsaKey) KeyAlgorithm() string {
+	return "RSA-OAEP-256"
+}
+
+func (k *rsaKey) Encrypt(plaintext []byte) (string, error) {
+	if !k.encryption {
+		return "", ErrInvalidOperationKeyUse
+	}
+	if !k.HasPublic() {
+		return "", ErrInvalidOperationCouldEncryptWithoutPublicKey
+	}
+	return encrypt(k, plaintext)
+}
+
+func (k *rsaKey) Decrypt(token string) ([]byte, error) {
+	if !k.encryption {
+		return nil, ErrInvalidOperationKeyUse
+	}
+	return decrypt(k, token)
+}
+
+func (k *rsaKey) wrapKey(_ *jweHeader, size int) ([]byte, []byte, error) {
+	cek := make([]byte, size)
+	if _, err := rand.Read(cek); err != nil {
+		return nil, nil, err
+	}
+
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, k.pub, cek, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cek, encryptedKey, nil
+}
+
+func (k *rsaKey) unwrapKey(_ *jweHeader, encryptedKey []byte, size int) ([]byte, error) {
+	cek, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, k.priv, encryptedKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(cek) != size {
+		return nil, ErrInvalidEncryptedPayload
+	}
+
+	return cek, nil
+}
@@ -0,0 +1,168 @@
+package key
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dchest/uniuri"
+	"golang.org/x/crypto/curve25519"
+)
+
+const x25519KeySize = 32
+
+type x25519Key struct {
+	timestamp time.Time
+	kid       string
+	priv      []byte
+	pub       []byte
+}
+
+// X25519 key holder, used for ECDH-ES key agreement only (RFC 8037)
+func X25519(context.Context) (Key, error) {
+	var priv, pub [x25519KeySize]byte
+	if _, err := rand.Read(priv[:]); err != nil {
+		return nil, err
+	}
+	curve25519.ScalarBaseMult(&pub, &priv)
+
+	return &x25519Key{
+		kid:       uniuri.NewLen(12),
+		timestamp: time.Now().UTC(),
+		priv:      priv[:],
+		pub:       pub[:],
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (k *x25519Key) ID() string {
+	return k.kid
+}
+
+func (k *x25519Key) Algorithm() string {
+	return k.KeyAlgorithm()
+}
+
+func (k *x25519Key) HasPrivate() bool {
+	return len(k.priv) > 0
+}
+
+func (k *x25519Key) HasPublic() bool {
+	return len(k.pub) > 0
+}
+
+func (k *x25519Key) Public() Key {
+	return &x25519Key{
+		timestamp: k.timestamp,
+		kid:       k.ID(),
+		pub:       k.pub,
+	}
+}
+
+func (k *x25519Key) Sign(data []byte) ([]byte, error) {
+	return nil, ErrAlgorithmNotSupported
+}
+
+func (k *x25519Key) Verify(data, sig []byte) error {
+	return ErrAlgorithmNotSupported
+}
+
+// -----------------------------------------------------------------------------
+
+func (k *x25519Key) KeyAlgorithm() string {
+	return "ECDH-ES"
+}
+
+func (k *x25519Key) Encrypt(plaintext []byte) (string, error) {
+	if !k.HasPublic() {
+		return "", ErrInvalidOperationCouldEncryptWithoutPublicKey
+	}
+	return encrypt(k, plaintext)
+}
+
+func (k *x25519Key) Decrypt(token string) ([]byte, error) {
+	return decrypt(k, token)
+}
+
+func (k *x25519Key) wrapKey(h *jweHeader, size int) ([]byte, []byte, error) {
+	var ephemeral, ephemeralPub [x25519KeySize]byte
+	if _, err := rand.Read(ephemeral[:]); err != nil {
+		return nil, nil, err
+	}
+	curve25519.ScalarBaseMult(&ephemeralPub, &ephemeral)
+
+	h.EphemeralKey = &rawJWK{
+		KeyType: "OKP",
+		Curve:   "X25519",
+		X:       base64.RawURLEncoding.EncodeToString(ephemeralPub[:]),
+	}
+
+	z, err := x25519SharedSecret(ephemeral[:], k.pub)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Direct key agreement, no encrypted key
+	return concatKDF(z, h.Encryption, size), nil, nil
+}
+
+func (k *x25519Key) unwrapKey(h *jweHeader, encryptedKey []byte, size int) ([]byte, error) {
+	epk := h.EphemeralKey
+	if len(encryptedKey) != 0 || epk == nil || epk.KeyType != "OKP" || epk.Curve != "X25519" {
+		return nil, ErrInvalidEncryptedPayload
+	}
+
+	pub, err := base64.RawURLEncoding.DecodeString(epk.X)
+	if err != nil {
+		return nil, err
+	}
+
+	z, err := x25519SharedSecret(k.priv, pub)
+	if err != nil {
+		return nil, err
+	}
+
+	return concatKDF(z, h.Encryption, size), nil
+}
+
+func x25519SharedSecret(priv, pub []byte) ([]byte, error) {
+	if len(priv) != x25519KeySize || len(pub) != x25519KeySize {
+		return nil, errors.New("key: invalid x25519 key size")
+	}
+
+	var scalar, point, z [x25519KeySize]byte
+	copy(scalar[:], priv)
+	copy(point[:], pub)
+	curve25519.ScalarMult(&z, &scalar, &point)
+
+	// Reject low order points
+	var zero [x25519KeySize]byte
+	if subtle.ConstantTimeCompare(z[:], zero[:]) == 1 {
+		return nil, errors.New("key: invalid x25519 public key")
+	}
+
+	return z[:], nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (k *x25519Key) MarshalJSON() ([]byte, error) {
+	r := &rawJWK{
+		KeyID:        k.ID(),
+		KeyType:      "OKP",
+		Algorithm:    k.KeyAlgorithm(),
+		Curve:        "X25519",
+		X:            base64.RawURLEncoding.EncodeToString(k.pub),
+		PublicKeyUse: useEncryption,
+	}
+	if k.HasPrivate() {
+		r.D = base64.RawURLEncoding.EncodeToString(k.priv)
+	}
+
+	return json.Marshal(r)
+}
@@ -0,0 +1,261 @@
+package key
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"time"
+)
+
+// x509Chain holds the certificate chain of a key, leaf first
+type x509Chain struct {
+	certs []*x509.Certificate
+}
+
+// Certificates returns the certificate chain, leaf first
+func (c x509Chain) Certificates() []*x509.Certificate {
+	return c.certs
+}
+
+// marshal sets the JWK X.509 parameters: RFC 7517 Section 4.7 to 4.9
+func (c x509Chain) marshal(r *rawJWK) {
+	if len(c.certs) == 0 {
+		return
+	}
+
+	for _, cert := range c.certs {
+		r.X509CertChain = append(r.X509CertChain, base64.StdEncoding.EncodeToString(cert.Raw))
+	}
+	r.X509Sha1Thumbprint = Thumbprint(c.certs[0])
+	r.X509CertSha256Thumbprint = ThumbprintSHA256(c.certs[0])
+}
+
+// -----------------------------------------------------------------------------
+
+// Thumbprint returns the base64url encoded SHA-1 thumbprint of the certificate (x5t)
+func Thumbprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ThumbprintSHA256 returns the base64url encoded SHA-256 thumbprint of the certificate (x5t#S256)
+func ThumbprintSHA256(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// FromCertificates builds a public key from a certificate chain, leaf first.
+// The key identifier is the SHA-256 thumbprint of the leaf certificate.
+func FromCertificates(chain ...*x509.Certificate) (Key, error) {
+	return fromCertificates(chain, nil)
+}
+
+// FromDER builds a public key from DER encoded certificates, leaf first
+func FromDER(der ...[]byte) (Key, error) {
+	chain := make([]*x509.Certificate, 0, len(der))
+	for _, b := range der {
+		cert, err := x509.ParseCertificate(b)
+		if err != nil {
+			return nil, fmt.Errorf("key: malformed certificate, %s", err)
+		}
+		chain = append(chain, cert)
+	}
+
+	return fromCertificates(chain, nil)
+}
+
+// FromPEM builds a key from PEM encoded certificates, leaf first. The private
+// key is restored when the input also contains a matching PKCS#8, PKCS#1 or
+// SEC 1 private key block.
+func FromPEM(data []byte) (Key, error) {
+	var (
+		chain []*x509.Certificate
+		priv  crypto.PrivateKey
+		err   error
+	)
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("key: malformed certificate, %s", err)
+			}
+			chain = append(chain, cert)
+		case "PRIVATE KEY":
+			priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			priv, err = x509.ParseECPrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key: malformed private key, %s", err)
+		}
+	}
+
+	return fromCertificates(chain, priv)
+}
+
+// VerifyCertificates validates the key certificate chain against the given
+// roots, such as a tlsconfig certificate pool, for the intended extended key
+// usages. The system pool is used when roots is nil, and server authentication
+// is required when no usage is given.
+func VerifyCertificates(k Key, roots *x509.CertPool, usages ...x509.ExtKeyUsage) error {
+	c, ok := k.(Certified)
+	if !ok || len(c.Certificates()) == 0 {
+		return ErrNoCertificate
+	}
+	chain := c.Certificates()
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     usages,
+	})
+	return err
+}
+
+// -----------------------------------------------------------------------------
+
+func fromCertificates(chain []*x509.Certificate, priv crypto.PrivateKey) (Key, error) {
+	if len(chain) == 0 {
+		return nil, ErrNoCertificate
+	}
+	leaf := chain[0]
+
+	// Private key must match the leaf certificate
+	if priv != nil {
+		signer, ok := priv.(crypto.Signer)
+		if !ok || !matchPublicKey(signer.Public(), leaf) {
+			return nil, ErrCertificateMismatch
+		}
+	}
+
+	var k Key
+	kid, timestamp := ThumbprintSHA256(leaf), time.Now().UTC()
+	switch pub := leaf.PublicKey.(type) {
+	case *rsa.PublicKey:
+		rk := &rsaKey{
+			kid:       kid,
+			timestamp: timestamp,
+			pub:       pub,
+			alg:       crypto.SHA256,
+		}
+		if priv != nil {
+			rk.priv = priv.(*rsa.PrivateKey)
+			rk.priv.Precompute()
+		}
+		k = rk
+	case *ecdsa.PublicKey:
+		alg, _ := ecdsaHash(pub.Curve)
+		if alg == 0 {
+			return nil, ErrAlgorithmNotSupported
+		}
+		ek := &ecdsaKey{
+			kid:       kid,
+			timestamp: timestamp,
+			pub:       pub,
+			alg:       alg,
+			curve:     pub.Curve,
+		}
+		if priv != nil {
+			ek.priv = priv.(*ecdsa.PrivateKey)
+		}
+		k = ek
+	case ed25519.PublicKey:
+		ek := &ed25519Key{
+			kid:       kid,
+			timestamp: timestamp,
+			pub:       []byte(pub),
+		}
+		if priv != nil {
+			ek.priv = []byte(priv.(ed25519.PrivateKey))
+		}
+		k = ek
+	default:
+		return nil, ErrAlgorithmNotSupported
+	}
+
+	if err := setCertificates(k, chain); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// decodeCertificates parses the JWK certificate chain and checks its thumbprints
+func decodeCertificates(raw *rawJWK) ([]*x509.Certificate, error) {
+	chain := make([]*x509.Certificate, 0, len(raw.X509CertChain))
+	for _, encoded := range raw.X509CertChain {
+		// Certificates are standard base64 encoded, not base64url: RFC 7517 Section 4.7
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key: malformed JWK x5c, %s", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("key: malformed JWK x5c, %s", err)
+		}
+		chain = append(chain, cert)
+	}
+
+	if raw.X509Sha1Thumbprint != "" && raw.X509Sha1Thumbprint != Thumbprint(chain[0]) {
+		return nil, ErrCertificateMismatch
+	}
+	if raw.X509CertSha256Thumbprint != "" && raw.X509CertSha256Thumbprint != ThumbprintSHA256(chain[0]) {
+		return nil, ErrCertificateMismatch
+	}
+
+	return chain, nil
+}
+
+// setCertificates binds the chain to the key once the leaf is checked
+func setCertificates(k Key, chain []*x509.Certificate) error {
+	var pub crypto.PublicKey
+	var c *x509Chain
+	switch k := k.(type) {
+	case *rsaKey:
+		pub, c = k.pub, &k.x509Chain
+	case *ecdsaKey:
+		pub, c = k.pub, &k.x509Chain
+	case *ed25519Key:
+		pub, c = ed25519.PublicKey(k.pub), &k.x509Chain
+	default:
+		return ErrAlgorithmNotSupported
+	}
+
+	if !matchPublicKey(pub, chain[0]) {
+		return ErrCertificateMismatch
+	}
+	c.certs = chain
+
+	return nil
+}
+
+func matchPublicKey(pub crypto.PublicKey, cert *x509.Certificate) bool {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(der, cert.RawSubjectPublicKeyInfo)
+}
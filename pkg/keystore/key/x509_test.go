@@ -0,0 +1,159 @@
+package key_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pkg "github.com/scraly/go.common/pkg/keystore/key"
+	"github.com/scraly/go.common/pkg/tlsconfig"
+
+	"github.com/stretchr/testify/require"
+)
+
+// issue creates a certificate for the public key, self-signed when parent is nil
+func issue(t *testing.T, name string, pub crypto.PublicKey, parent *x509.Certificate, signer crypto.Signer, isCA bool, usages ...x509.ExtKeyUsage) *x509.Certificate {
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		ExtKeyUsage:           usages,
+	}
+	if parent == nil {
+		parent = template
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+func TestCertificates(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ca := issue(t, "ca", caKey.Public(), nil, caKey, true)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	// CA file for the tlsconfig pool
+	dir, err := ioutil.TempDir("", "keystore-x509")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0600))
+	roots, err := tlsconfig.CertPool(caFile, true)
+	require.NoError(t, err)
+
+	for name, signer := range map[string]crypto.Signer{"rsa": rsaKey, "ecdsa": ecKey, "ed25519": edKey} {
+		signer := signer
+		t.Run("case="+name, func(t *testing.T) {
+			leaf := issue(t, name, signer.Public(), ca, caKey, false)
+
+			der, err := x509.MarshalPKCS8PrivateKey(signer)
+			require.NoError(t, err)
+			bundle := append(
+				pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}),
+				pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})...,
+			)
+
+			k, err := pkg.FromPEM(bundle)
+			require.NoError(t, err, "Error should not be raised on import")
+			require.True(t, k.HasPrivate(), "Key should have a private key")
+			require.Equal(t, pkg.ThumbprintSHA256(leaf), k.ID(), "Key identifier should be the certificate thumbprint")
+			require.Len(t, k.(pkg.Certified).Certificates(), 1, "Key should hold the certificate")
+
+			sig, err := k.Sign([]byte("toto"))
+			require.NoError(t, err, "Error should not be raised on signature")
+
+			// Public JWK keeps the certificate chain
+			raw, err := json.Marshal(k.Public())
+			require.NoError(t, err)
+			var jwk map[string]interface{}
+			require.NoError(t, json.Unmarshal(raw, &jwk))
+			require.Equal(t, pkg.Thumbprint(leaf), jwk["x5t"], "JWK should expose the SHA-1 thumbprint")
+			require.Equal(t, pkg.ThumbprintSHA256(leaf), jwk["x5t#S256"], "JWK should expose the SHA-256 thumbprint")
+
+			pub, err := pkg.FromString(raw)
+			require.NoError(t, err, "Error should not be raised on decoding")
+			require.NoError(t, pub.Verify([]byte("toto"), sig), "Decoded key should verify the signature")
+			require.Equal(t, leaf.Raw, pub.(pkg.Certified).Certificates()[0].Raw, "Decoded key should hold the certificate")
+
+			require.NoError(t, pkg.VerifyCertificates(pub, roots), "Chain should be trusted")
+			require.Error(t, pkg.VerifyCertificates(pub, x509.NewCertPool()), "Chain should not be trusted")
+
+			// Tampered thumbprint
+			jwk["x5t#S256"] = pkg.ThumbprintSHA256(ca)
+			raw, err = json.Marshal(jwk)
+			require.NoError(t, err)
+			_, err = pkg.FromString(raw)
+			require.Equal(t, pkg.ErrCertificateMismatch, err, "Error should be as expected")
+		})
+	}
+
+	t.Run("case=chain", func(t *testing.T) {
+		intermediate := issue(t, "intermediate", ecKey.Public(), ca, caKey, true)
+		leaf := issue(t, "leaf", rsaKey.Public(), intermediate, ecKey, false)
+
+		k, err := pkg.FromDER(leaf.Raw, intermediate.Raw)
+		require.NoError(t, err, "Error should not be raised on import")
+		require.False(t, k.HasPrivate(), "Key should not have a private key")
+		require.NoError(t, pkg.VerifyCertificates(k, roots), "Chain should be trusted")
+		require.Error(t, pkg.VerifyCertificates(k, x509.NewCertPool()), "Chain should not be trusted")
+	})
+
+	t.Run("case=usage", func(t *testing.T) {
+		leaf := issue(t, "client", rsaKey.Public(), ca, caKey, false, x509.ExtKeyUsageClientAuth)
+
+		k, err := pkg.FromDER(leaf.Raw)
+		require.NoError(t, err, "Error should not be raised on import")
+		require.NoError(t, pkg.VerifyCertificates(k, roots, x509.ExtKeyUsageClientAuth), "Chain should be trusted for client authentication")
+		require.Error(t, pkg.VerifyCertificates(k, roots), "Chain should not be trusted for server authentication")
+		require.Error(t, pkg.VerifyCertificates(k, roots, x509.ExtKeyUsageCodeSigning), "Chain should not be trusted for code signing")
+	})
+
+	t.Run("case=mismatch", func(t *testing.T) {
+		leaf := issue(t, "leaf", rsaKey.Public(), ca, caKey, false)
+
+		der, err := x509.MarshalPKCS8PrivateKey(ecKey)
+		require.NoError(t, err)
+		bundle := append(
+			pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}),
+			pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})...,
+		)
+
+		_, err = pkg.FromPEM(bundle)
+		require.Equal(t, pkg.ErrCertificateMismatch, err, "Error should be as expected")
+
+		_, err = pkg.FromCertificates()
+		require.Equal(t, pkg.ErrNoCertificate, err, "Error should be as expected")
+	})
+}
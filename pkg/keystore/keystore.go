@@ -10,9 +10,10 @@ import (
 	"github.com/scraly/go.common/pkg/keystore/backends"
 	"github.com/scraly/go.common/pkg/keystore/key"
 	"github.com/scraly/go.common/pkg/log"
+	"github.com/scraly/go.common/pkg/storage/value"
+	"github.com/scraly/go.common/pkg/storage/value/compress"
 	"go.uber.org/zap"
 
-	"github.com/golang/snappy"
 	"github.com/pkg/errors"
 )
 
@@ -23,7 +24,11 @@ type defaultKeyStore struct {
 	dopts *Options
 	done  context.CancelFunc
 
-	keys map[string]key.Key
+	keys   map[string]key.Key
+	events subscribers
+
+	// codec compresses JWK before they are stored
+	codec value.Transformer
 }
 
 // New returns a default keystore implementation instance
@@ -46,6 +51,16 @@ func New(backend backends.Backend, opts ...Option) (KeyStore, error) {
 		store: backend,
 		dopts: options,
 		keys:  make(map[string]key.Key),
+		codec: value.IdentityTransformer,
+	}
+
+	// Snappy compressed JWK have no compression header
+	if options.Snappy {
+		codec, err := compress.NewRawTransformer(compress.Snappy)
+		if err != nil {
+			return nil, err
+		}
+		ks.codec = codec
 	}
 
 	// Synchronize with backend
@@ -59,6 +74,8 @@ func (ks *defaultKeyStore) Generate(ctx context.Context, generator key.Generator
 		return nil, fmt.Errorf("keystore: Key generation error %v", err)
 	}
 
+	ks.audit(ctx, AuditGenerated, k.ID())
+
 	return k, nil
 }
 
@@ -80,6 +97,10 @@ func (ks *defaultKeyStore) OnlyPublicKeys(_ context.Context) ([]key.Key, error)
 
 	var result []key.Key
 	for _, i := range ks.keys {
+		// Shared secrets have no public part
+		if _, ok := i.(key.Symmetric); ok {
+			continue
+		}
 		result = append(result, i.Public())
 	}
 
@@ -88,40 +109,50 @@ func (ks *defaultKeyStore) OnlyPublicKeys(_ context.Context) ([]key.Key, error)
 
 func (ks *defaultKeyStore) Add(ctx context.Context, keys ...key.Key) error {
 	for _, k := range keys {
-		// Encode key
-		jwk, err := json.Marshal(k.Public())
-		if err != nil {
-			continue
-		}
-
-		if ks.dopts.Snappy {
-			// Compress JWK
-			jwk = snappy.Encode(nil, jwk)
-		}
-
 		// Wrap the key in a holder
 		holder := &keyHolder{
 			IssuedAt: time.Now().UTC().Unix(),
-			Data:     jwk,
 		}
-
-		// Marshal only public key to json
-		payload, err := json.Marshal(holder)
-		if err != nil {
-			return fmt.Errorf("keystore: Unable to marshal key as JSON: %v", err)
+		if err := ks.encode(k, holder); err != nil {
+			return err
 		}
 
-		// Add to backend
-		err = ks.store.Set(ctx, fmt.Sprintf("jwk/%s", k.ID()), payload)
-		if err != nil {
-			return fmt.Errorf("keystore: Unable to save key to backend: %v", err)
+		if err := ks.save(ctx, k.ID(), holder); err != nil {
+			return err
 		}
+
+		ks.audit(ctx, AuditAdded, k.ID())
 	}
 
 	// Synchronize the local cache
 	return ks.synchronize(ctx)
 }
 
+func (ks *defaultKeyStore) Expire(ctx context.Context, id string, date time.Time) error {
+	payload, err := ks.store.Get(ctx, fmt.Sprintf("jwk/%s", id))
+	switch {
+	case err == backends.ErrKeyNotFound:
+		return ErrKeyNotFound
+	case err != nil:
+		return fmt.Errorf("keystore: Unable to read key from backend: %v", err)
+	}
+
+	holder := &keyHolder{}
+	if err := json.Unmarshal(payload, holder); err != nil {
+		return fmt.Errorf("keystore: Unable to decode key holder: %v", err)
+	}
+	holder.ExpiresOn(date)
+
+	if err := ks.save(ctx, id, holder); err != nil {
+		return err
+	}
+
+	ks.audit(ctx, AuditExpired, id)
+
+	// Synchronize the local cache, already expired key is dropped
+	return ks.synchronize(ctx)
+}
+
 func (ks *defaultKeyStore) Get(_ context.Context, id string) (key.Key, error) {
 	ks.RLock()
 	defer ks.RUnlock()
@@ -133,16 +164,20 @@ func (ks *defaultKeyStore) Get(_ context.Context, id string) (key.Key, error) {
 	return ks.keys[id], nil
 }
 
-func (ks *defaultKeyStore) Remove(_ context.Context, id string) error {
-	ks.Lock()
-	defer ks.Unlock()
-
-	if _, ok := ks.keys[id]; ok {
-		delete(ks.keys, id)
-		return nil
+func (ks *defaultKeyStore) Remove(ctx context.Context, id string) error {
+	if err := ks.remove(ctx, id); err != nil {
+		return err
 	}
 
-	return ErrKeyNotFound
+	ks.audit(ctx, AuditRemoved, id)
+
+	ks.publish([]Event{{Type: KeyRemoved, KeyID: id, Time: time.Now().UTC()}})
+
+	return nil
+}
+
+func (ks *defaultKeyStore) Subscribe(fn func(Event)) func() {
+	return ks.events.subscribe(fn)
 }
 
 func (ks *defaultKeyStore) StartMonitor(ctx context.Context) {
@@ -151,7 +186,11 @@ func (ks *defaultKeyStore) StartMonitor(ctx context.Context) {
 	ks.done = cancel
 
 	// Fork the monitor process
-	go ks.monitor(ctx)
+	if ks.dopts.Watch {
+		go ks.watch(ctx)
+	} else {
+		go ks.monitor(ctx)
+	}
 }
 
 func (ks *defaultKeyStore) Close() {
@@ -162,6 +201,30 @@ func (ks *defaultKeyStore) Close() {
 
 // -----------------------------------------------------------------------------
 
+func (ks *defaultKeyStore) remove(ctx context.Context, id string) error {
+	ks.Lock()
+	defer ks.Unlock()
+
+	// Unpublish the key when the backend supports deletion
+	if deleter, ok := ks.store.(backends.AtomicBackend); ok {
+		err := deleter.Delete(ctx, fmt.Sprintf("jwk/%s", id))
+		switch {
+		case err == nil:
+			delete(ks.keys, id)
+			return nil
+		case err != backends.ErrKeyNotFound:
+			return fmt.Errorf("keystore: Unable to remove key from backend: %v", err)
+		}
+	}
+
+	if _, ok := ks.keys[id]; ok {
+		delete(ks.keys, id)
+		return nil
+	}
+
+	return ErrKeyNotFound
+}
+
 func (ks *defaultKeyStore) monitor(ctx context.Context) {
 	for {
 		select {
@@ -175,59 +238,235 @@ func (ks *defaultKeyStore) monitor(ctx context.Context) {
 	}
 }
 
+// watch synchronizes the keystore on backend changes, it falls back to
+// polling when the backend does not support watches
+func (ks *defaultKeyStore) watch(ctx context.Context) {
+	var index uint64
+	for {
+		// First call returns immediately to synchronize from current index
+		next, err := ks.store.WatchPrefix(ctx, "jwk", backends.WithWaitIndex(index))
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err == backends.ErrWatchNotSupported:
+			log.For(ctx).Warn("Backend does not support watch, fallback to polling")
+			ks.monitor(ctx)
+			return
+		case err != nil:
+			log.For(ctx).Error("Unable to watch keystore backend", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(ks.dopts.Interval) * time.Second):
+			}
+			continue
+		}
+		index = next
+
+		if err := ks.synchronize(ctx); err != nil {
+			log.For(ctx).Error("Unable to synchronize keystore with backend", zap.Error(err))
+		}
+	}
+}
+
 func (ks *defaultKeyStore) synchronize(ctx context.Context) error {
-	keys, err := ks.store.List(ctx, "jwk")
+	kids, err := ks.store.List(ctx, "jwk")
 	if err != nil {
 		return errors.Wrap(err, "keystore: Unable to synchronize keystore with backend")
 	}
 
-	// Delete keys
-	for kid := range ks.keys {
-		if err := ks.Remove(ctx, kid); err != nil {
-			log.For(ctx).Error("Unable to remove key from local cache", zap.Error(err))
-		}
-	}
-
-	// Update keys
-	for _, kid := range keys {
+	// Rebuild the local cache, keys removed from backend are dropped
+	keys := make(map[string]key.Key)
+	expired := make(map[string]bool)
+	for _, kid := range kids {
 		// Retrieve each value
-		value, err := ks.store.Get(ctx, fmt.Sprintf("jwk/%s", kid))
+		payload, err := ks.store.Get(ctx, fmt.Sprintf("jwk/%s", kid))
 		if err != nil {
 			continue
 		}
 
 		// Decode value as Key
 		holder := &keyHolder{}
-		err = json.Unmarshal(value, holder)
+		err = json.Unmarshal(payload, holder)
 		if err != nil {
 			return err
 		}
 
 		// If key is expired ignore it
 		if holder.IsExpired() {
+			expired[kid] = true
 			continue
 		}
 
-		payload := holder.Data
-		if ks.dopts.Snappy {
-			// Decompress buffer
-			payload, err = snappy.Decode(nil, holder.Data)
-			if err != nil {
-				continue
-			}
-		}
-
-		// Deserialize JWK
-		k, err := key.FromString(payload)
+		k, err := ks.decode(ctx, kid, holder)
 		if err != nil {
+			log.For(ctx).Warn("Unable to decode key", zap.String("kid", kid), zap.Error(err))
 			continue
 		}
 
 		// Add key to local cache
-		ks.Lock()
-		ks.keys[k.ID()] = k
-		ks.Unlock()
+		keys[k.ID()] = k
+	}
+
+	// Diff with the previous cache under lock, so that events follow cache updates
+	now := time.Now().UTC()
+	var events []Event
+	ks.Lock()
+	for kid := range keys {
+		if _, ok := ks.keys[kid]; !ok {
+			events = append(events, Event{Type: KeyAdded, KeyID: kid, Time: now})
+		}
+	}
+	for kid := range ks.keys {
+		if _, ok := keys[kid]; ok {
+			continue
+		}
+		event := Event{Type: KeyRemoved, KeyID: kid, Time: now}
+		if expired[kid] {
+			event.Type = KeyExpired
+		}
+		events = append(events, event)
+	}
+	ks.keys = keys
+	ks.Unlock()
+
+	ks.publish(events)
+
+	return nil
+}
+
+// encode sets the holder data from the key, the private key is transformed
+// only if enabled
+func (ks *defaultKeyStore) encode(k key.Key, holder *keyHolder) error {
+	// Shared secrets are never stored in clear
+	if _, ok := k.(key.Symmetric); ok && ks.dopts.PrivateKeys == nil {
+		return ErrSymmetricKeyNeedsEncryption
+	}
+
+	// Encode public key
+	jwk, err := json.Marshal(k.Public())
+	if err != nil {
+		return fmt.Errorf("keystore: Unable to marshal key as JSON: %v", err)
+	}
+	holder.Data, err = ks.codec.TransformToStorage(jwk, nil)
+	if err != nil {
+		return fmt.Errorf("keystore: Unable to compress key: %v", err)
+	}
+
+	if ks.dopts.PrivateKeys == nil || !k.HasPrivate() {
+		return nil
+	}
+
+	// Encode and transform private key
+	jwk, err = json.Marshal(k)
+	if err != nil {
+		return fmt.Errorf("keystore: Unable to marshal key as JSON: %v", err)
+	}
+	holder.Private, err = ks.privateKeys().TransformToStorage(jwk, privateKeyContext(k.ID()))
+	if err != nil {
+		return fmt.Errorf("keystore: Unable to transform private key: %v", err)
+	}
+
+	return nil
+}
+
+// decode returns the holded key, with its private part when available
+func (ks *defaultKeyStore) decode(ctx context.Context, kid string, holder *keyHolder) (key.Key, error) {
+	payload, _, err := ks.codec.TransformFromStorage(holder.Data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deserialize JWK
+	k, err := key.FromString(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if ks.dopts.PrivateKeys == nil || len(holder.Private) == 0 {
+		return k, nil
+	}
+
+	// Restore private key, fallback to public key on error
+	payload, stale, err := ks.privateKeys().TransformFromStorage(holder.Private, privateKeyContext(kid))
+	if err != nil {
+		log.For(ctx).Warn("Unable to transform private key", zap.String("kid", kid), zap.Error(err))
+		return k, nil
+	}
+	priv, err := key.FromString(payload)
+	if err != nil {
+		return nil, err
+	}
+	if priv.ID() != k.ID() || !priv.HasPrivate() {
+		return nil, errors.New("keystore: Private key does not match public key")
+	}
+
+	// Write back with the current transformer
+	if stale {
+		if err := ks.encode(priv, holder); err == nil {
+			if err := ks.save(ctx, kid, holder); err != nil {
+				log.For(ctx).Warn("Unable to rewrite stale private key", zap.String("kid", kid), zap.Error(err))
+			}
+		}
+	}
+
+	return priv, nil
+}
+
+func (ks *defaultKeyStore) save(ctx context.Context, kid string, holder *keyHolder) error {
+	payload, err := json.Marshal(holder)
+	if err != nil {
+		return fmt.Errorf("keystore: Unable to marshal key as JSON: %v", err)
+	}
+
+	// Add to backend
+	err = ks.store.Set(ctx, fmt.Sprintf("jwk/%s", kid), payload)
+	if err != nil {
+		return fmt.Errorf("keystore: Unable to save key to backend: %v", err)
 	}
 
 	return nil
 }
+
+// privateKeys compresses then transforms private keys
+func (ks *defaultKeyStore) privateKeys() value.Transformer {
+	return value.NewChainTransformer(ks.codec, ks.dopts.PrivateKeys)
+}
+
+// publish notifies subscribers and the event bus
+func (ks *defaultKeyStore) publish(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	ks.events.notify(events)
+	if ks.dopts.EventBus != nil {
+		for _, e := range events {
+			ks.dopts.EventBus.Publish(EventTopic, e)
+		}
+	}
+}
+
+// audit appends a record for the operation, the actor is taken from context.
+// The operation is already applied, so that errors are logged and the local
+// cache is still synchronized.
+func (ks *defaultKeyStore) audit(ctx context.Context, action AuditAction, kid string) {
+	if ks.dopts.AuditLog == nil {
+		return
+	}
+
+	err := ks.dopts.AuditLog.Append(ctx, &AuditRecord{
+		Action: action,
+		KeyID:  kid,
+		Actor:  ActorFromContext(ctx),
+		Time:   time.Now().UTC(),
+	})
+	if err != nil {
+		log.For(ctx).Error("Unable to append keystore audit record", zap.String("kid", kid), zap.String("action", string(action)), zap.Error(err))
+	}
+}
+
+// privateKeyContext binds the transformed private key to its identifier
+func privateKeyContext(kid string) value.Context {
+	return value.DefaultContext(fmt.Sprintf("jwk/%s", kid))
+}
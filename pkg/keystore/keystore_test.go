@@ -3,16 +3,25 @@ package keystore
 import (
 	"context"
 	"crypto"
+	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
 	"fmt"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/hokaccha/go-prettyjson"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/scraly/go.common/pkg/eventbus"
 	"github.com/scraly/go.common/pkg/keystore/backends/inmemory"
 	"github.com/scraly/go.common/pkg/keystore/key"
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/secretbox"
 )
 
 func TestInMemoryKeyStore(t *testing.T) {
@@ -68,3 +77,255 @@ func TestInMemoryKeyStore(t *testing.T) {
 	// Remove a key
 	ks.Remove(ctx, k2.ID())
 }
+
+func TestPrivateKeys(t *testing.T) {
+	ctx := context.Background()
+	backend, _ := inmemory.New()
+
+	var secret [32]byte
+	copy(secret[:], "0123456789abcdef0123456789abcdef")
+
+	ks1, _ := New(backend, WithPrivateKeys(secretbox.NewSecretboxTransformer(secret)))
+	ks2, _ := New(backend, WithPrivateKeys(secretbox.NewSecretboxTransformer(secret)))
+	public, _ := New(backend)
+
+	k, err := ks1.Generate(ctx, key.Ed25519)
+	require.NoError(t, err)
+	require.NoError(t, ks1.Add(ctx, k))
+
+	// Another instance reloads the signing key
+	require.NoError(t, ks2.Add(ctx))
+	reloaded, err := ks2.Get(ctx, k.ID())
+	require.NoError(t, err)
+	require.True(t, reloaded.HasPrivate(), "Private key should be reloaded from backend")
+
+	data := []byte("toto")
+	sig, err := reloaded.Sign(data)
+	require.NoError(t, err)
+	require.NoError(t, k.Verify(data, sig))
+
+	publicKeys, err := ks2.OnlyPublicKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, publicKeys, 1)
+	require.False(t, publicKeys[0].HasPrivate(), "Public keys should not expose private keys")
+
+	// Private key is not readable without the transformer
+	payload, err := backend.Get(ctx, fmt.Sprintf("jwk/%s", k.ID()))
+	require.NoError(t, err)
+	require.NotContains(t, string(payload), `"d"`)
+
+	require.NoError(t, public.Add(ctx))
+	pub, err := public.Get(ctx, k.ID())
+	require.NoError(t, err)
+	require.False(t, pub.HasPrivate(), "Keystore without transformer should only load public keys")
+
+	// Wrong secret falls back to public key
+	copy(secret[:], "fedcba9876543210fedcba9876543210")
+	wrong, _ := New(backend, WithPrivateKeys(secretbox.NewSecretboxTransformer(secret)))
+	require.NoError(t, wrong.Add(ctx))
+	pub, err = wrong.Get(ctx, k.ID())
+	require.NoError(t, err)
+	require.False(t, pub.HasPrivate())
+}
+
+func TestGetByThumbprint(t *testing.T) {
+	ctx := context.Background()
+	backend, _ := inmemory.New()
+	ks, _ := New(backend)
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "keystore"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	require.NoError(t, err)
+
+	k, err := key.FromDER(der)
+	require.NoError(t, err)
+	other, _ := ks.Generate(ctx, key.Ed25519)
+	require.NoError(t, ks.Add(ctx, k, other))
+
+	cert := k.(key.Certified).Certificates()[0]
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	for _, thumbprint := range []string{key.Thumbprint(cert), key.ThumbprintSHA256(cert)} {
+		found, err := GetByThumbprint(ctx, ks, thumbprint, roots)
+		require.NoError(t, err, "Error should not be raised")
+		require.Equal(t, k.ID(), found.ID(), "Key should be found by thumbprint")
+	}
+
+	_, err = GetByThumbprint(ctx, ks, key.Thumbprint(cert), x509.NewCertPool())
+	require.Error(t, err, "Untrusted key should not be returned")
+
+	_, err = GetByThumbprint(ctx, ks, "unknown", roots)
+	require.Equal(t, ErrKeyNotFound, err, "Error should be as expected")
+}
+
+func TestSymmetricKeys(t *testing.T) {
+	ctx := context.Background()
+	backend, _ := inmemory.New()
+
+	var secret [32]byte
+	copy(secret[:], "0123456789abcdef0123456789abcdef")
+
+	k, err := key.HMAC(crypto.SHA256)(ctx)
+	require.NoError(t, err)
+
+	// Shared secrets are never stored in clear
+	public, _ := New(backend)
+	require.Equal(t, ErrSymmetricKeyNeedsEncryption, public.Add(ctx, k))
+
+	ks1, _ := New(backend, WithPrivateKeys(secretbox.NewSecretboxTransformer(secret)))
+	ks2, _ := New(backend, WithPrivateKeys(secretbox.NewSecretboxTransformer(secret)))
+	require.NoError(t, ks1.Add(ctx, k))
+
+	payload, err := backend.Get(ctx, fmt.Sprintf("jwk/%s", k.ID()))
+	require.NoError(t, err)
+	require.NotContains(t, string(payload), `"k"`)
+
+	// Another instance reloads the secret
+	require.NoError(t, ks2.Add(ctx))
+	reloaded, err := ks2.Get(ctx, k.ID())
+	require.NoError(t, err)
+
+	data := []byte("toto")
+	sig, err := k.Sign(data)
+	require.NoError(t, err)
+	require.NoError(t, reloaded.Verify(data, sig), "Reloaded secret should verify the signature")
+
+	// Shared secrets are not published
+	publicKeys, err := ks2.OnlyPublicKeys(ctx)
+	require.NoError(t, err)
+	require.Empty(t, publicKeys)
+}
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	backend, _ := inmemory.New()
+
+	bus := eventbus.NewLocal()
+	var published []Event
+	require.NoError(t, bus.Subscribe(EventTopic, func(e Event) {
+		published = append(published, e)
+	}))
+
+	ks, _ := New(backend, WithEventBus(bus))
+	var events []Event
+	cancel := ks.(Observable).Subscribe(func(e Event) {
+		events = append(events, e)
+	})
+
+	k1, _ := ks.Generate(ctx, key.Ed25519)
+	k2, _ := ks.Generate(ctx, key.Ed25519)
+	require.NoError(t, ks.Add(ctx, k1))
+	require.NoError(t, ks.Add(ctx, k2))
+	require.Len(t, events, 2)
+	require.Equal(t, Event{Type: KeyAdded, KeyID: k1.ID(), Time: events[0].Time}, events[0])
+	require.Equal(t, Event{Type: KeyAdded, KeyID: k2.ID(), Time: events[1].Time}, events[1])
+
+	// Synchronization without changes emits nothing
+	require.NoError(t, ks.Add(ctx))
+	require.Len(t, events, 2)
+
+	// Key expiring later is kept
+	require.NoError(t, ks.(Expirer).Expire(ctx, k2.ID(), time.Now().Add(time.Hour)))
+	require.Len(t, events, 2)
+	_, err := ks.Get(ctx, k2.ID())
+	require.NoError(t, err)
+
+	// Expired key is dropped
+	require.NoError(t, ks.(Expirer).Expire(ctx, k1.ID(), time.Now().Add(-time.Minute)))
+	require.Len(t, events, 3)
+	require.Equal(t, KeyExpired, events[2].Type)
+	require.Equal(t, k1.ID(), events[2].KeyID)
+
+	require.NoError(t, ks.Remove(ctx, k2.ID()))
+	require.Len(t, events, 4)
+	require.Equal(t, KeyRemoved, events[3].Type)
+	require.Equal(t, k2.ID(), events[3].KeyID)
+
+	// Event bus receives the same events
+	require.Equal(t, events, published)
+
+	// Canceled subscription
+	cancel()
+	k3, _ := ks.Generate(ctx, key.Ed25519)
+	require.NoError(t, ks.Add(ctx, k3))
+	require.Len(t, events, 4)
+	require.Len(t, published, 5)
+}
+
+func TestAuditLog(t *testing.T) {
+	backend, _ := inmemory.New()
+	audit := NewAuditLog(backend)
+	ks, _ := New(backend, WithAuditLog(audit))
+
+	ctx := WithActor(context.Background(), "alice")
+	k, err := ks.Generate(ctx, key.Ed25519)
+	require.NoError(t, err)
+	require.NoError(t, ks.Add(ctx, k))
+	require.NoError(t, ks.(Expirer).Expire(ctx, k.ID(), time.Now().Add(time.Hour)))
+	require.NoError(t, ks.Remove(WithActor(context.Background(), "bob"), k.ID()))
+
+	// Unknown key is not audited
+	require.Equal(t, ErrKeyNotFound, ks.Remove(ctx, k.ID()))
+	require.Equal(t, ErrKeyNotFound, ks.(Expirer).Expire(ctx, k.ID(), time.Now()))
+
+	records, err := audit.Records(ctx, k.ID())
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	for i, expected := range []struct {
+		action AuditAction
+		actor  string
+	}{{AuditGenerated, "alice"}, {AuditAdded, "alice"}, {AuditExpired, "alice"}, {AuditRemoved, "bob"}} {
+		require.Equal(t, expected.action, records[i].Action)
+		require.Equal(t, expected.actor, records[i].Actor)
+		require.Equal(t, k.ID(), records[i].KeyID)
+		require.False(t, records[i].Time.IsZero())
+	}
+
+	// Audit trail is not a key
+	keys, err := ks.All(ctx)
+	require.NoError(t, err)
+	require.Empty(t, keys)
+}
+
+type failingAuditLog struct{}
+
+func (failingAuditLog) Append(context.Context, *AuditRecord) error {
+	return errors.New("audit: unavailable")
+}
+
+func (failingAuditLog) Records(context.Context, string) ([]*AuditRecord, error) {
+	return nil, errors.New("audit: unavailable")
+}
+
+func TestAuditLog_Failure(t *testing.T) {
+	ctx := context.Background()
+	backend, _ := inmemory.New()
+	ks, _ := New(backend, WithAuditLog(failingAuditLog{}))
+
+	var events []Event
+	ks.(Observable).Subscribe(func(e Event) {
+		events = append(events, e)
+	})
+
+	// Audit errors do not leave the cache out of sync with the backend
+	k, err := ks.Generate(ctx, key.Ed25519)
+	require.NoError(t, err)
+	require.NoError(t, ks.Add(ctx, k))
+	_, err = ks.Get(ctx, k.ID())
+	require.NoError(t, err, "Added key should be cached")
+
+	require.NoError(t, ks.Remove(ctx, k.ID()))
+	_, err = ks.Get(ctx, k.ID())
+	require.Equal(t, ErrKeyNotFound, err, "Removed key should not be cached")
+
+	require.Len(t, events, 2)
+	require.Equal(t, KeyAdded, events[0].Type)
+	require.Equal(t, KeyRemoved, events[1].Type)
+}
@@ -1,11 +1,26 @@
 package keystore
 
+import (
+	"github.com/scraly/go.common/pkg/eventbus"
+	"github.com/scraly/go.common/pkg/storage/value"
+)
+
 // Options contains all values that are needed for keystore.
 type Options struct {
 	OneTime  bool
 	Watch    bool
 	Snappy   bool
 	Interval uint64
+
+	// PrivateKeys transforms private keys before storing them, private keys
+	// are not persisted when nil
+	PrivateKeys value.Transformer
+
+	// EventBus receives keystore events on EventTopic, events are only sent
+	// to subscribers when nil
+	EventBus eventbus.EventBus
+	// AuditLog records keystore operations, no audit trail is kept when nil
+	AuditLog AuditLog
 }
 
 // Option configures the keystore.
@@ -25,7 +40,7 @@ func OneTime() Option {
 	}
 }
 
-// EnableWatch enables watch feature on backend side.
+// EnableWatch synchronizes the keystore on backend changes instead of polling.
 func EnableWatch() Option {
 	return func(o *Options) {
 		o.Watch = true
@@ -38,3 +53,25 @@ func DisableSnappy() Option {
 		o.Snappy = false
 	}
 }
+
+// WithPrivateKeys persists private keys encrypted with the given transformer,
+// so that signing keys can be reloaded from the backend.
+func WithPrivateKeys(transformer value.Transformer) Option {
+	return func(o *Options) {
+		o.PrivateKeys = transformer
+	}
+}
+
+// WithEventBus publishes keystore events to the event bus on EventTopic.
+func WithEventBus(bus eventbus.EventBus) Option {
+	return func(o *Options) {
+		o.EventBus = bus
+	}
+}
+
+// WithAuditLog records who generated, added and removed keys.
+func WithAuditLog(log AuditLog) Option {
+	return func(o *Options) {
+		o.AuditLog = log
+	}
+}
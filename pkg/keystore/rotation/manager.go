@@ -0,0 +1,392 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package rotation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/scraly/go.common/pkg/keystore"
+	"github.com/scraly/go.common/pkg/keystore/backends"
+	"github.com/scraly/go.common/pkg/keystore/key"
+	"github.com/scraly/go.common/pkg/lock"
+	"github.com/scraly/go.common/pkg/log"
+	"go.uber.org/zap"
+)
+
+// lockTTL is the rotation lock lifetime, renewed while rotating
+const lockTTL = 30 * time.Second
+
+var (
+	// ErrNoActiveKey is raised when no key has been rotated in yet
+	ErrNoActiveKey = errors.New("rotation: No active key")
+	// ErrNoPrivateKey is raised when the active key private part is not available to this instance
+	ErrNoPrivateKey = errors.New("rotation: Active key private part is not available")
+	// ErrLockLost is raised when the rotation lock is lost, or superseded by a
+	// newer holder, before the rotation state is saved
+	ErrLockLost = errors.New("rotation: Rotation lock has been lost")
+	// ErrInvalidPeriod is raised when the rotation period is not positive
+	ErrInvalidPeriod = errors.New("rotation: Period must be positive")
+	// ErrInvalidGracePeriod is raised when the grace period is not positive
+	ErrInvalidGracePeriod = errors.New("rotation: Grace period must be positive")
+)
+
+// Manager rotates the keystore signing key
+type Manager interface {
+	// Rotate generates a new active key when the current one is older than the
+	// rotation period, and removes superseded keys past their grace period.
+	// Instances not holding the rotation lock only reload the shared state.
+	Rotate(ctx context.Context) error
+	// Run calls Rotate on every check interval until the context is done
+	Run(ctx context.Context) error
+	// Active returns the active key identifier
+	Active(ctx context.Context) (string, error)
+	// SigningKey returns the active key, it implements jwt.KeySource
+	SigningKey(ctx context.Context) (key.Key, error)
+}
+
+// state is the rotation record shared by all instances
+type state struct {
+	Active string     `json:"active"`
+	Keys   []keyState `json:"keys"`
+	// Fence is the fencing token of the last lock holder that saved the state
+	Fence uint64 `json:"fence,omitempty"`
+
+	// raw is the stored payload, used to detect concurrent updates
+	raw []byte
+}
+
+type keyState struct {
+	ID        string    `json:"kid"`
+	CreatedAt time.Time `json:"created_at"`
+	// RetiredAt is set when the key is superseded by a new active key
+	RetiredAt time.Time `json:"retired_at"`
+}
+
+type defaultManager struct {
+	sync.Mutex
+
+	keys      keystore.KeyStore
+	backend   backends.AtomicBackend
+	generator key.Generator
+	dopts     *Options
+
+	current *state
+	// signers holds private keys generated by this instance
+	signers map[string]key.Key
+}
+
+// New returns a rotation manager publishing keys built by generator in the
+// keystore. The rotation state is stored in the given backend, which must be
+// shared by all instances, usually the keystore one. State updates are
+// conditional, so that concurrent rotations never orphan a key: the losing
+// instance removes the key it generated and reloads the winner state.
+//
+// The private part of keys generated by another instance is only available
+// when the keystore persists private keys, see keystore.WithPrivateKeys.
+func New(keys keystore.KeyStore, backend backends.AtomicBackend, generator key.Generator, opts ...Option) (Manager, error) {
+	// Default Options
+	options := &Options{
+		Name:          "default",
+		Period:        24 * time.Hour,
+		GracePeriod:   24 * time.Hour,
+		CheckInterval: time.Minute,
+	}
+
+	// Overrides with option
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	// Check options
+	if options.Period <= 0 {
+		return nil, ErrInvalidPeriod
+	}
+	if options.GracePeriod <= 0 {
+		return nil, ErrInvalidGracePeriod
+	}
+
+	return &defaultManager{
+		keys:      keys,
+		backend:   backend,
+		generator: generator,
+		dopts:     options,
+		signers:   map[string]key.Key{},
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+func (m *defaultManager) Rotate(ctx context.Context) error {
+	var lck lock.Lock
+	if m.dopts.Locker != nil {
+		var err error
+		lck, err = m.dopts.Locker.TryAcquire(ctx, m.lockName(), lockTTL)
+		switch {
+		case err == lock.ErrNotAcquired:
+			// Another instance is rotating, only reload its result
+			return m.reload(ctx)
+		case err != nil:
+			return fmt.Errorf("rotation: Unable to acquire rotation lock: %v", err)
+		}
+		defer func() {
+			if err := lck.Release(ctx); err != nil {
+				log.For(ctx).Warn("Unable to release rotation lock", zap.Error(err))
+			}
+		}()
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	st, err := m.load(ctx)
+	if err != nil {
+		return err
+	}
+	if lck != nil && st.Fence > lck.Token() {
+		return ErrLockLost
+	}
+
+	now := time.Now().UTC()
+	changed := false
+	generated := ""
+
+	// Generate a new active key
+	if active := st.find(st.Active); active == nil || now.Sub(active.CreatedAt) >= m.dopts.Period {
+		k, err := m.keys.Generate(ctx, m.generator)
+		if err != nil {
+			return fmt.Errorf("rotation: Unable to generate key: %v", err)
+		}
+		if err := m.keys.Add(ctx, k); err != nil {
+			return fmt.Errorf("rotation: Unable to publish key: %v", err)
+		}
+		m.signers[k.ID()] = k
+		generated = k.ID()
+
+		if active != nil {
+			active.RetiredAt = now
+		}
+		st.Keys = append(st.Keys, keyState{
+			ID:        k.ID(),
+			CreatedAt: now,
+		})
+		st.Active = k.ID()
+		changed = true
+	}
+
+	// Retired keys are only removed once no longer referenced by the state
+	var retired []string
+	kept := make([]keyState, 0, len(st.Keys))
+	for _, ks := range st.Keys {
+		if ks.RetiredAt.IsZero() || now.Sub(ks.RetiredAt) < m.dopts.GracePeriod {
+			kept = append(kept, ks)
+			continue
+		}
+		retired = append(retired, ks.ID)
+		changed = true
+	}
+	st.Keys = kept
+
+	if !changed {
+		m.current = st
+		return nil
+	}
+
+	if lck != nil {
+		st.Fence = lck.Token()
+	}
+	saved, err := m.save(ctx, st, lck)
+	if err != nil || !saved {
+		m.discard(ctx, generated)
+	}
+	if err != nil {
+		return err
+	}
+	if !saved {
+		// Another instance updated the state first, adopt its result
+		log.For(ctx).Info("Concurrent rotation detected, reloading state", zap.String("name", m.dopts.Name))
+		current, err := m.load(ctx)
+		if err != nil {
+			return err
+		}
+		m.current = current
+		return nil
+	}
+	m.current = st
+
+	if generated != "" {
+		log.For(ctx).Info("Signing key rotated", zap.String("name", m.dopts.Name), zap.String("kid", generated))
+	}
+	for _, kid := range retired {
+		if err := m.keys.Remove(ctx, kid); err != nil && err != keystore.ErrKeyNotFound {
+			log.For(ctx).Warn("Unable to remove retired key", zap.String("kid", kid), zap.Error(err))
+			continue
+		}
+		delete(m.signers, kid)
+
+		log.For(ctx).Info("Signing key retired", zap.String("name", m.dopts.Name), zap.String("kid", kid))
+	}
+
+	return nil
+}
+
+func (m *defaultManager) Run(ctx context.Context) error {
+	for {
+		if err := m.Rotate(ctx); err != nil {
+			log.For(ctx).Error("Unable to rotate keys", zap.String("name", m.dopts.Name), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.dopts.CheckInterval):
+		}
+	}
+}
+
+func (m *defaultManager) Active(ctx context.Context) (string, error) {
+	m.Lock()
+	current := m.current
+	m.Unlock()
+
+	if current == nil {
+		if err := m.reload(ctx); err != nil {
+			return "", err
+		}
+		m.Lock()
+		current = m.current
+		m.Unlock()
+	}
+
+	if current.Active == "" {
+		return "", ErrNoActiveKey
+	}
+
+	return current.Active, nil
+}
+
+func (m *defaultManager) SigningKey(ctx context.Context) (key.Key, error) {
+	kid, err := m.Active(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Lock()
+	k, ok := m.signers[kid]
+	m.Unlock()
+	if ok {
+		return k, nil
+	}
+
+	// Key generated by another instance
+	k, err = m.keys.Get(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	if !k.HasPrivate() {
+		return nil, ErrNoPrivateKey
+	}
+
+	return k, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (m *defaultManager) lockName() string {
+	return fmt.Sprintf("keystore-rotation/%s", m.dopts.Name)
+}
+
+func (m *defaultManager) stateKey() string {
+	return fmt.Sprintf("rotation/%s", m.dopts.Name)
+}
+
+func (m *defaultManager) reload(ctx context.Context) error {
+	m.Lock()
+	defer m.Unlock()
+
+	st, err := m.load(ctx)
+	if err != nil {
+		return err
+	}
+	m.current = st
+
+	return nil
+}
+
+func (m *defaultManager) load(ctx context.Context) (*state, error) {
+	st := &state{}
+
+	payload, err := m.backend.Get(ctx, m.stateKey())
+	switch {
+	case err == backends.ErrKeyNotFound:
+		return st, nil
+	case err != nil:
+		return nil, fmt.Errorf("rotation: Unable to load rotation state: %v", err)
+	}
+
+	if err := json.Unmarshal(payload, st); err != nil {
+		return nil, fmt.Errorf("rotation: Unable to decode rotation state: %v", err)
+	}
+	st.raw = payload
+
+	return st, nil
+}
+
+// save replaces the loaded state, it returns false if the state has been
+// updated since it was loaded
+func (m *defaultManager) save(ctx context.Context, st *state, lck lock.Lock) (bool, error) {
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return false, fmt.Errorf("rotation: Unable to encode rotation state: %v", err)
+	}
+
+	if lck != nil {
+		select {
+		case <-lck.Lost():
+			return false, ErrLockLost
+		default:
+		}
+	}
+
+	ok, err := m.backend.CompareAndSwap(ctx, m.stateKey(), st.raw, payload)
+	if err != nil {
+		return false, fmt.Errorf("rotation: Unable to save rotation state: %v", err)
+	}
+	if ok {
+		st.raw = payload
+	}
+
+	return ok, nil
+}
+
+// discard removes a key generated by a rotation that has not been saved
+func (m *defaultManager) discard(ctx context.Context, kid string) {
+	if kid == "" {
+		return
+	}
+
+	delete(m.signers, kid)
+	if err := m.keys.Remove(ctx, kid); err != nil && err != keystore.ErrKeyNotFound {
+		log.For(ctx).Warn("Unable to remove discarded key", zap.String("kid", kid), zap.Error(err))
+	}
+}
+
+func (st *state) find(kid string) *keyState {
+	for i := range st.Keys {
+		if st.Keys[i].ID == kid {
+			return &st.Keys[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package rotation_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scraly/go.common/pkg/keystore"
+	"github.com/scraly/go.common/pkg/keystore/backends"
+	"github.com/scraly/go.common/pkg/keystore/backends/inmemory"
+	"github.com/scraly/go.common/pkg/keystore/key"
+	"github.com/scraly/go.common/pkg/keystore/rotation"
+	"github.com/scraly/go.common/pkg/lock"
+	"github.com/scraly/go.common/pkg/lock/kv"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staleLocker struct {
+	lock.Locker
+	token uint64
+	lost  bool
+}
+
+func (l *staleLocker) TryAcquire(ctx context.Context, name string, ttl time.Duration) (lock.Lock, error) {
+	return &staleLock{token: l.token, lost: l.lost}, nil
+}
+
+type staleLock struct {
+	lock.Lock
+	token uint64
+	lost  bool
+}
+
+func (l *staleLock) Token() uint64 {
+	return l.token
+}
+
+func (l *staleLock) Lost() <-chan struct{} {
+	ch := make(chan struct{})
+	if l.lost {
+		close(ch)
+	}
+	return ch
+}
+
+func (l *staleLock) Release(context.Context) error {
+	return nil
+}
+
+func newKeyStore(t *testing.T, backend backends.Backend) keystore.KeyStore {
+	ks, err := keystore.New(backend)
+	require.NoError(t, err)
+	return ks
+}
+
+func TestManager_Rotate(t *testing.T) {
+	ctx := context.Background()
+	backend, err := inmemory.New()
+	require.NoError(t, err)
+	ks := newKeyStore(t, backend)
+
+	m, err := rotation.New(ks, backend, key.Ed25519,
+		rotation.WithPeriod(50*time.Millisecond),
+		rotation.WithGracePeriod(50*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	_, err = m.Active(ctx)
+	require.Equal(t, rotation.ErrNoActiveKey, err, "No key should be active before first rotation")
+
+	// First rotation generates the active key
+	require.NoError(t, m.Rotate(ctx))
+	k1, err := m.SigningKey(ctx)
+	require.NoError(t, err)
+	require.True(t, k1.HasPrivate(), "Signing key should have a private key")
+	_, err = ks.Get(ctx, k1.ID())
+	require.NoError(t, err, "Active key should be published")
+
+	// Active key is kept during its period
+	require.NoError(t, m.Rotate(ctx))
+	active, err := m.Active(ctx)
+	require.NoError(t, err)
+	require.Equal(t, k1.ID(), active)
+
+	// New key after the period, previous one stays published
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, m.Rotate(ctx))
+	k2, err := m.SigningKey(ctx)
+	require.NoError(t, err)
+	require.NotEqual(t, k1.ID(), k2.ID(), "Key should have been rotated")
+	_, err = ks.Get(ctx, k1.ID())
+	require.NoError(t, err, "Superseded key should be published during grace period")
+
+	// Superseded key is removed after the grace period
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, m.Rotate(ctx))
+	_, err = ks.Get(ctx, k1.ID())
+	require.Equal(t, keystore.ErrKeyNotFound, err, "Retired key should be removed")
+	_, err = ks.Get(ctx, k2.ID())
+	require.NoError(t, err, "Previous key should be published during grace period")
+
+	keys, err := ks.All(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+}
+
+func TestManager_Coordination(t *testing.T) {
+	ctx := context.Background()
+	backend, err := inmemory.New()
+	require.NoError(t, err)
+	locker := lock.New(kv.New(backend))
+
+	m1, err := rotation.New(newKeyStore(t, backend), backend, key.Ed25519, rotation.WithLocker(locker))
+	require.NoError(t, err)
+	ks2 := newKeyStore(t, backend)
+	m2, err := rotation.New(ks2, backend, key.Ed25519, rotation.WithLocker(locker))
+	require.NoError(t, err)
+
+	// Rotation is skipped while another instance holds the lock
+	lck, err := locker.TryAcquire(ctx, "keystore-rotation/default", time.Second)
+	require.NoError(t, err)
+	require.NoError(t, m1.Rotate(ctx))
+	_, err = m1.Active(ctx)
+	require.Equal(t, rotation.ErrNoActiveKey, err, "Instance without the lock should not rotate")
+	require.NoError(t, lck.Release(ctx))
+
+	require.NoError(t, m1.Rotate(ctx))
+	active, err := m1.Active(ctx)
+	require.NoError(t, err)
+
+	// Other instances share the active key
+	require.NoError(t, m2.Rotate(ctx))
+	other, err := m2.Active(ctx)
+	require.NoError(t, err)
+	require.Equal(t, active, other, "Instances should share the active key")
+
+	// Adding no key synchronizes the local cache with the backend
+	require.NoError(t, ks2.Add(ctx))
+	_, err = m2.SigningKey(ctx)
+	require.Equal(t, rotation.ErrNoPrivateKey, err, "Private key should only be known by the generating instance")
+}
+
+func TestManager_ConcurrentRotations(t *testing.T) {
+	ctx := context.Background()
+	backend, err := inmemory.New()
+	require.NoError(t, err)
+	ks := newKeyStore(t, backend)
+
+	managers := make([]rotation.Manager, 5)
+	for i := range managers {
+		managers[i], err = rotation.New(newKeyStore(t, backend), backend, key.Ed25519)
+		require.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	for _, m := range managers {
+		wg.Add(1)
+		go func(m rotation.Manager) {
+			defer wg.Done()
+			assert.NoError(t, m.Rotate(ctx))
+		}(m)
+	}
+	wg.Wait()
+
+	active, err := managers[0].Active(ctx)
+	require.NoError(t, err)
+	for _, m := range managers[1:] {
+		other, err := m.Active(ctx)
+		require.NoError(t, err)
+		require.Equal(t, active, other, "Instances should share the active key")
+	}
+
+	require.NoError(t, ks.Add(ctx))
+	keys, err := ks.All(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1, "Keys of discarded rotations should be removed")
+	require.Equal(t, active, keys[0].ID())
+}
+
+func TestManager_LockLost(t *testing.T) {
+	ctx := context.Background()
+	backend, err := inmemory.New()
+	require.NoError(t, err)
+	ks := newKeyStore(t, backend)
+
+	m, err := rotation.New(ks, backend, key.Ed25519, rotation.WithLocker(&staleLocker{token: 1, lost: true}))
+	require.NoError(t, err)
+	require.Equal(t, rotation.ErrLockLost, m.Rotate(ctx), "State should not be saved once the lock is lost")
+
+	keys, err := ks.All(ctx)
+	require.NoError(t, err)
+	require.Empty(t, keys, "Generated key should be removed")
+
+	// Newer lock holder rotates
+	m, err = rotation.New(ks, backend, key.Ed25519, rotation.WithLocker(&staleLocker{token: 5}))
+	require.NoError(t, err)
+	require.NoError(t, m.Rotate(ctx))
+	active, err := m.Active(ctx)
+	require.NoError(t, err)
+
+	// Older lock holder is fenced
+	stale, err := rotation.New(ks, backend, key.Ed25519, rotation.WithPeriod(time.Nanosecond), rotation.WithLocker(&staleLocker{token: 4}))
+	require.NoError(t, err)
+	require.Equal(t, rotation.ErrLockLost, stale.Rotate(ctx), "Older lock holder should be rejected")
+	other, err := stale.Active(ctx)
+	require.NoError(t, err)
+	require.Equal(t, active, other, "Active key should not change")
+}
+
+func TestManager_InvalidOptions(t *testing.T) {
+	backend, err := inmemory.New()
+	require.NoError(t, err)
+	ks := newKeyStore(t, backend)
+
+	for _, period := range []time.Duration{0, -time.Hour} {
+		_, err = rotation.New(ks, backend, key.Ed25519, rotation.WithPeriod(period))
+		require.Equal(t, rotation.ErrInvalidPeriod, err, "Period %v should be rejected", period)
+
+		_, err = rotation.New(ks, backend, key.Ed25519, rotation.WithGracePeriod(period))
+		require.Equal(t, rotation.ErrInvalidGracePeriod, err, "Grace period %v should be rejected", period)
+	}
+}
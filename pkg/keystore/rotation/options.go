@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package rotation
+
+import (
+	"time"
+
+	"github.com/scraly/go.common/pkg/lock"
+)
+
+// Options contains all values that are needed for rotation manager.
+type Options struct {
+	// Name identifies the rotation state and lock, defaults to "default"
+	Name string
+	// Period is the active key lifetime before a new one is generated
+	Period time.Duration
+	// GracePeriod is the delay a superseded key stays published for verification,
+	// it must be longer than issued tokens lifetime
+	GracePeriod time.Duration
+	// CheckInterval is the delay between two rotation checks in Run
+	CheckInterval time.Duration
+	// Locker elects the rotating instance, so that other instances do not
+	// generate keys only to discard them. Its fencing token is saved in the
+	// rotation state, which rejects rotations from older lock holders.
+	Locker lock.Locker
+}
+
+// Option configures the rotation manager.
+type Option func(*Options)
+
+// WithName sets the rotation name, used to run several rotations on the same backend.
+func WithName(name string) Option {
+	return func(o *Options) {
+		o.Name = name
+	}
+}
+
+// WithPeriod sets the active key lifetime.
+func WithPeriod(period time.Duration) Option {
+	return func(o *Options) {
+		o.Period = period
+	}
+}
+
+// WithGracePeriod sets the superseded key publication delay.
+func WithGracePeriod(grace time.Duration) Option {
+	return func(o *Options) {
+		o.GracePeriod = grace
+	}
+}
+
+// WithCheckInterval sets the delay between two rotation checks.
+func WithCheckInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.CheckInterval = interval
+	}
+}
+
+// WithLocker sets the locker used to elect the rotating instance.
+func WithLocker(locker lock.Locker) Option {
+	return func(o *Options) {
+		o.Locker = locker
+	}
+}
@@ -0,0 +1,39 @@
+package keystore
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/scraly/go.common/pkg/keystore/key"
+)
+
+// GetByThumbprint returns the key whose leaf certificate matches the given
+// x5t or x5t#S256 thumbprint. The certificate chain is verified against roots
+// for the intended extended key usages before the key is returned, see
+// key.VerifyCertificates.
+func GetByThumbprint(ctx context.Context, ks KeyStore, thumbprint string, roots *x509.CertPool, usages ...x509.ExtKeyUsage) (key.Key, error) {
+	keys, err := ks.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range keys {
+		c, ok := k.(key.Certified)
+		if !ok || len(c.Certificates()) == 0 {
+			continue
+		}
+
+		leaf := c.Certificates()[0]
+		if key.Thumbprint(leaf) != thumbprint && key.ThumbprintSHA256(leaf) != thumbprint {
+			continue
+		}
+		if err := key.VerifyCertificates(k, roots, usages...); err != nil {
+			return nil, fmt.Errorf("keystore: Untrusted certificate chain: %v", err)
+		}
+
+		return k, nil
+	}
+
+	return nil, ErrKeyNotFound
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package lock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Locker acquires named locks shared across instances
+type Locker interface {
+	// Acquire blocks until the lock is obtained or the context is done
+	Acquire(ctx context.Context, name string, ttl time.Duration) (Lock, error)
+	// TryAcquire returns ErrNotAcquired if the lock is already held
+	TryAcquire(ctx context.Context, name string, ttl time.Duration) (Lock, error)
+}
+
+// Lock is an acquired lock, automatically renewed until released
+type Lock interface {
+	Name() string
+	// Token returns the fencing token, increasing on each acquisition of the
+	// same name. Protected resources should reject writes carrying a lower token.
+	Token() uint64
+	// Lost is closed when the lock could not be renewed before its expiration
+	Lost() <-chan struct{}
+	Release(ctx context.Context) error
+}
+
+// Driver is the storage contract used to hold locks
+type Driver interface {
+	// Acquire takes the lock for the owner, returns false if held by someone else
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) (token uint64, ok bool, err error)
+	// Renew extends the lock expiration, returns false if the owner lost the lock
+	Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	// Release frees the lock if still held by the owner
+	Release(ctx context.Context, name, owner string) error
+}
+
+var (
+	// ErrNotAcquired is raised when the lock is held by another owner
+	ErrNotAcquired = errors.New("lock: Lock is held by another owner")
+	// ErrNotHeld is raised when releasing a lock that has been lost
+	ErrNotHeld = errors.New("lock: Lock is not held")
+	// ErrInvalidTTL is raised when acquiring a lock with a ttl below a millisecond
+	ErrInvalidTTL = errors.New("lock: Lock ttl must be at least a millisecond")
+)
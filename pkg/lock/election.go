@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package lock
+
+import (
+	"context"
+	"time"
+
+	"github.com/scraly/go.common/pkg/log"
+	"go.uber.org/zap"
+)
+
+// LeaderFunc is invoked while the instance holds the leadership, its context
+// is canceled as soon as the leadership is lost.
+type LeaderFunc func(ctx context.Context, token uint64) error
+
+// Elect campaigns for the leadership of the given name and runs fn each time it
+// is obtained. It returns when the context is done, or with the result of fn
+// when fn returns while still being leader.
+func Elect(ctx context.Context, locker Locker, name string, ttl time.Duration, fn LeaderFunc) error {
+	for {
+		lck, err := locker.Acquire(ctx, name, ttl)
+		if err != nil {
+			return err
+		}
+
+		log.For(ctx).Info("Leadership acquired", zap.String("name", name), zap.Uint64("token", lck.Token()))
+
+		leaderCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-lck.Lost():
+				cancel()
+			case <-leaderCtx.Done():
+			}
+		}()
+
+		errRun := fn(leaderCtx, lck.Token())
+		lost := leaderCtx.Err() != nil
+		cancel()
+
+		// Use a fresh context, the parent one may already be done
+		releaseCtx, releaseCancel := context.WithTimeout(context.Background(), ttl)
+		if errRelease := lck.Release(releaseCtx); errRelease != nil && errRelease != ErrNotHeld {
+			log.For(ctx).Error("Unable to release leadership", zap.String("name", name), zap.Error(errRelease))
+		}
+		releaseCancel()
+
+		if !lost {
+			return errRun
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.For(ctx).Warn("Leadership lost, campaigning again", zap.String("name", name))
+	}
+}
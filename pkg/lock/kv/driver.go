@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/scraly/go.common/pkg/keystore/backends"
+	"github.com/scraly/go.common/pkg/lock"
+)
+
+// record is the lock state persisted in the backend. It is never deleted so
+// that the fencing token keeps increasing across acquisitions.
+type record struct {
+	Owner      string `json:"owner"`
+	Token      uint64 `json:"token"`
+	Expiration int64  `json:"exp"`
+}
+
+func (r *record) held(now time.Time) bool {
+	return len(r.Owner) > 0 && now.UnixNano() < r.Expiration
+}
+
+type kvDriver struct {
+	backend backends.AtomicBackend
+}
+
+// New returns a lock driver storing locks in the given backend. Expirations
+// rely on instance clocks, which must be kept synchronized.
+func New(backend backends.AtomicBackend) lock.Driver {
+	return &kvDriver{
+		backend: backend,
+	}
+}
+
+// -----------------------------------------------------------------------------
+func (d *kvDriver) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (uint64, bool, error) {
+	current, raw, err := d.get(ctx, name)
+	if err != nil {
+		return 0, false, err
+	}
+
+	now := time.Now()
+	if current != nil && current.held(now) {
+		return 0, false, nil
+	}
+
+	next := &record{
+		Owner:      owner,
+		Expiration: now.Add(ttl).UnixNano(),
+		Token:      1,
+	}
+	if current != nil {
+		next.Token = current.Token + 1
+	}
+
+	ok, err := d.swap(ctx, name, raw, next)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+
+	return next.Token, true, nil
+}
+
+func (d *kvDriver) Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	current, raw, err := d.get(ctx, name)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	if current == nil || current.Owner != owner || !current.held(now) {
+		return false, nil
+	}
+
+	next := *current
+	next.Expiration = now.Add(ttl).UnixNano()
+
+	return d.swap(ctx, name, raw, &next)
+}
+
+func (d *kvDriver) Release(ctx context.Context, name, owner string) error {
+	current, raw, err := d.get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if current == nil || current.Owner != owner || !current.held(time.Now()) {
+		return lock.ErrNotHeld
+	}
+
+	// Keep the token, only clear the owner
+	ok, err := d.swap(ctx, name, raw, &record{Token: current.Token})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return lock.ErrNotHeld
+	}
+
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (d *kvDriver) get(ctx context.Context, name string) (*record, []byte, error) {
+	raw, err := d.backend.Get(ctx, path(name))
+	if err == backends.ErrKeyNotFound {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("lock: Unable to read lock from backend: %v", err)
+	}
+
+	r := &record{}
+	if err := json.Unmarshal(raw, r); err != nil {
+		return nil, nil, fmt.Errorf("lock: Unable to decode lock: %v", err)
+	}
+
+	return r, raw, nil
+}
+
+func (d *kvDriver) swap(ctx context.Context, name string, old []byte, next *record) (bool, error) {
+	payload, err := json.Marshal(next)
+	if err != nil {
+		return false, fmt.Errorf("lock: Unable to marshal lock as JSON: %v", err)
+	}
+
+	ok, err := d.backend.CompareAndSwap(ctx, path(name), old, payload)
+	if err != nil {
+		return false, fmt.Errorf("lock: Unable to save lock to backend: %v", err)
+	}
+
+	return ok, nil
+}
+
+func path(name string) string {
+	return fmt.Sprintf("lock/%s", name)
+}
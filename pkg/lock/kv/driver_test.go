@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/scraly/go.common/pkg/keystore/backends/inmemory"
+	"github.com/scraly/go.common/pkg/lock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newDriver(t *testing.T) lock.Driver {
+	backend, err := inmemory.New()
+	require.NoError(t, err)
+
+	return New(backend)
+}
+
+func TestDriver_Acquire(t *testing.T) {
+	ctx := context.Background()
+	driver := newDriver(t)
+
+	token1, ok, err := driver.Acquire(ctx, "job", "owner1", 20*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok, "First acquisition should succeed")
+	require.Equal(t, uint64(1), token1)
+
+	_, ok, err = driver.Acquire(ctx, "job", "owner2", time.Second)
+	require.NoError(t, err)
+	require.False(t, ok, "Lock should be held")
+
+	time.Sleep(30 * time.Millisecond)
+
+	token2, ok, err := driver.Acquire(ctx, "job", "owner2", time.Second)
+	require.NoError(t, err)
+	require.True(t, ok, "Expired lock should be taken over")
+	require.True(t, token2 > token1, "Fencing token should increase")
+}
+
+func TestDriver_Renew(t *testing.T) {
+	ctx := context.Background()
+	driver := newDriver(t)
+
+	_, ok, err := driver.Acquire(ctx, "job", "owner1", 30*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = driver.Renew(ctx, "job", "owner2", time.Second)
+	require.NoError(t, err)
+	require.False(t, ok, "Only the owner should renew the lock")
+
+	ok, err = driver.Renew(ctx, "job", "owner1", time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	time.Sleep(40 * time.Millisecond)
+	_, ok, err = driver.Acquire(ctx, "job", "owner2", time.Second)
+	require.NoError(t, err)
+	require.False(t, ok, "Renewed lock should not expire")
+
+	ok, err = driver.Renew(ctx, "other", "owner1", time.Second)
+	require.NoError(t, err)
+	require.False(t, ok, "Unknown lock should not be renewed")
+}
+
+func TestDriver_Release(t *testing.T) {
+	ctx := context.Background()
+	driver := newDriver(t)
+
+	_, _, err := driver.Acquire(ctx, "job", "owner1", time.Second)
+	require.NoError(t, err)
+
+	require.Equal(t, lock.ErrNotHeld, driver.Release(ctx, "job", "owner2"), "Only the owner should release the lock")
+	require.NoError(t, driver.Release(ctx, "job", "owner1"))
+	require.Equal(t, lock.ErrNotHeld, driver.Release(ctx, "job", "owner1"), "Lock should not be released twice")
+
+	token, ok, err := driver.Acquire(ctx, "job", "owner2", time.Second)
+	require.NoError(t, err)
+	require.True(t, ok, "Lock should be acquired after release")
+	require.Equal(t, uint64(2), token, "Fencing token should survive release")
+}
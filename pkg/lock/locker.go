@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/scraly/go.common/pkg/log"
+	"go.uber.org/zap"
+
+	"github.com/dchest/uniuri"
+)
+
+type defaultLocker struct {
+	driver Driver
+	dopts  *Options
+}
+
+// New returns a locker using the given driver to hold locks
+func New(driver Driver, opts ...Option) Locker {
+	// Default Options
+	options := &Options{
+		RetryInterval: 100 * time.Millisecond,
+		AutoRenew:     true,
+	}
+
+	// Overrides with option
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &defaultLocker{
+		driver: driver,
+		dopts:  options,
+	}
+}
+
+// -----------------------------------------------------------------------------
+func (l *defaultLocker) Acquire(ctx context.Context, name string, ttl time.Duration) (Lock, error) {
+	for {
+		lck, err := l.TryAcquire(ctx, name, ttl)
+		if err != ErrNotAcquired {
+			return lck, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(l.dopts.RetryInterval):
+		}
+	}
+}
+
+func (l *defaultLocker) TryAcquire(ctx context.Context, name string, ttl time.Duration) (Lock, error) {
+	// Drivers expire locks with millisecond precision
+	if ttl < time.Millisecond {
+		return nil, ErrInvalidTTL
+	}
+
+	owner := uniuri.NewLen(20)
+
+	token, ok, err := l.driver.Acquire(ctx, name, owner, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotAcquired
+	}
+
+	lck := &heldLock{
+		driver: l.driver,
+		name:   name,
+		owner:  owner,
+		token:  token,
+		ttl:    ttl,
+		lost:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if l.dopts.AutoRenew {
+		go lck.renew(time.Now())
+	} else {
+		go lck.expire()
+	}
+
+	return lck, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type heldLock struct {
+	driver Driver
+	name   string
+	owner  string
+	token  uint64
+	ttl    time.Duration
+
+	lost     chan struct{}
+	lostOnce sync.Once
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func (l *heldLock) Name() string {
+	return l.name
+}
+
+func (l *heldLock) Token() uint64 {
+	return l.token
+}
+
+func (l *heldLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *heldLock) Release(ctx context.Context) error {
+	// Stop renewal
+	l.doneOnce.Do(func() {
+		close(l.done)
+	})
+
+	return l.driver.Release(ctx, l.name, l.owner)
+}
+
+func (l *heldLock) renew(acquiredAt time.Time) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	renewedAt := acquiredAt
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+			ok, err := l.driver.Renew(ctx, l.name, l.owner, l.ttl)
+			cancel()
+
+			switch {
+			case err != nil:
+				log.Bg().Warn("Unable to renew lock", zap.String("name", l.name), zap.Error(err))
+				// Retry on next tick while the lock is still valid
+				if time.Since(renewedAt) < l.ttl {
+					continue
+				}
+			case ok:
+				renewedAt = time.Now()
+				continue
+			}
+
+			l.markLost()
+			return
+		}
+	}
+}
+
+func (l *heldLock) expire() {
+	select {
+	case <-l.done:
+	case <-time.After(l.ttl):
+		l.markLost()
+	}
+}
+
+func (l *heldLock) markLost() {
+	l.lostOnce.Do(func() {
+		close(l.lost)
+	})
+}
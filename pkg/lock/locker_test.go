@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package lock_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/scraly/go.common/pkg/keystore/backends/inmemory"
+	"github.com/scraly/go.common/pkg/lock"
+	"github.com/scraly/go.common/pkg/lock/kv"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLocker(t *testing.T, opts ...lock.Option) lock.Locker {
+	backend, err := inmemory.New()
+	require.NoError(t, err)
+
+	return lock.New(kv.New(backend), append([]lock.Option{lock.WithRetryInterval(5 * time.Millisecond)}, opts...)...)
+}
+
+func TestLocker_MutualExclusion(t *testing.T) {
+	ctx := context.Background()
+	locker := newLocker(t)
+
+	l1, err := locker.TryAcquire(ctx, "job", time.Second)
+	require.NoError(t, err, "First acquisition should succeed")
+
+	_, err = locker.TryAcquire(ctx, "job", time.Second)
+	require.Equal(t, lock.ErrNotAcquired, err, "Lock should be held")
+
+	_, err = locker.TryAcquire(ctx, "other", time.Second)
+	require.NoError(t, err, "Locks should be independent")
+
+	require.NoError(t, l1.Release(ctx))
+	require.Equal(t, lock.ErrNotHeld, l1.Release(ctx), "Lock should not be released twice")
+
+	l2, err := locker.Acquire(ctx, "job", time.Second)
+	require.NoError(t, err, "Lock should be acquired after release")
+	require.True(t, l2.Token() > l1.Token(), "Fencing token should increase")
+	require.NoError(t, l2.Release(ctx))
+}
+
+func TestLocker_Expiration(t *testing.T) {
+	ctx := context.Background()
+	locker := newLocker(t, lock.DisableAutoRenew())
+
+	l1, err := locker.TryAcquire(ctx, "job", 20*time.Millisecond)
+	require.NoError(t, err)
+
+	select {
+	case <-l1.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("Lock should be lost after its ttl")
+	}
+
+	l2, err := locker.TryAcquire(ctx, "job", time.Second)
+	require.NoError(t, err, "Expired lock should be taken over")
+	require.True(t, l2.Token() > l1.Token(), "Fencing token should increase")
+	require.Equal(t, lock.ErrNotHeld, l1.Release(ctx), "Expired lock should not release the new owner")
+}
+
+func TestLocker_AutoRenew(t *testing.T) {
+	ctx := context.Background()
+	locker := newLocker(t)
+
+	l1, err := locker.TryAcquire(ctx, "job", 30*time.Millisecond)
+	require.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	_, err = locker.TryAcquire(ctx, "job", time.Second)
+	require.Equal(t, lock.ErrNotAcquired, err, "Lock should have been renewed")
+	require.NoError(t, l1.Release(ctx))
+}
+
+func TestLocker_AcquireCanceled(t *testing.T) {
+	locker := newLocker(t)
+
+	_, err := locker.TryAcquire(context.Background(), "job", time.Second)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = locker.Acquire(ctx, "job", time.Second)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestLocker_InvalidTTL(t *testing.T) {
+	ctx := context.Background()
+	locker := newLocker(t)
+
+	_, err := locker.TryAcquire(ctx, "job", 0)
+	require.Equal(t, lock.ErrInvalidTTL, err)
+
+	_, err = locker.Acquire(ctx, "job", time.Microsecond)
+	require.Equal(t, lock.ErrInvalidTTL, err, "Acquire should not retry an invalid ttl")
+}
+
+func TestElect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	locker := newLocker(t)
+
+	var leaders int32
+	errStop := errors.New("stop")
+	run := func(ctx context.Context, token uint64) error {
+		assert.Equal(t, int32(1), atomic.AddInt32(&leaders, 1), "Only one leader should run")
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&leaders, -1)
+		return errStop
+	}
+
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			errs <- lock.Elect(ctx, locker, "leader", time.Second, run)
+		}()
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-errs:
+			require.Equal(t, errStop, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("All candidates should have been leader once")
+		}
+	}
+}
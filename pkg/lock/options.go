@@ -0,0 +1,36 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package lock
+
+import "time"
+
+// Options contains all values that are needed for locker.
+type Options struct {
+	RetryInterval time.Duration
+	AutoRenew     bool
+}
+
+// Option configures the locker.
+type Option func(*Options)
+
+// WithRetryInterval sets the delay between two acquisition attempts.
+func WithRetryInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.RetryInterval = interval
+	}
+}
+
+// DisableAutoRenew lets acquired locks expire after their ttl.
+func DisableAutoRenew() Option {
+	return func(o *Options) {
+		o.AutoRenew = false
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/scraly/go.common/pkg/lock"
+	"github.com/scraly/go.common/pkg/log"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var (
+	// KEYS[1] lock key, KEYS[2] fencing counter key, ARGV[1] owner, ARGV[2] ttl in ms
+	acquireScript = redis.NewScript(2, `
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0`)
+
+	// KEYS[1] lock key, ARGV[1] owner, ARGV[2] ttl in ms
+	renewScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	// KEYS[1] lock key, ARGV[1] owner
+	releaseScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+type redisDriver struct {
+	pool *redis.Pool
+}
+
+// New returns a lock driver using SET NX PX on the given redis pool. Fencing
+// tokens are kept in a counter key that never expires.
+func New(pool *redis.Pool) lock.Driver {
+	return &redisDriver{
+		pool: pool,
+	}
+}
+
+// -----------------------------------------------------------------------------
+func (d *redisDriver) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (uint64, bool, error) {
+	token, err := redis.Uint64(d.do(ctx, acquireScript, lockKey(name), fenceKey(name), owner, milliseconds(ttl)))
+	if err != nil {
+		return 0, false, err
+	}
+
+	return token, token > 0, nil
+}
+
+func (d *redisDriver) Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	return redis.Bool(d.do(ctx, renewScript, lockKey(name), owner, milliseconds(ttl)))
+}
+
+func (d *redisDriver) Release(ctx context.Context, name, owner string) error {
+	ok, err := redis.Bool(d.do(ctx, releaseScript, lockKey(name), owner))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return lock.ErrNotHeld
+	}
+
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (d *redisDriver) do(ctx context.Context, script *redis.Script, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	conn := d.pool.Get()
+	defer func(conn redis.Conn) {
+		log.SafeClose(conn, "Unable to close redis connection")
+	}(conn)
+
+	return script.Do(conn, args...)
+}
+
+// lockKey and fenceKey share a hash tag so that scripts run on a single
+// Redis Cluster slot
+func lockKey(name string) string {
+	return fmt.Sprintf("lock:{%s}", name)
+}
+
+func fenceKey(name string) string {
+	return fmt.Sprintf("lock:{%s}:fence", name)
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
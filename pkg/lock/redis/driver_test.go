@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/scraly/go.common/pkg/lock"
+
+	"github.com/alicebob/miniredis"
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func newDriver(t *testing.T) (lock.Driver, *miniredis.Miniredis) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+
+	return New(pool), server
+}
+
+func TestDriver_Acquire(t *testing.T) {
+	ctx := context.Background()
+	driver, server := newDriver(t)
+	defer server.Close()
+
+	token1, ok, err := driver.Acquire(ctx, "job", "owner1", time.Second)
+	require.NoError(t, err)
+	require.True(t, ok, "First acquisition should succeed")
+	require.Equal(t, uint64(1), token1)
+
+	_, ok, err = driver.Acquire(ctx, "job", "owner2", time.Second)
+	require.NoError(t, err)
+	require.False(t, ok, "Lock should be held")
+
+	// Keys share a hash tag so that scripts run on a single cluster slot
+	require.True(t, server.Exists("lock:{job}"))
+	require.True(t, server.Exists("lock:{job}:fence"))
+
+	server.FastForward(2 * time.Second)
+
+	token2, ok, err := driver.Acquire(ctx, "job", "owner2", time.Second)
+	require.NoError(t, err)
+	require.True(t, ok, "Expired lock should be taken over")
+	require.True(t, token2 > token1, "Fencing token should increase")
+}
+
+func TestDriver_Renew(t *testing.T) {
+	ctx := context.Background()
+	driver, server := newDriver(t)
+	defer server.Close()
+
+	_, ok, err := driver.Acquire(ctx, "job", "owner1", time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = driver.Renew(ctx, "job", "owner2", time.Second)
+	require.NoError(t, err)
+	require.False(t, ok, "Only the owner should renew the lock")
+
+	server.FastForward(500 * time.Millisecond)
+	ok, err = driver.Renew(ctx, "job", "owner1", time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	server.FastForward(800 * time.Millisecond)
+	require.True(t, server.Exists("lock:{job}"), "Renewed lock should not expire")
+
+	server.FastForward(time.Second)
+	ok, err = driver.Renew(ctx, "job", "owner1", time.Second)
+	require.NoError(t, err)
+	require.False(t, ok, "Expired lock should not be renewed")
+}
+
+func TestDriver_Release(t *testing.T) {
+	ctx := context.Background()
+	driver, server := newDriver(t)
+	defer server.Close()
+
+	_, _, err := driver.Acquire(ctx, "job", "owner1", time.Second)
+	require.NoError(t, err)
+
+	require.Equal(t, lock.ErrNotHeld, driver.Release(ctx, "job", "owner2"), "Only the owner should release the lock")
+	require.NoError(t, driver.Release(ctx, "job", "owner1"))
+	require.Equal(t, lock.ErrNotHeld, driver.Release(ctx, "job", "owner1"), "Lock should not be released twice")
+
+	token, ok, err := driver.Acquire(ctx, "job", "owner2", time.Second)
+	require.NoError(t, err)
+	require.True(t, ok, "Lock should be acquired after release")
+	require.Equal(t, uint64(2), token, "Fencing token should survive release")
+}
+
+func TestDriver_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	driver, server := newDriver(t)
+	defer server.Close()
+
+	_, _, err := driver.Acquire(ctx, "job", "owner1", time.Second)
+	require.Equal(t, context.Canceled, err)
+}
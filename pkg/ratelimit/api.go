@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInvalidRate is raised when a rate limit is not positive or its period is below a millisecond
+var ErrInvalidRate = errors.New("ratelimit: Rate limit must be positive and period at least a millisecond")
+
+// Limiter decides if an event identified by a key may happen now
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// Rate defines the number of events allowed per period
+type Rate struct {
+	Limit  int64
+	Period time.Duration
+	// Burst is the token bucket capacity, defaults to Limit
+	Burst int64
+}
+
+// Result is a limiter decision
+type Result struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	// RetryAfter is the delay before the next event may be allowed, zero if allowed
+	RetryAfter time.Duration
+	// ResetAfter is the delay before the limiter is back to its initial state
+	ResetAfter time.Duration
+}
+
+// PerSecond returns a rate of n events per second
+func PerSecond(n int64) Rate {
+	return Rate{Limit: n, Period: time.Second}
+}
+
+// PerMinute returns a rate of n events per minute
+func PerMinute(n int64) Rate {
+	return Rate{Limit: n, Period: time.Minute}
+}
+
+// PerHour returns a rate of n events per hour
+func PerHour(n int64) Rate {
+	return Rate{Limit: n, Period: time.Hour}
+}
+
+// Validate checks that the rate can be enforced, shared limiters count time
+// in milliseconds
+func (r Rate) Validate() error {
+	if r.Limit <= 0 || r.Period < time.Millisecond {
+		return ErrInvalidRate
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (r Rate) burst() int64 {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+// perNanosecond returns the token refill rate
+func (r Rate) perNanosecond() float64 {
+	return float64(r.Limit) / float64(r.Period)
+}
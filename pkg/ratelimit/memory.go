@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// state is the per key limiter state
+type state interface {
+	allow(now time.Time, rate Rate) Result
+	// idle returns true when the state is back to its initial value
+	idle(now time.Time, rate Rate) bool
+}
+
+type memoryLimiter struct {
+	sync.Mutex
+
+	rate      Rate
+	newState  func(now time.Time, rate Rate) state
+	states    map[string]state
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewTokenBucket returns an in-process token bucket limiter, allowing bursts up
+// to rate.Burst events and refilling rate.Limit tokens per rate.Period
+func NewTokenBucket(rate Rate) (Limiter, error) {
+	if err := rate.Validate(); err != nil {
+		return nil, err
+	}
+
+	return newMemoryLimiter(rate, func(now time.Time, rate Rate) state {
+		return &tokenBucket{
+			tokens: float64(rate.burst()),
+			last:   now,
+		}
+	}), nil
+}
+
+// NewSlidingWindow returns an in-process sliding window limiter, allowing
+// rate.Limit events over any rate.Period interval (weighted approximation)
+func NewSlidingWindow(rate Rate) (Limiter, error) {
+	if err := rate.Validate(); err != nil {
+		return nil, err
+	}
+
+	return newMemoryLimiter(rate, func(now time.Time, rate Rate) state {
+		return &slidingWindow{
+			start: now.Truncate(rate.Period),
+		}
+	}), nil
+}
+
+func newMemoryLimiter(rate Rate, newState func(time.Time, Rate) state) *memoryLimiter {
+	return &memoryLimiter{
+		rate:     rate,
+		newState: newState,
+		states:   map[string]state{},
+		now:      time.Now,
+	}
+}
+
+// -----------------------------------------------------------------------------
+func (l *memoryLimiter) Allow(_ context.Context, key string) (Result, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	s, ok := l.states[key]
+	if !ok {
+		s = l.newState(now, l.rate)
+		l.states[key] = s
+	}
+
+	return s.allow(now, l.rate), nil
+}
+
+// sweep drops idle states to keep memory bounded by the active keys
+func (l *memoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.rate.Period {
+		return
+	}
+	l.lastSweep = now
+
+	for key, s := range l.states {
+		if s.idle(now, l.rate) {
+			delete(l.states, key)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) refill(now time.Time, rate Rate) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(elapsed) * rate.perNanosecond()
+		if burst := float64(rate.burst()); b.tokens > burst {
+			b.tokens = burst
+		}
+		b.last = now
+	}
+}
+
+func (b *tokenBucket) allow(now time.Time, rate Rate) Result {
+	b.refill(now, rate)
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	return TokenBucketResult(rate, b.tokens, allowed)
+}
+
+func (b *tokenBucket) idle(now time.Time, rate Rate) bool {
+	b.refill(now, rate)
+	return b.tokens >= float64(rate.burst())
+}
+
+// -----------------------------------------------------------------------------
+
+type slidingWindow struct {
+	start    time.Time
+	current  int64
+	previous int64
+}
+
+func (w *slidingWindow) slide(now time.Time, rate Rate) {
+	start := now.Truncate(rate.Period)
+	if !start.After(w.start) {
+		return
+	}
+
+	if start.Sub(w.start) == rate.Period {
+		w.previous = w.current
+	} else {
+		w.previous = 0
+	}
+	w.current = 0
+	w.start = start
+}
+
+func (w *slidingWindow) allow(now time.Time, rate Rate) Result {
+	w.slide(now, rate)
+
+	elapsed := now.Sub(w.start)
+	weight := float64(rate.Period-elapsed) / float64(rate.Period)
+	allowed := float64(w.previous)*weight+float64(w.current)+1 <= float64(rate.Limit)
+	if allowed {
+		w.current++
+	}
+
+	return SlidingWindowResult(rate, elapsed, w.current, w.previous, allowed)
+}
+
+func (w *slidingWindow) idle(now time.Time, rate Rate) bool {
+	w.slide(now, rate)
+	return w.current == 0 && w.previous == 0
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func withClock(l Limiter) (*memoryLimiter, *clock) {
+	c := &clock{now: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ml := l.(*memoryLimiter)
+	ml.now = c.Now
+	return ml, c
+}
+
+func TestTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	limiter, err := NewTokenBucket(Rate{Limit: 10, Period: time.Second, Burst: 3})
+	require.NoError(t, err)
+	l, c := withClock(limiter)
+
+	for i := int64(2); i >= 0; i-- {
+		res, err := l.Allow(ctx, "key")
+		require.NoError(t, err)
+		require.True(t, res.Allowed, "Burst should be allowed")
+		require.Equal(t, i, res.Remaining)
+		require.Equal(t, int64(3), res.Limit)
+	}
+
+	res, err := l.Allow(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, res.Allowed, "Bucket should be empty")
+	require.Equal(t, 100*time.Millisecond, res.RetryAfter)
+
+	res, _ = l.Allow(ctx, "other")
+	require.True(t, res.Allowed, "Keys should be independent")
+
+	c.Advance(100 * time.Millisecond)
+	res, _ = l.Allow(ctx, "key")
+	require.True(t, res.Allowed, "Bucket should have been refilled")
+
+	c.Advance(time.Second)
+	res, _ = l.Allow(ctx, "unknown")
+	require.True(t, res.Allowed)
+	require.Len(t, l.states, 1, "Idle states should be swept")
+}
+
+func TestSlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	limiter, err := NewSlidingWindow(PerMinute(10))
+	require.NoError(t, err)
+	l, c := withClock(limiter)
+
+	for i := 0; i < 10; i++ {
+		res, err := l.Allow(ctx, "key")
+		require.NoError(t, err)
+		require.True(t, res.Allowed)
+		require.Equal(t, int64(9-i), res.Remaining)
+	}
+
+	res, _ := l.Allow(ctx, "key")
+	require.False(t, res.Allowed, "Window should be full")
+	require.Equal(t, time.Minute, res.RetryAfter)
+
+	// Previous window weight is 75%: 7.5 estimated events
+	c.Advance(75 * time.Second)
+	res, _ = l.Allow(ctx, "key")
+	require.True(t, res.Allowed)
+	res, _ = l.Allow(ctx, "key")
+	require.True(t, res.Allowed)
+	res, _ = l.Allow(ctx, "key")
+	require.False(t, res.Allowed, "Weighted previous window should be accounted")
+	require.Equal(t, 3*time.Second, res.RetryAfter)
+
+	c.Advance(3 * time.Second)
+	res, _ = l.Allow(ctx, "key")
+	require.True(t, res.Allowed, "Event should be allowed after retry delay")
+}
+
+func TestInvalidRate(t *testing.T) {
+	for _, rate := range []Rate{
+		PerSecond(0),
+		PerMinute(-1),
+		{Limit: 10},
+		{Limit: 10, Period: time.Microsecond},
+	} {
+		_, err := NewTokenBucket(rate)
+		require.Equal(t, ErrInvalidRate, err, "Rate %v should be rejected", rate)
+
+		_, err = NewSlidingWindow(rate)
+		require.Equal(t, ErrInvalidRate, err, "Rate %v should be rejected", rate)
+	}
+}
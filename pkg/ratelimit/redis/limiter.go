@@ -0,0 +1,180 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/scraly/go.common/pkg/log"
+	"github.com/scraly/go.common/pkg/ratelimit"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var (
+	// KEYS[1] bucket key, ARGV[1] tokens per ms, ARGV[2] burst, ARGV[3] now in ms
+	tokenBucketScript = redis.NewScript(1, `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}`)
+
+	// KEYS[1] current window key, KEYS[2] previous window key,
+	// ARGV[1] limit, ARGV[2] period in ms, ARGV[3] elapsed time in current window in ms
+	slidingWindowScript = redis.NewScript(2, `
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local previous = tonumber(redis.call("GET", KEYS[2]) or "0")
+if previous * (period - elapsed) / period + current + 1 > limit then
+	return {0, current, previous}
+end
+current = redis.call("INCR", KEYS[1])
+if current == 1 then
+	redis.call("PEXPIRE", KEYS[1], period * 2)
+end
+return {1, current, previous}`)
+)
+
+type redisLimiter struct {
+	pool   *redis.Pool
+	rate   ratelimit.Rate
+	prefix string
+}
+
+type tokenBucketLimiter struct {
+	redisLimiter
+}
+
+type slidingWindowLimiter struct {
+	redisLimiter
+}
+
+// NewTokenBucket returns a token bucket limiter shared through redis. Time is
+// taken from the calling instance, clocks must be kept synchronized.
+func NewTokenBucket(pool *redis.Pool, rate ratelimit.Rate) (ratelimit.Limiter, error) {
+	if err := rate.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &tokenBucketLimiter{
+		redisLimiter{
+			pool:   pool,
+			rate:   rate,
+			prefix: "ratelimit:tb",
+		},
+	}, nil
+}
+
+// NewSlidingWindow returns a sliding window limiter shared through redis. Time
+// is taken from the calling instance, clocks must be kept synchronized.
+func NewSlidingWindow(pool *redis.Pool, rate ratelimit.Rate) (ratelimit.Limiter, error) {
+	if err := rate.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &slidingWindowLimiter{
+		redisLimiter{
+			pool:   pool,
+			rate:   rate,
+			prefix: "ratelimit:sw",
+		},
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+func (l *tokenBucketLimiter) Allow(ctx context.Context, key string) (ratelimit.Result, error) {
+	burst := l.rate.Burst
+	if burst <= 0 {
+		burst = l.rate.Limit
+	}
+	perMs := float64(l.rate.Limit) / float64(l.rate.Period/time.Millisecond)
+
+	values, err := redis.Values(l.do(ctx, tokenBucketScript,
+		fmt.Sprintf("%s:%s", l.prefix, key),
+		strconv.FormatFloat(perMs, 'g', -1, 64), burst, milliseconds(time.Now())))
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	var allowed int64
+	var tokens string
+	if _, err := redis.Scan(values, &allowed, &tokens); err != nil {
+		return ratelimit.Result{}, err
+	}
+	remaining, err := strconv.ParseFloat(tokens, 64)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	return ratelimit.TokenBucketResult(l.rate, remaining, allowed == 1), nil
+}
+
+func (l *slidingWindowLimiter) Allow(ctx context.Context, key string) (ratelimit.Result, error) {
+	now := time.Now()
+	start := now.Truncate(l.rate.Period)
+	window := start.UnixNano() / int64(l.rate.Period)
+	elapsed := now.Sub(start)
+
+	currentKey, previousKey := l.windowKeys(key, window)
+	values, err := redis.Values(l.do(ctx, slidingWindowScript, currentKey, previousKey,
+		l.rate.Limit, int64(l.rate.Period/time.Millisecond), int64(elapsed/time.Millisecond)))
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	var allowed, current, previous int64
+	if _, err := redis.Scan(values, &allowed, &current, &previous); err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	return ratelimit.SlidingWindowResult(l.rate, elapsed, current, previous, allowed == 1), nil
+}
+
+// windowKeys returns the counter keys of the window and the previous one, the
+// hash tag keeps them in the same Redis Cluster slot
+func (l *slidingWindowLimiter) windowKeys(key string, window int64) (string, string) {
+	return fmt.Sprintf("%s:{%s}:%d", l.prefix, key, window), fmt.Sprintf("%s:{%s}:%d", l.prefix, key, window-1)
+}
+
+// -----------------------------------------------------------------------------
+
+func (l *redisLimiter) do(ctx context.Context, script *redis.Script, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	conn := l.pool.Get()
+	defer func(conn redis.Conn) {
+		log.SafeClose(conn, "Unable to close redis connection")
+	}(conn)
+
+	return script.Do(conn, args...)
+}
+
+func milliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
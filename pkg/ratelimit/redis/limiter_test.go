@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scraly/go.common/pkg/ratelimit"
+
+	"github.com/alicebob/miniredis"
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func newPool(server *miniredis.Miniredis) *redis.Pool {
+	return &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	l, err := NewTokenBucket(newPool(server), ratelimit.Rate{Limit: 1, Period: time.Minute, Burst: 2})
+	require.NoError(t, err)
+
+	for i := int64(1); i >= 0; i-- {
+		res, err := l.Allow(ctx, "key")
+		require.NoError(t, err)
+		require.True(t, res.Allowed, "Burst should be allowed")
+		require.Equal(t, i, res.Remaining)
+		require.Equal(t, int64(2), res.Limit)
+	}
+
+	res, err := l.Allow(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, res.Allowed, "Bucket should be empty")
+	require.InDelta(t, time.Minute, res.RetryAfter, float64(time.Second))
+
+	res, err = l.Allow(ctx, "other")
+	require.NoError(t, err)
+	require.True(t, res.Allowed, "Keys should be independent")
+
+	require.True(t, server.Exists("ratelimit:tb:key"))
+	require.True(t, server.TTL("ratelimit:tb:key") > 0, "Bucket should expire once refilled")
+}
+
+func TestSlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	l, err := NewSlidingWindow(newPool(server), ratelimit.PerMinute(2))
+	require.NoError(t, err)
+
+	for i := int64(1); i >= 0; i-- {
+		res, err := l.Allow(ctx, "key")
+		require.NoError(t, err)
+		require.True(t, res.Allowed)
+		require.Equal(t, i, res.Remaining)
+	}
+
+	// Previous window is still weighted if the window slid between calls
+	res, err := l.Allow(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, res.Allowed, "Window should be full")
+	require.True(t, res.RetryAfter > 0)
+
+	res, err = l.Allow(ctx, "other")
+	require.NoError(t, err)
+	require.True(t, res.Allowed, "Keys should be independent")
+}
+
+func TestSlidingWindowKeys(t *testing.T) {
+	ctx := context.Background()
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	l, err := NewSlidingWindow(newPool(server), ratelimit.PerMinute(2))
+	require.NoError(t, err)
+
+	current, previous := l.(*slidingWindowLimiter).windowKeys("key", 42)
+	require.Equal(t, "ratelimit:sw:{key}:42", current)
+	require.Equal(t, "ratelimit:sw:{key}:41", previous)
+
+	_, err = l.Allow(ctx, "key")
+	require.NoError(t, err)
+
+	keys := server.Keys()
+	require.NotEmpty(t, keys)
+	for _, k := range keys {
+		require.True(t, strings.HasPrefix(k, "ratelimit:sw:{key}:"), "Window keys should share the hash tag")
+	}
+}
+
+func TestInvalidRate(t *testing.T) {
+	_, err := NewTokenBucket(nil, ratelimit.PerSecond(0))
+	require.Equal(t, ratelimit.ErrInvalidRate, err)
+
+	_, err = NewSlidingWindow(nil, ratelimit.Rate{Limit: 10, Period: time.Microsecond})
+	require.Equal(t, ratelimit.ErrInvalidRate, err)
+}
+
+func TestCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	l, err := NewTokenBucket(nil, ratelimit.PerSecond(1))
+	require.NoError(t, err)
+
+	_, err = l.Allow(ctx, "key")
+	require.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// TokenBucketResult computes the decision of a token bucket holding the given
+// tokens once the event has been accounted
+func TokenBucketResult(rate Rate, tokens float64, allowed bool) Result {
+	burst := rate.burst()
+	perNs := rate.perNanosecond()
+
+	res := Result{
+		Allowed:    allowed,
+		Limit:      burst,
+		Remaining:  int64(math.Floor(tokens)),
+		ResetAfter: time.Duration(math.Ceil((float64(burst) - tokens) / perNs)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration(math.Ceil((1 - tokens) / perNs))
+	}
+
+	return res
+}
+
+// SlidingWindowResult computes the decision of a sliding window given the
+// elapsed time in the current window, the current window counter (including
+// the event if allowed) and the previous window counter
+func SlidingWindowResult(rate Rate, elapsed time.Duration, current, previous int64, allowed bool) Result {
+	period := float64(rate.Period)
+	weight := (period - float64(elapsed)) / period
+	estimated := float64(previous)*weight + float64(current)
+
+	res := Result{
+		Allowed:    allowed,
+		Limit:      rate.Limit,
+		Remaining:  rate.Limit - int64(math.Ceil(estimated)),
+		ResetAfter: 2*rate.Period - elapsed,
+	}
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	if current == 0 && previous == 0 {
+		res.ResetAfter = 0
+	}
+
+	if !allowed {
+		if current+1 > rate.Limit || previous == 0 {
+			// Wait for the next window
+			res.RetryAfter = rate.Period - elapsed
+		} else {
+			// Wait for the previous window weight to decrease enough
+			excess := previous - (rate.Limit - current - 1)
+			res.RetryAfter = time.Duration((int64(rate.Period)*excess+previous-1)/previous) - elapsed
+		}
+		if res.RetryAfter <= 0 {
+			res.RetryAfter = time.Millisecond
+		}
+	}
+
+	return res
+}
@@ -30,10 +30,12 @@ type IndexRange struct {
 type Z3Search interface {
 	GetSpaceTimeFillingCurve() zorder.SpaceTimeFillingCurve
 	GetZ3Ranges(bbox BoundingBox, dateMin, dateMax time.Time) ([]*IndexRange, []*utils.WeekTimeRange, error)
+	GetZ3RangesForShape(shape Shape, dateMin, dateMax time.Time) ([]*IndexRange, []*utils.WeekTimeRange, error)
 }
 
 // Z2Search interface
 type Z2Search interface {
 	GetSpaceFillingCurve() zorder.SpaceFillingCurve
 	GetZ2Ranges(bbox BoundingBox) ([]*IndexRange, error)
+	GetZ2RangesForShape(shape Shape) ([]*IndexRange, error)
 }
@@ -0,0 +1,56 @@
+package sfc
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrInvalidBoundingBox is raised when the south latitude is greater than the north latitude
+var ErrInvalidBoundingBox = errors.New("Bounding box south latitude must not be greater than north latitude")
+
+// CrossesAntimeridian returns true if the west longitude is greater than the
+// east longitude, such as 170 to -170
+func (b BoundingBox) CrossesAntimeridian() bool {
+	return b.SouthWest.Longitude > b.NorthEast.Longitude
+}
+
+// Split normalizes the box and splits it at the antimeridian into boxes that
+// can be indexed. Longitudes are wrapped to [-180, 180] and a box spanning 360
+// degrees or more covers all longitudes. Latitudes are clamped to [-90, 90], so
+// that polar caps may be given with overshooting latitudes.
+func (b BoundingBox) Split() ([]BoundingBox, error) {
+	south, north := b.SouthWest.Latitude, b.NorthEast.Latitude
+	west, east := b.SouthWest.Longitude, b.NorthEast.Longitude
+	if math.IsNaN(south) || math.IsNaN(north) || math.IsNaN(west) || math.IsNaN(east) || south > north {
+		return nil, ErrInvalidBoundingBox
+	}
+	south, north = math.Max(-90, south), math.Min(90, north)
+
+	if west <= east && east-west >= 360 {
+		west, east = -180, 180
+	}
+	west, east = wrapLongitude(west), wrapLongitude(east)
+
+	if west <= east {
+		return []BoundingBox{
+			{SouthWest: Point{Longitude: west, Latitude: south}, NorthEast: Point{Longitude: east, Latitude: north}},
+		}, nil
+	}
+
+	return []BoundingBox{
+		{SouthWest: Point{Longitude: west, Latitude: south}, NorthEast: Point{Longitude: 180, Latitude: north}},
+		{SouthWest: Point{Longitude: -180, Latitude: south}, NorthEast: Point{Longitude: east, Latitude: north}},
+	}, nil
+}
+
+// wrapLongitude returns the longitude in [-180, 180]
+func wrapLongitude(lon float64) float64 {
+	if lon >= -180 && lon <= 180 {
+		return lon
+	}
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	return lon - 180
+}
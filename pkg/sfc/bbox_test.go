@@ -0,0 +1,101 @@
+package sfc_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/scraly/go.common/pkg/sfc"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name   string
+		bbox   sfc.BoundingBox
+		result []sfc.BoundingBox
+		err    error
+	}{
+		{
+			name:   "regular",
+			bbox:   box(1.4, 43.5, 1.5, 44),
+			result: []sfc.BoundingBox{box(1.4, 43.5, 1.5, 44)},
+		},
+		{
+			name:   "antimeridian",
+			bbox:   box(170, -20, -170, 20),
+			result: []sfc.BoundingBox{box(170, -20, 180, 20), box(-180, -20, -170, 20)},
+		},
+		{
+			name:   "overshooting east",
+			bbox:   box(170, -20, 190, 20),
+			result: []sfc.BoundingBox{box(170, -20, 180, 20), box(-180, -20, -170, 20)},
+		},
+		{
+			name:   "overshooting west",
+			bbox:   box(-190, -20, -170, 20),
+			result: []sfc.BoundingBox{box(170, -20, 180, 20), box(-180, -20, -170, 20)},
+		},
+		{
+			name:   "shifted",
+			bbox:   box(190, -20, 200, 20),
+			result: []sfc.BoundingBox{box(-170, -20, -160, 20)},
+		},
+		{
+			name:   "all longitudes",
+			bbox:   box(-200, 80, 200, 90),
+			result: []sfc.BoundingBox{box(-180, 80, 180, 90)},
+		},
+		{
+			name:   "polar cap",
+			bbox:   box(-180, 75, 180, 95),
+			result: []sfc.BoundingBox{box(-180, 75, 180, 90)},
+		},
+		{
+			name:   "south polar cap",
+			bbox:   box(-180, -100, 180, -60),
+			result: []sfc.BoundingBox{box(-180, -90, 180, -60)},
+		},
+		{
+			name: "inverted latitudes",
+			bbox: box(0, 10, 1, 5),
+			err:  sfc.ErrInvalidBoundingBox,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			result, err := tt.bbox.Split()
+			if tt.err != nil {
+				Expect(err).To(Equal(tt.err))
+				return
+			}
+
+			Expect(err).To(BeNil())
+			Expect(result).To(Equal(tt.result))
+			Expect(tt.bbox.CrossesAntimeridian()).To(Equal(tt.name == "antimeridian"))
+		})
+	}
+}
+
+func TestCircleAntimeridian(t *testing.T) {
+	RegisterTestingT(t)
+
+	// Taveuni, Fiji
+	circle, _ := sfc.NewCircle(sfc.Point{Longitude: 179.95, Latitude: -16.8}, 20000)
+
+	bounds := circle.Bounds()
+	Expect(bounds.CrossesAntimeridian()).To(BeTrue())
+	Expect(bounds.SouthWest.Longitude).To(BeNumerically("~", 179.76, 0.01))
+	Expect(bounds.NorthEast.Longitude).To(BeNumerically("~", -179.86, 0.01))
+
+	Expect(circle.ContainsBox(box(-180, -16.85, -179.95, -16.75))).To(BeTrue())
+	Expect(circle.IntersectsBox(box(-179.9, -17, -179.8, -16.6))).To(BeTrue())
+	Expect(circle.IntersectsBox(box(-179.8, -17, -179.7, -16.6))).To(BeFalse())
+
+	polyline, _ := sfc.NewPolyline([]sfc.Point{{Longitude: 179.9, Latitude: -16.8}, {Longitude: -179.9, Latitude: -16.8}}, 1000)
+	Expect(polyline.Bounds().CrossesAntimeridian()).To(BeTrue())
+	Expect(polyline.ContainsBox(box(-180, -16.801, -179.99, -16.799))).To(BeTrue())
+	Expect(polyline.IntersectsBox(box(-179.95, -16.9, -179.94, -16.7))).To(BeTrue())
+}
@@ -0,0 +1,95 @@
+package hilbert
+
+import (
+	"sync"
+
+	api "github.com/scraly/go.common/pkg/sfc/zorder"
+)
+
+// H2 struct is a 2D Hilbert index with 31 bits per dimension
+type H2 struct {
+	*sync.RWMutex
+
+	BitsPerDimension int
+	Dimensions       int
+	TotalBits        int
+	Quadrants        int
+	HValue           uint64
+
+	curve curve
+}
+
+// NewH2 method constructs a H2 struct and returns it as Z2N interface
+func NewH2() api.Z2N {
+	return newInternalH2(0)
+}
+
+// NewH2WithHValue constructs a H2 struct with initial index value and returns it as Z2N interface
+func NewH2WithHValue(h uint64) api.Z2N {
+	return newInternalH2(h)
+}
+
+func newInternalH2(h uint64) *H2 {
+	return &H2{
+		RWMutex:          &sync.RWMutex{},
+		BitsPerDimension: 31,
+		Dimensions:       2,
+		TotalBits:        62,
+		Quadrants:        4,
+		HValue:           h,
+		curve:            curve{dimensions: 2, bits: 31},
+	}
+}
+
+// Apply method calculates the Hilbert index of x,y
+func (h2 *H2) Apply(x, y uint) uint64 {
+	h2.setHValue(h2.curve.encode(coords{x, y}))
+	return h2.GetZValue()
+}
+
+// UnApply method calculates x,y from the Hilbert index
+func (h2 *H2) UnApply(h uint64) (uint, uint) {
+	h2.setHValue(h)
+	values := h2.curve.decode(h)
+	return values[0], values[1]
+}
+
+// GetDimensions returns the number of dimensions (2 for H2)
+func (h2 *H2) GetDimensions() int {
+	return h2.Dimensions
+}
+
+// GetQuadrants returns the number of quadrants (4 for H2)
+func (h2 *H2) GetQuadrants() int {
+	return h2.Quadrants
+}
+
+// GetTotalBits returns the total bits used to encode H2
+func (h2 *H2) GetTotalBits() int {
+	return h2.TotalBits
+}
+
+// GetZValue returns the computed Hilbert index
+func (h2 *H2) GetZValue() uint64 {
+	return h2.HValue
+}
+
+func (h2 *H2) setHValue(hValue uint64) {
+	h2.Lock()
+	defer h2.Unlock()
+
+	h2.HValue = hValue
+}
+
+// Contains indicates if the value is in the box with opposite corners rangeZ.Min and rangeZ.Max
+func (h2 *H2) Contains(rangeZ api.ZRange, value uint64) bool {
+	min, max := h2.curve.box(rangeZ)
+	return contains(min, max, h2.curve.decode(value))
+}
+
+// Overlaps indicates if the boxes with opposite corners range1 and range2 bounds are overlapped
+func (h2 *H2) Overlaps(range1 api.ZRange, range2 api.ZRange) bool {
+	min1, max1 := h2.curve.box(range1)
+	min2, max2 := h2.curve.box(range2)
+	return overlaps(min1, max1, min2, max2)
+}
@@ -0,0 +1,95 @@
+package hilbert
+
+import (
+	"sync"
+
+	api "github.com/scraly/go.common/pkg/sfc/zorder"
+)
+
+// H3 struct is a 3D Hilbert index with 21 bits per dimension
+type H3 struct {
+	*sync.RWMutex
+
+	BitsPerDimension int
+	Dimensions       int
+	TotalBits        int
+	Quadrants        int
+	HValue           uint64
+
+	curve curve
+}
+
+// NewH3 method constructs a H3 struct and returns it as Z3N interface
+func NewH3() api.Z3N {
+	return newInternalH3(0)
+}
+
+// NewH3WithHValue constructs a H3 struct with initial index value and returns it as Z3N interface
+func NewH3WithHValue(h uint64) api.Z3N {
+	return newInternalH3(h)
+}
+
+func newInternalH3(h uint64) *H3 {
+	return &H3{
+		RWMutex:          &sync.RWMutex{},
+		BitsPerDimension: 21,
+		Dimensions:       3,
+		TotalBits:        63,
+		Quadrants:        8,
+		HValue:           h,
+		curve:            curve{dimensions: 3, bits: 21},
+	}
+}
+
+// Apply method calculates the Hilbert index of x,y,z
+func (h3 *H3) Apply(x, y, z uint) uint64 {
+	h3.setHValue(h3.curve.encode(coords{x, y, z}))
+	return h3.GetZValue()
+}
+
+// UnApply method calculates x,y,z from the Hilbert index
+func (h3 *H3) UnApply(h uint64) (uint, uint, uint) {
+	h3.setHValue(h)
+	values := h3.curve.decode(h)
+	return values[0], values[1], values[2]
+}
+
+// GetDimensions returns the number of dimensions (3 for H3)
+func (h3 *H3) GetDimensions() int {
+	return h3.Dimensions
+}
+
+// GetQuadrants returns the number of quadrants (8 for H3)
+func (h3 *H3) GetQuadrants() int {
+	return h3.Quadrants
+}
+
+// GetTotalBits returns the total bits used to encode H3
+func (h3 *H3) GetTotalBits() int {
+	return h3.TotalBits
+}
+
+// GetZValue returns the computed Hilbert index
+func (h3 *H3) GetZValue() uint64 {
+	return h3.HValue
+}
+
+func (h3 *H3) setHValue(hValue uint64) {
+	h3.Lock()
+	defer h3.Unlock()
+
+	h3.HValue = hValue
+}
+
+// Contains indicates if the value is in the box with opposite corners rangeZ.Min and rangeZ.Max
+func (h3 *H3) Contains(rangeZ api.ZRange, value uint64) bool {
+	min, max := h3.curve.box(rangeZ)
+	return contains(min, max, h3.curve.decode(value))
+}
+
+// Overlaps indicates if the boxes with opposite corners range1 and range2 bounds are overlapped
+func (h3 *H3) Overlaps(range1 api.ZRange, range2 api.ZRange) bool {
+	min1, max1 := h3.curve.box(range1)
+	min2, max2 := h3.curve.box(range2)
+	return overlaps(min1, max1, min2, max2)
+}
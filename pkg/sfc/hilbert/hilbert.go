@@ -0,0 +1,162 @@
+// Package hilbert implements 2D and 3D Hilbert space filling curves behind the
+// zorder.SpaceFillingCurve and zorder.SpaceTimeFillingCurve interfaces.
+//
+// Consecutive Hilbert indexes are always neighbour cells, so that the cells
+// of a search area usually merge into fewer ranges than with a Z-order curve,
+// at the cost of a slower index computation. As with Z-order, an aligned block
+// of 2^(dimensions*k) indexes covers an aligned cube of 2^k cells per side,
+// which the range decomposition relies on: both curves cover the same cells
+// for the same recursion depth.
+package hilbert
+
+import (
+	"math/bits"
+
+	"github.com/scraly/go.common/pkg/sfc/zorder"
+)
+
+// maxDimensions is the number of dimensions of the 3D curve
+const maxDimensions = 3
+
+// coords holds a value per dimension, unused dimensions are zero
+type coords [maxDimensions]uint
+
+// curve encodes coordinates of dimensions values of bits each, using the
+// transpose algorithm from J. Skilling, "Programming the Hilbert curve" (2004)
+type curve struct {
+	dimensions int
+	bits       uint
+}
+
+// encode returns the index of the coordinates
+func (c curve) encode(values coords) uint64 {
+	var buf [maxDimensions]uint64
+	x := buf[:c.dimensions]
+	mask := uint64(1)<<c.bits - 1
+	for i := range x {
+		x[i] = uint64(values[i]) & mask
+	}
+
+	// Inverse undo
+	for q := uint64(1) << (c.bits - 1); q > 1; q >>= 1 {
+		p := q - 1
+		for i := range x {
+			if x[i]&q != 0 {
+				x[0] ^= p
+			} else {
+				t := (x[0] ^ x[i]) & p
+				x[0] ^= t
+				x[i] ^= t
+			}
+		}
+	}
+
+	// Gray encode
+	for i := 1; i < c.dimensions; i++ {
+		x[i] ^= x[i-1]
+	}
+	t := uint64(0)
+	for q := uint64(1) << (c.bits - 1); q > 1; q >>= 1 {
+		if x[c.dimensions-1]&q != 0 {
+			t ^= q - 1
+		}
+	}
+	for i := range x {
+		x[i] ^= t
+	}
+
+	// Interleave the transpose, most significant bits first
+	index := uint64(0)
+	for b := int(c.bits) - 1; b >= 0; b-- {
+		for i := range x {
+			index = index<<1 | (x[i]>>uint(b))&1
+		}
+	}
+	return index
+}
+
+// decode returns the coordinates of the index
+func (c curve) decode(index uint64) coords {
+	var buf [maxDimensions]uint64
+	x := buf[:c.dimensions]
+	for b := int(c.bits) - 1; b >= 0; b-- {
+		for i := range x {
+			shift := uint(b*c.dimensions + c.dimensions - 1 - i)
+			x[i] |= (index >> shift & 1) << uint(b)
+		}
+	}
+
+	// Gray decode
+	t := x[c.dimensions-1] >> 1
+	for i := c.dimensions - 1; i > 0; i-- {
+		x[i] ^= x[i-1]
+	}
+	x[0] ^= t
+
+	// Undo excess work
+	for q := uint64(2); q != uint64(1)<<c.bits; q <<= 1 {
+		p := q - 1
+		for i := c.dimensions - 1; i >= 0; i-- {
+			if x[i]&q != 0 {
+				x[0] ^= p
+			} else {
+				t := (x[0] ^ x[i]) & p
+				x[0] ^= t
+				x[i] ^= t
+			}
+		}
+	}
+
+	var values coords
+	for i := range x {
+		values[i] = uint(x[i])
+	}
+	return values
+}
+
+// cells returns the lowest and highest coordinates of the cube covered by an
+// aligned block of indexes, such as a quadrant of the range decomposition
+func (c curve) cells(block zorder.ZRange) (coords, coords) {
+	side := uint(1) << uint(bits.Len64(block.Max-block.Min)/c.dimensions)
+
+	min := c.decode(block.Min)
+	var max coords
+	for i := 0; i < c.dimensions; i++ {
+		min[i] &^= side - 1
+		max[i] = min[i] + side - 1
+	}
+	return min, max
+}
+
+// box returns the lowest and highest coordinates of the box with two opposite
+// corners given by their index
+func (c curve) box(rangeZ zorder.ZRange) (coords, coords) {
+	min, max := c.decode(rangeZ.Min), c.decode(rangeZ.Max)
+	for i := 0; i < c.dimensions; i++ {
+		if min[i] > max[i] {
+			min[i], max[i] = max[i], min[i]
+		}
+	}
+	return min, max
+}
+
+// contains returns true if the coordinates are in the box, unused dimensions
+// are zero and always match
+func contains(min, max, values coords) bool {
+	for i := range values {
+		if values[i] < min[i] || values[i] > max[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// overlaps returns true if the boxes overlap
+func overlaps(min1, max1, min2, max2 coords) bool {
+	for i := range min1 {
+		if min1[i] > max2[i] || min2[i] > max1[i] {
+			return false
+		}
+	}
+	return true
+}
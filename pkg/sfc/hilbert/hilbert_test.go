@@ -0,0 +1,409 @@
+package hilbert_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/scraly/go.common/pkg/sfc"
+	"github.com/scraly/go.common/pkg/sfc/hilbert"
+	"github.com/scraly/go.common/pkg/sfc/utils"
+	"github.com/scraly/go.common/pkg/sfc/zorder"
+	"github.com/scraly/go.common/pkg/sfc/zorder/normalizer"
+	"github.com/scraly/go.common/pkg/sfc/zorder/z2"
+	"github.com/scraly/go.common/pkg/sfc/zorder/z3"
+)
+
+func distance(p1, p2 []uint) uint {
+	d := uint(0)
+	for i := range p1 {
+		if p1[i] > p2[i] {
+			d += p1[i] - p2[i]
+		} else {
+			d += p2[i] - p1[i]
+		}
+	}
+	return d
+}
+
+func TestH2(t *testing.T) {
+	RegisterTestingT(t)
+
+	h2 := hilbert.NewH2()
+	Expect(h2.Apply(0, 0)).To(Equal(uint64(0)))
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		x, y := uint(r.Int31()), uint(r.Int31())
+
+		// Round trip
+		h := h2.Apply(x, y)
+		Expect(h).To(BeNumerically("<", uint64(1)<<62))
+		ux, uy := h2.UnApply(h)
+		Expect([]uint{ux, uy}).To(Equal([]uint{x, y}))
+
+		// Consecutive indexes are neighbour cells
+		if h+1 < uint64(1)<<62 {
+			nx, ny := h2.UnApply(h + 1)
+			Expect(distance([]uint{x, y}, []uint{nx, ny})).To(Equal(uint(1)))
+		}
+
+		// An aligned block of 4^k indexes is an aligned square of side 2^k
+		k := uint(r.Intn(31))
+		side := uint(1) << k
+		first := h &^ (uint64(1)<<(2*k) - 1)
+		last := first | (uint64(1)<<(2*k) - 1)
+		fx, fy := h2.UnApply(first)
+		lx, ly := h2.UnApply(last)
+		Expect(x &^ (side - 1)).To(Equal(fx &^ (side - 1)))
+		Expect(y &^ (side - 1)).To(Equal(fy &^ (side - 1)))
+		Expect(x &^ (side - 1)).To(Equal(lx &^ (side - 1)))
+		Expect(y &^ (side - 1)).To(Equal(ly &^ (side - 1)))
+	}
+}
+
+func TestH3(t *testing.T) {
+	RegisterTestingT(t)
+
+	h3 := hilbert.NewH3()
+	Expect(h3.Apply(0, 0, 0)).To(Equal(uint64(0)))
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		x, y, z := uint(r.Intn(1<<21)), uint(r.Intn(1<<21)), uint(r.Intn(1<<21))
+
+		h := h3.Apply(x, y, z)
+		Expect(h).To(BeNumerically("<", uint64(1)<<63))
+		ux, uy, uz := h3.UnApply(h)
+		Expect([]uint{ux, uy, uz}).To(Equal([]uint{x, y, z}))
+
+		if h+1 < uint64(1)<<63 {
+			nx, ny, nz := h3.UnApply(h + 1)
+			Expect(distance([]uint{x, y, z}, []uint{nx, ny, nz})).To(Equal(uint(1)))
+		}
+
+		k := uint(r.Intn(21))
+		side := uint(1) << k
+		first := h &^ (uint64(1)<<(3*k) - 1)
+		fx, fy, fz := h3.UnApply(first)
+		Expect([]uint{fx &^ (side - 1), fy &^ (side - 1), fz &^ (side - 1)}).To(Equal([]uint{x &^ (side - 1), y &^ (side - 1), z &^ (side - 1)}))
+	}
+}
+
+func TestH2Sfc(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, err := hilbert.NewH2Sfc(32)
+	Expect(err).ToNot(BeNil())
+
+	h2Sfc, err := hilbert.NewH2Sfc(hilbert.H2MaxPrecision)
+	Expect(err).To(BeNil())
+
+	_, err = h2Sfc.Index(181, 0)
+	Expect(err).ToNot(BeNil())
+
+	h2n, err := h2Sfc.Index(1.391483667, 43.56362116)
+	Expect(err).To(BeNil())
+
+	x, y := h2Sfc.Invert(h2n)
+	Expect(x).Should(BeNumerically("~", 1.391483667, 0.0001))
+	Expect(y).Should(BeNumerically("~", 43.56362116, 0.0001))
+}
+
+func TestH3Sfc(t *testing.T) {
+	RegisterTestingT(t)
+
+	h3Sfc, err := hilbert.NewH3Sfc(hilbert.H3MaxPrecision)
+	Expect(err).To(BeNil())
+
+	_, err = h3Sfc.Index(0, 0, 604801)
+	Expect(err).ToNot(BeNil())
+
+	h3n, err := h3Sfc.Index(1.391483667, 43.56362116, 86400)
+	Expect(err).To(BeNil())
+
+	x, y, s := h3Sfc.Invert(h3n)
+	Expect(x).Should(BeNumerically("~", 1.391483667, 0.0001))
+	Expect(y).Should(BeNumerically("~", 43.56362116, 0.0001))
+	Expect(s).Should(BeNumerically("~", 86400, 0.2))
+}
+
+// -----------------------------------------------------------------------------
+
+var queries = []struct {
+	name string
+	bbox sfc.BoundingBox
+}{
+	{
+		name: "toulouse",
+		bbox: sfc.BoundingBox{SouthWest: sfc.Point{Longitude: 1.4, Latitude: 43.5}, NorthEast: sfc.Point{Longitude: 1.5, Latitude: 44}},
+	},
+	{
+		name: "south-west-france",
+		bbox: sfc.BoundingBox{SouthWest: sfc.Point{Longitude: -0.83, Latitude: 43.52}, NorthEast: sfc.Point{Longitude: 5.29, Latitude: 47.44}},
+	},
+	{
+		name: "fiji",
+		bbox: sfc.BoundingBox{SouthWest: sfc.Point{Longitude: 176, Latitude: -21}, NorthEast: sfc.Point{Longitude: -178, Latitude: -12}},
+	},
+}
+
+var (
+	dateMin = time.Date(2018, 9, 10, 7, 0, 0, 0, time.UTC)
+	dateMax = time.Date(2018, 9, 10, 19, 0, 0, 0, time.UTC)
+)
+
+func TestH2Search(t *testing.T) {
+	_, err := hilbert.NewH2Search(0)
+	if err == nil {
+		t.Fatal("precision 0 must be rejected")
+	}
+
+	for _, tt := range queries {
+		t.Run(tt.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			search, _ := hilbert.NewH2Search(hilbert.H2MaxPrecision)
+			result, err := search.GetZ2Ranges(tt.bbox)
+			Expect(err).To(BeNil())
+			Expect(result).ToNot(BeEmpty())
+
+			for i := 1; i < len(result); i++ {
+				Expect(result[i].Lower).To(BeNumerically(">", result[i-1].Upper))
+			}
+
+			curve := search.GetSpaceFillingCurve()
+			boxes, _ := tt.bbox.Split()
+			for _, box := range boxes {
+				stepx := (box.NorthEast.Longitude - box.SouthWest.Longitude) / 20
+				stepy := (box.NorthEast.Latitude - box.SouthWest.Latitude) / 20
+				for x := box.SouthWest.Longitude; x <= box.NorthEast.Longitude; x = x + stepx {
+					for y := box.SouthWest.Latitude; y <= box.NorthEast.Latitude; y = y + stepy {
+						h2n, _ := curve.Index(x, y)
+						Expect(findRange(h2n.GetZValue(), result)).ToNot(BeNil())
+					}
+				}
+			}
+
+			// Contained ranges are inside the box
+			for _, indexRange := range result {
+				if !indexRange.Contained {
+					continue
+				}
+				for _, h := range []uint64{indexRange.Lower, (indexRange.Lower + indexRange.Upper) / 2, indexRange.Upper} {
+					x, y := curve.Invert(hilbert.NewH2WithHValue(h))
+					inside := false
+					for _, box := range boxes {
+						inside = inside || (x >= box.SouthWest.Longitude && x <= box.NorthEast.Longitude && y >= box.SouthWest.Latitude && y <= box.NorthEast.Latitude)
+					}
+					Expect(inside).To(BeTrue())
+				}
+			}
+		})
+	}
+}
+
+func TestH2SearchShape(t *testing.T) {
+	RegisterTestingT(t)
+
+	circle, _ := sfc.NewCircle(sfc.Point{Longitude: 1.444, Latitude: 43.604}, 5000)
+
+	search, _ := hilbert.NewH2Search(hilbert.H2MaxPrecision)
+	result, err := search.GetZ2RangesForShape(circle)
+	Expect(err).To(BeNil())
+	Expect(result).ToNot(BeEmpty())
+
+	bbox := circle.Bounds()
+	curve := search.GetSpaceFillingCurve()
+	stepx := (bbox.NorthEast.Longitude - bbox.SouthWest.Longitude) / 50
+	stepy := (bbox.NorthEast.Latitude - bbox.SouthWest.Latitude) / 50
+	contained := false
+	for x := bbox.SouthWest.Longitude; x <= bbox.NorthEast.Longitude; x = x + stepx {
+		for y := bbox.SouthWest.Latitude; y <= bbox.NorthEast.Latitude; y = y + stepy {
+			point := sfc.Point{Longitude: x, Latitude: y}
+			h2n, _ := curve.Index(x, y)
+			indexRange := findRange(h2n.GetZValue(), result)
+
+			if circle.ContainsBox(sfc.BoundingBox{SouthWest: point, NorthEast: point}) {
+				Expect(indexRange).ToNot(BeNil())
+				contained = contained || indexRange.Contained
+			} else if indexRange != nil {
+				Expect(indexRange.Contained).To(BeFalse())
+			}
+		}
+	}
+	Expect(contained).To(BeTrue())
+}
+
+func TestH3Search(t *testing.T) {
+	for _, tt := range queries {
+		t.Run(tt.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			search, _ := hilbert.NewH3Search()
+			result, weekTimeRanges, err := search.GetZ3Ranges(tt.bbox, dateMin, dateMax)
+			Expect(err).To(BeNil())
+			Expect(result).ToNot(BeEmpty())
+
+			stfc := search.GetSpaceTimeFillingCurve()
+			boxes, _ := tt.bbox.Split()
+			for _, box := range boxes {
+				stepx := (box.NorthEast.Longitude - box.SouthWest.Longitude) / 10
+				stepy := (box.NorthEast.Latitude - box.SouthWest.Latitude) / 10
+				for x := box.SouthWest.Longitude; x <= box.NorthEast.Longitude; x = x + stepx {
+					for y := box.SouthWest.Latitude; y <= box.NorthEast.Latitude; y = y + stepy {
+						for _, weekTimeRange := range weekTimeRanges {
+							for s := weekTimeRange.MinWeekDate.Seconds; s <= weekTimeRange.MaxWeekDate.Seconds; s = s + 3600 {
+								h3n, _ := stfc.Index(x, y, uint64(math.Round(s)))
+								Expect(findRange(h3n.GetZValue(), result)).ToNot(BeNil())
+							}
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestH3SearchShape(t *testing.T) {
+	RegisterTestingT(t)
+
+	circle, _ := sfc.NewCircle(sfc.Point{Longitude: 1.444, Latitude: 43.604}, 5000)
+
+	search, _ := hilbert.NewH3Search()
+	result, weekTimeRanges, err := search.GetZ3RangesForShape(circle, dateMin, dateMax)
+	Expect(err).To(BeNil())
+	Expect(result).ToNot(BeEmpty())
+
+	bbox := circle.Bounds()
+	stfc := search.GetSpaceTimeFillingCurve()
+	stepx := (bbox.NorthEast.Longitude - bbox.SouthWest.Longitude) / 10
+	stepy := (bbox.NorthEast.Latitude - bbox.SouthWest.Latitude) / 10
+	for x := bbox.SouthWest.Longitude; x <= bbox.NorthEast.Longitude; x = x + stepx {
+		for y := bbox.SouthWest.Latitude; y <= bbox.NorthEast.Latitude; y = y + stepy {
+			point := sfc.Point{Longitude: x, Latitude: y}
+			inside := circle.ContainsBox(sfc.BoundingBox{SouthWest: point, NorthEast: point})
+
+			for s := weekTimeRanges[0].MinWeekDate.Seconds; s <= weekTimeRanges[0].MaxWeekDate.Seconds; s = s + 7200 {
+				h3n, _ := stfc.Index(x, y, uint64(math.Round(s)))
+				indexRange := findRange(h3n.GetZValue(), result)
+
+				if inside {
+					Expect(indexRange).ToNot(BeNil())
+				} else if indexRange != nil {
+					Expect(indexRange.Contained).To(BeFalse())
+				}
+			}
+		}
+	}
+}
+
+func findRange(value uint64, ranges []*sfc.IndexRange) *sfc.IndexRange {
+	for _, indexRange := range ranges {
+		if indexRange.Lower <= value && value <= indexRange.Upper {
+			return indexRange
+		}
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// falsePositiveRatio returns the share of indexes in ranges that are outside
+// of the query, with the given number of cells per box and time range
+func falsePositiveRatio(ranges []*sfc.IndexRange, queryCells float64) float64 {
+	covered := 0.0
+	for _, indexRange := range ranges {
+		covered += float64(indexRange.Upper - indexRange.Lower + 1)
+	}
+	return 1 - queryCells/covered
+}
+
+// boxCells returns the number of normalized cells in bbox
+func boxCells(bbox sfc.BoundingBox, precision uint) float64 {
+	lon, _ := normalizer.NewNormalizer(-180, 180, precision)
+	lat, _ := normalizer.NewNormalizer(-90, 90, precision)
+
+	cells := 0.0
+	boxes, _ := bbox.Split()
+	for _, box := range boxes {
+		cells += float64(lon.Normalize(box.NorthEast.Longitude)-lon.Normalize(box.SouthWest.Longitude)+1) *
+			float64(lat.Normalize(box.NorthEast.Latitude)-lat.Normalize(box.SouthWest.Latitude)+1)
+	}
+	return cells
+}
+
+// timeCells returns the number of normalized time cells in the week time ranges
+func timeCells(weekTimeRanges []*utils.WeekTimeRange, precision uint) float64 {
+	t, _ := normalizer.NewNormalizer(0, 604800, precision)
+
+	cells := 0.0
+	for _, weekTimeRange := range weekTimeRanges {
+		cells += float64(t.Normalize(math.Floor(weekTimeRange.MaxWeekDate.Seconds)) - t.Normalize(math.Floor(weekTimeRange.MinWeekDate.Seconds)) + 1)
+	}
+	return cells
+}
+
+func benchmarkZ2Search(b *testing.B, search sfc.Z2Search) {
+	for _, q := range queries {
+		b.Run(q.name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			var result []*sfc.IndexRange
+			for n := 0; n < b.N; n++ {
+				result, _ = search.GetZ2Ranges(q.bbox)
+			}
+
+			b.ReportMetric(float64(len(result)), "ranges")
+			b.ReportMetric(falsePositiveRatio(result, boxCells(q.bbox, 31)), "false-positive-ratio")
+		})
+	}
+}
+
+func benchmarkZ3Search(b *testing.B, search sfc.Z3Search) {
+	for _, q := range queries {
+		b.Run(q.name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			var result []*sfc.IndexRange
+			var weekTimeRanges []*utils.WeekTimeRange
+			for n := 0; n < b.N; n++ {
+				result, weekTimeRanges, _ = search.GetZ3Ranges(q.bbox, dateMin, dateMax)
+			}
+
+			b.ReportMetric(float64(len(result)), "ranges")
+			b.ReportMetric(falsePositiveRatio(result, boxCells(q.bbox, 21)*timeCells(weekTimeRanges, 21)), "false-positive-ratio")
+		})
+	}
+}
+
+func BenchmarkZ2Search(b *testing.B) {
+	search, _ := z2.NewSearch(z2.NormalizerMaxPrecision)
+	benchmarkZ2Search(b, search)
+}
+
+func BenchmarkH2Search(b *testing.B) {
+	search, _ := hilbert.NewH2Search(hilbert.H2MaxPrecision)
+	benchmarkZ2Search(b, search)
+}
+
+func BenchmarkZ3Search(b *testing.B) {
+	search, _ := z3.NewSearch()
+	benchmarkZ3Search(b, search)
+}
+
+func BenchmarkH3Search(b *testing.B) {
+	search, _ := hilbert.NewH3Search()
+	benchmarkZ3Search(b, search)
+}
+
+// Both curves implement the same interfaces
+var (
+	_ zorder.SpaceFillingCurve     = &hilbert.H2Sfc{}
+	_ zorder.SpaceTimeFillingCurve = &hilbert.H3Sfc{}
+	_ sfc.Z2Search                 = &hilbert.H2Search{}
+	_ sfc.Z3Search                 = &hilbert.H3Search{}
+)
@@ -0,0 +1,146 @@
+package hilbert
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/scraly/go.common/pkg/sfc/zorder"
+	"github.com/scraly/go.common/pkg/sfc/zorder/normalizer"
+)
+
+const (
+	// H2MaxPrecision is the maximum allowed precision value of H2Sfc
+	H2MaxPrecision = 31 // Inclusive
+	// H3MaxPrecision is the maximum allowed precision value of H3Sfc
+	H3MaxPrecision = 21 // Inclusive
+	// MinPrecision is the minimum allowed precision value
+	MinPrecision = 1 // Inclusive
+)
+
+// H2Sfc struct
+type H2Sfc struct {
+	precision     uint
+	lonNormalizer zorder.Normalizer
+	latNormalizer zorder.Normalizer
+}
+
+// NewH2Sfc creates a 2D Hilbert space filling curve
+func NewH2Sfc(precision uint) (zorder.SpaceFillingCurve, error) {
+	return newH2Sfc(precision)
+}
+
+func newH2Sfc(precision uint) (*H2Sfc, error) {
+	if precision < MinPrecision || precision > H2MaxPrecision {
+		return nil, errors.New("Invalid precision")
+	}
+
+	lonNormalizer, errLonNormalizer := normalizer.NewNormalizer(-180, 180, precision)
+	if errLonNormalizer != nil {
+		return nil, errLonNormalizer
+	}
+
+	latNormalizer, errLatNormalizer := normalizer.NewNormalizer(-90, 90, precision)
+	if errLatNormalizer != nil {
+		return nil, errLatNormalizer
+	}
+
+	return &H2Sfc{
+		precision:     precision,
+		lonNormalizer: lonNormalizer,
+		latNormalizer: latNormalizer,
+	}, nil
+}
+
+// Index function takes longitude x latitude y and outputs the Hilbert index of their normalized values
+func (h2sfc *H2Sfc) Index(x, y float64) (zorder.Z2N, error) {
+	if x < h2sfc.lonNormalizer.GetMin() || x > h2sfc.lonNormalizer.GetMax() {
+		return nil, fmt.Errorf("x value %f out of range (min %f, max %f)", x, h2sfc.lonNormalizer.GetMin(), h2sfc.lonNormalizer.GetMax())
+	}
+
+	if y < h2sfc.latNormalizer.GetMin() || y > h2sfc.latNormalizer.GetMax() {
+		return nil, fmt.Errorf("y value %f out of range (min %f, max %f)", y, h2sfc.latNormalizer.GetMin(), h2sfc.latNormalizer.GetMax())
+	}
+
+	h2 := NewH2()
+	h2.Apply(h2sfc.lonNormalizer.Normalize(x), h2sfc.latNormalizer.Normalize(y))
+
+	return h2, nil
+}
+
+// Invert function takes a Hilbert index and outputs denormalized longitude, latitude
+func (h2sfc *H2Sfc) Invert(h2 zorder.Z2N) (float64, float64) {
+	x, y := h2.UnApply(h2.GetZValue())
+	return h2sfc.lonNormalizer.DeNormalize(x), h2sfc.latNormalizer.DeNormalize(y)
+}
+
+// -----------------------------------------------------------------------------
+
+// H3Sfc struct
+type H3Sfc struct {
+	precision      uint
+	lonNormalizer  zorder.Normalizer
+	latNormalizer  zorder.Normalizer
+	timeNormalizer zorder.Normalizer
+}
+
+// NewH3Sfc creates a 3D Hilbert space filling curve, time is the number of
+// seconds from the beginning of the week as with Z3
+func NewH3Sfc(precision uint) (zorder.SpaceTimeFillingCurve, error) {
+	return newH3Sfc(precision)
+}
+
+func newH3Sfc(precision uint) (*H3Sfc, error) {
+	if precision < MinPrecision || precision > H3MaxPrecision {
+		return nil, errors.New("Invalid precision")
+	}
+
+	lonNormalizer, errLonNormalizer := normalizer.NewNormalizer(-180, 180, precision)
+	if errLonNormalizer != nil {
+		return nil, errLonNormalizer
+	}
+
+	latNormalizer, errLatNormalizer := normalizer.NewNormalizer(-90, 90, precision)
+	if errLatNormalizer != nil {
+		return nil, errLatNormalizer
+	}
+
+	// Seconds from beginning of the week
+	timeNormalizer, errTimeNormalizer := normalizer.NewNormalizer(0, 604800, precision)
+	if errTimeNormalizer != nil {
+		return nil, errTimeNormalizer
+	}
+
+	return &H3Sfc{
+		precision:      precision,
+		lonNormalizer:  lonNormalizer,
+		latNormalizer:  latNormalizer,
+		timeNormalizer: timeNormalizer,
+	}, nil
+}
+
+// Index function takes longitude x latitude y time t and outputs the Hilbert index of their normalized values
+func (h3sfc *H3Sfc) Index(x, y float64, t uint64) (zorder.Z3N, error) {
+	if x < h3sfc.lonNormalizer.GetMin() || x > h3sfc.lonNormalizer.GetMax() {
+		return nil, fmt.Errorf("x value %f out of range (min %f, max %f)", x, h3sfc.lonNormalizer.GetMin(), h3sfc.lonNormalizer.GetMax())
+	}
+
+	if y < h3sfc.latNormalizer.GetMin() || y > h3sfc.latNormalizer.GetMax() {
+		return nil, fmt.Errorf("y value %f out of range (min %f, max %f)", y, h3sfc.latNormalizer.GetMin(), h3sfc.latNormalizer.GetMax())
+	}
+
+	if t < uint64(h3sfc.timeNormalizer.GetMin()) || t > uint64(h3sfc.timeNormalizer.GetMax()) {
+		return nil, fmt.Errorf("t value %d out of range (min %f, max %f)", t, h3sfc.timeNormalizer.GetMin(), h3sfc.timeNormalizer.GetMax())
+	}
+
+	h3 := NewH3()
+	h3.Apply(h3sfc.lonNormalizer.Normalize(x), h3sfc.latNormalizer.Normalize(y), h3sfc.timeNormalizer.Normalize(float64(t)))
+
+	return h3, nil
+}
+
+// Invert function takes a Hilbert index and outputs denormalized longitude, latitude and time values
+func (h3sfc *H3Sfc) Invert(h3 zorder.Z3N) (float64, float64, uint64) {
+	x, y, t := h3.UnApply(h3.GetZValue())
+	return h3sfc.lonNormalizer.DeNormalize(x), h3sfc.latNormalizer.DeNormalize(y), uint64(math.Round(h3sfc.timeNormalizer.DeNormalize(t)))
+}
@@ -0,0 +1,126 @@
+package hilbert
+
+import (
+	"github.com/scraly/go.common/pkg/sfc"
+	"github.com/scraly/go.common/pkg/sfc/utils"
+	"github.com/scraly/go.common/pkg/sfc/zorder"
+)
+
+// cellBox is a box of normalized values, one interval per dimension
+type cellBox struct {
+	min, max coords
+}
+
+// corners returns the index of each corner of the box, as degenerate ranges
+// giving the common prefix of the box
+func (c curve) corners(box cellBox) []zorder.ZRange {
+	zbounds := make([]zorder.ZRange, 0, 1<<uint(c.dimensions))
+	for corner := 0; corner < 1<<uint(c.dimensions); corner++ {
+		values := box.min
+		for i := 0; i < c.dimensions; i++ {
+			if corner&(1<<uint(i)) != 0 {
+				values[i] = box.max[i]
+			}
+		}
+		h := c.encode(values)
+		zbounds = append(zbounds, zorder.ZRange{Min: h, Max: h})
+	}
+	return zbounds
+}
+
+// boxRegion is the region covered by boxes of normalized values
+type boxRegion struct {
+	curve curve
+	boxes []cellBox
+}
+
+func (r *boxRegion) Contains(quadrant zorder.ZRange) bool {
+	min, max := r.curve.cells(quadrant)
+	for _, box := range r.boxes {
+		if contains(box.min, box.max, min) && contains(box.min, box.max, max) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *boxRegion) Overlaps(quadrant zorder.ZRange) bool {
+	min, max := r.curve.cells(quadrant)
+	for _, box := range r.boxes {
+		if overlaps(box.min, box.max, min, max) {
+			return true
+		}
+	}
+	return false
+}
+
+// interval is a range of normalized values
+type interval struct {
+	min, max uint
+}
+
+// shapeRegion relates the cells of a quadrant to a shape, and to time
+// intervals for the 3D curve
+type shapeRegion struct {
+	curve    curve
+	shape    sfc.Shape
+	times    []interval
+	maxIndex uint
+	lonStep  float64
+	latStep  float64
+}
+
+func newShapeRegion(c curve, shape sfc.Shape, precision uint, times []interval) *shapeRegion {
+	bins := uint(1) << precision
+	return &shapeRegion{
+		curve:    c,
+		shape:    shape,
+		times:    times,
+		maxIndex: bins - 1,
+		lonStep:  360 / float64(bins),
+		latStep:  180 / float64(bins),
+	}
+}
+
+func (r *shapeRegion) Contains(quadrant zorder.ZRange) bool {
+	box, min, max, ok := r.cells(quadrant)
+	return ok && r.matchTime(min, max, true) && r.shape.ContainsBox(box)
+}
+
+func (r *shapeRegion) Overlaps(quadrant zorder.ZRange) bool {
+	box, min, max, ok := r.cells(quadrant)
+	return ok && r.matchTime(min, max, false) && r.shape.IntersectsBox(box)
+}
+
+// matchTime returns true if the time cells are contained in, or overlap, a time interval
+func (r *shapeRegion) matchTime(min, max coords, contained bool) bool {
+	if r.times == nil {
+		return true
+	}
+	for _, times := range r.times {
+		if contained && times.min <= min[2] && max[2] <= times.max {
+			return true
+		}
+		if !contained && times.min <= max[2] && min[2] <= times.max {
+			return true
+		}
+	}
+	return false
+}
+
+// cells returns the area and normalized values covered by the quadrant, ok is
+// false when the quadrant is beyond the normalized values
+func (r *shapeRegion) cells(quadrant zorder.ZRange) (sfc.BoundingBox, coords, coords, bool) {
+	min, max := r.curve.cells(quadrant)
+	for i := 0; i < r.curve.dimensions; i++ {
+		if min[i] > r.maxIndex {
+			return sfc.BoundingBox{}, min, max, false
+		}
+		max[i] = utils.MinUint(max[i], r.maxIndex)
+	}
+
+	return sfc.BoundingBox{
+		SouthWest: sfc.Point{Longitude: -180 + float64(min[0])*r.lonStep, Latitude: -90 + float64(min[1])*r.latStep},
+		NorthEast: sfc.Point{Longitude: -180 + float64(max[0]+1)*r.lonStep, Latitude: -90 + float64(max[1]+1)*r.latStep},
+	}, min, max, true
+}
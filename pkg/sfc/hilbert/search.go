@@ -0,0 +1,209 @@
+package hilbert
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/scraly/go.common/pkg/sfc"
+	"github.com/scraly/go.common/pkg/sfc/utils"
+	"github.com/scraly/go.common/pkg/sfc/zorder"
+	zranges "github.com/scraly/go.common/pkg/sfc/zorder/zrange"
+)
+
+// shapeMaxRecurse is deeper than for bounding boxes, so that cells along the
+// shape edges are small enough to be contained or excluded
+const shapeMaxRecurse = 10
+
+// H2Search struct
+type H2Search struct {
+	curve *H2Sfc
+}
+
+// NewH2Search constructs a H2Search and returns it as sfc.Z2Search
+func NewH2Search(precision uint) (sfc.Z2Search, error) {
+	if precision < MinPrecision || precision > H2MaxPrecision {
+		return nil, fmt.Errorf("precision must be in range [%d, %d]", MinPrecision, H2MaxPrecision)
+	}
+
+	h2Sfc, errH2Sfc := newH2Sfc(precision)
+	if errH2Sfc != nil {
+		return nil, errH2Sfc
+	}
+
+	return &H2Search{
+		curve: h2Sfc,
+	}, nil
+}
+
+// GetZ2Ranges returns all Hilbert Index Ranges corresponding to bounding box bbox, a box crossing the antimeridian is searched in two parts
+func (h2search *H2Search) GetZ2Ranges(bbox sfc.BoundingBox) ([]*sfc.IndexRange, error) {
+	boxes, errSplit := bbox.Split()
+	if errSplit != nil {
+		return nil, errSplit
+	}
+
+	h2 := newInternalH2(0)
+
+	result := make([]*sfc.IndexRange, 0)
+	for _, box := range boxes {
+		cells := h2search.cells(box)
+		region := &boxRegion{curve: h2.curve, boxes: []cellBox{cells}}
+
+		ranges, errRanges := zranges.CalculateRegionRanges(h2, h2.curve.corners(cells), region, 64, 0, 7)
+		if errRanges != nil {
+			return nil, errRanges
+		}
+		result = append(result, ranges...)
+	}
+
+	return zranges.MergeRanges(result), nil
+}
+
+// GetZ2RangesForShape returns all Hilbert Index Ranges covering shape, ranges are contained when all their cells are inside the shape
+func (h2search *H2Search) GetZ2RangesForShape(shape sfc.Shape) ([]*sfc.IndexRange, error) {
+	boxes, errSplit := shape.Bounds().Split()
+	if errSplit != nil {
+		return nil, errSplit
+	}
+
+	h2 := newInternalH2(0)
+	region := newShapeRegion(h2.curve, shape, h2search.curve.precision, nil)
+
+	result := make([]*sfc.IndexRange, 0)
+	for _, box := range boxes {
+		ranges, errRanges := zranges.CalculateRegionRanges(h2, h2.curve.corners(h2search.cells(box)), region, 64, 0, shapeMaxRecurse)
+		if errRanges != nil {
+			return nil, errRanges
+		}
+		result = append(result, ranges...)
+	}
+
+	return zranges.MergeRanges(result), nil
+}
+
+// GetSpaceFillingCurve returns the 2D Hilbert space filling curve
+func (h2search *H2Search) GetSpaceFillingCurve() zorder.SpaceFillingCurve {
+	return h2search.curve
+}
+
+// cells returns the normalized values of bbox
+func (h2search *H2Search) cells(bbox sfc.BoundingBox) cellBox {
+	lon, lat := h2search.curve.lonNormalizer, h2search.curve.latNormalizer
+	return cellBox{
+		min: coords{lon.Normalize(bbox.SouthWest.Longitude), lat.Normalize(bbox.SouthWest.Latitude)},
+		max: coords{lon.Normalize(bbox.NorthEast.Longitude), lat.Normalize(bbox.NorthEast.Latitude)},
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// H3Search struct
+type H3Search struct {
+	curve *H3Sfc
+}
+
+// NewH3Search constructs a H3Search and returns it as sfc.Z3Search
+func NewH3Search() (sfc.Z3Search, error) {
+	h3Sfc, errH3Sfc := newH3Sfc(H3MaxPrecision)
+	if errH3Sfc != nil {
+		return nil, errH3Sfc
+	}
+
+	return &H3Search{
+		curve: h3Sfc,
+	}, nil
+}
+
+// GetZ3Ranges returns all Hilbert Index Ranges corresponding to bounding box bbox and time frame (dateMin, dateMax), a box crossing the antimeridian is searched in two parts
+func (h3search *H3Search) GetZ3Ranges(bbox sfc.BoundingBox, dateMin, dateMax time.Time) ([]*sfc.IndexRange, []*utils.WeekTimeRange, error) {
+	weekTimeRanges, errWeekTimeRanges := utils.GetWeekTimeRangeFromDateRange(dateMin, dateMax)
+	if errWeekTimeRanges != nil {
+		return nil, nil, errWeekTimeRanges
+	}
+
+	boxes, errSplit := bbox.Split()
+	if errSplit != nil {
+		return nil, nil, errSplit
+	}
+
+	h3 := newInternalH3(0)
+
+	result := make([]*sfc.IndexRange, 0)
+	for _, box := range boxes {
+		cells, zbounds := h3search.cells(h3.curve, box, weekTimeRanges)
+		region := &boxRegion{curve: h3.curve, boxes: cells}
+
+		ranges, errRanges := zranges.CalculateRegionRanges(h3, zbounds, region, 64, 0, 7)
+		if errRanges != nil {
+			return nil, nil, errRanges
+		}
+		result = append(result, ranges...)
+	}
+
+	return zranges.MergeRanges(result), weekTimeRanges, nil
+}
+
+// GetZ3RangesForShape returns all Hilbert Index Ranges covering shape and time frame (dateMin, dateMax), ranges are contained when all their cells are inside both
+func (h3search *H3Search) GetZ3RangesForShape(shape sfc.Shape, dateMin, dateMax time.Time) ([]*sfc.IndexRange, []*utils.WeekTimeRange, error) {
+	weekTimeRanges, errWeekTimeRanges := utils.GetWeekTimeRangeFromDateRange(dateMin, dateMax)
+	if errWeekTimeRanges != nil {
+		return nil, nil, errWeekTimeRanges
+	}
+
+	boxes, errSplit := shape.Bounds().Split()
+	if errSplit != nil {
+		return nil, nil, errSplit
+	}
+
+	h3 := newInternalH3(0)
+
+	times := make([]interval, 0, len(weekTimeRanges))
+	for _, weekTimeRange := range weekTimeRanges {
+		min, max := h3search.times(weekTimeRange)
+		times = append(times, interval{min: min, max: max})
+	}
+	region := newShapeRegion(h3.curve, shape, h3search.curve.precision, times)
+
+	result := make([]*sfc.IndexRange, 0)
+	for _, box := range boxes {
+		_, zbounds := h3search.cells(h3.curve, box, weekTimeRanges)
+
+		ranges, errRanges := zranges.CalculateRegionRanges(h3, zbounds, region, 64, 0, shapeMaxRecurse)
+		if errRanges != nil {
+			return nil, nil, errRanges
+		}
+		result = append(result, ranges...)
+	}
+
+	return zranges.MergeRanges(result), weekTimeRanges, nil
+}
+
+// GetSpaceTimeFillingCurve returns the 3D Hilbert space filling curve
+func (h3search *H3Search) GetSpaceTimeFillingCurve() zorder.SpaceTimeFillingCurve {
+	return h3search.curve
+}
+
+// cells returns the normalized values of bbox for each week time range, and their corners
+func (h3search *H3Search) cells(c curve, bbox sfc.BoundingBox, weekTimeRanges []*utils.WeekTimeRange) ([]cellBox, []zorder.ZRange) {
+	lon, lat := h3search.curve.lonNormalizer, h3search.curve.latNormalizer
+
+	cells := make([]cellBox, 0, len(weekTimeRanges))
+	zbounds := make([]zorder.ZRange, 0)
+	for _, weekTimeRange := range weekTimeRanges {
+		tMin, tMax := h3search.times(weekTimeRange)
+		box := cellBox{
+			min: coords{lon.Normalize(bbox.SouthWest.Longitude), lat.Normalize(bbox.SouthWest.Latitude), tMin},
+			max: coords{lon.Normalize(bbox.NorthEast.Longitude), lat.Normalize(bbox.NorthEast.Latitude), tMax},
+		}
+		cells = append(cells, box)
+		zbounds = append(zbounds, c.corners(box)...)
+	}
+	return cells, zbounds
+}
+
+// times returns the normalized values of the week time range
+func (h3search *H3Search) times(weekTimeRange *utils.WeekTimeRange) (uint, uint) {
+	t := h3search.curve.timeNormalizer
+	return t.Normalize(math.Floor(weekTimeRange.MinWeekDate.Seconds)), t.Normalize(math.Floor(weekTimeRange.MaxWeekDate.Seconds))
+}
@@ -0,0 +1,536 @@
+package sfc
+
+import (
+	"errors"
+	"math"
+)
+
+// EarthRadius is the mean earth radius in meters
+const EarthRadius = 6371008.8
+
+// Shape is a query area of a Z2 or Z3 search. Cells reported as contained
+// must be entirely inside the shape, cells reported as not intersecting must
+// be entirely outside of it.
+type Shape interface {
+	// Bounds returns the bounding box of the shape, it may cross the antimeridian
+	Bounds() BoundingBox
+	// ContainsBox returns true if the box is entirely inside the shape
+	ContainsBox(box BoundingBox) bool
+	// IntersectsBox returns true if the box may overlap the shape
+	IntersectsBox(box BoundingBox) bool
+}
+
+var (
+	// ErrInvalidPolygon is raised when a ring has less than 3 points
+	ErrInvalidPolygon = errors.New("Polygon rings must have at least 3 points")
+	// ErrInvalidPolyline is raised when a polyline has no point
+	ErrInvalidPolyline = errors.New("Polyline must have at least 1 point")
+	// ErrInvalidRadius is raised when a radius or buffer is not positive
+	ErrInvalidRadius = errors.New("Radius must be positive")
+)
+
+// -----------------------------------------------------------------------------
+
+// Polygon is an area with straight edges in longitude/latitude space, holes
+// are excluded from it. Rings may be open or closed.
+type Polygon struct {
+	Exterior []Point
+	Holes    [][]Point
+}
+
+// NewPolygon validates the rings and returns the polygon as a Shape
+func NewPolygon(exterior []Point, holes ...[]Point) (Shape, error) {
+	if len(exterior) < 3 {
+		return nil, ErrInvalidPolygon
+	}
+	for _, hole := range holes {
+		if len(hole) < 3 {
+			return nil, ErrInvalidPolygon
+		}
+	}
+
+	return &Polygon{
+		Exterior: exterior,
+		Holes:    holes,
+	}, nil
+}
+
+// Bounds returns the bounding box of the exterior ring
+func (p *Polygon) Bounds() BoundingBox {
+	return pointsBounds(p.Exterior)
+}
+
+// ContainsBox returns true if the box is inside the exterior ring and does not touch any hole
+func (p *Polygon) ContainsBox(box BoundingBox) bool {
+	if !ringContainsBox(p.Exterior, box) {
+		return false
+	}
+	for _, hole := range p.Holes {
+		if ringIntersectsBox(hole, box) {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectsBox returns true if the box overlaps the exterior ring and is not inside a hole
+func (p *Polygon) IntersectsBox(box BoundingBox) bool {
+	if !ringIntersectsBox(p.Exterior, box) {
+		return false
+	}
+	for _, hole := range p.Holes {
+		if ringContainsBox(hole, box) {
+			return false
+		}
+	}
+	return true
+}
+
+// -----------------------------------------------------------------------------
+
+// Circle is the area within Radius meters (great circle distance) of Center
+type Circle struct {
+	Center Point
+	Radius float64
+}
+
+// NewCircle returns the circle as a Shape
+func NewCircle(center Point, radius float64) (Shape, error) {
+	if radius <= 0 {
+		return nil, ErrInvalidRadius
+	}
+
+	return &Circle{
+		Center: center,
+		Radius: radius,
+	}, nil
+}
+
+// Bounds returns the bounding box of the circle, the whole longitude range
+// when it covers a pole
+func (c *Circle) Bounds() BoundingBox {
+	return distanceBounds(pointsBounds([]Point{c.Center}), c.Radius)
+}
+
+// ContainsBox returns true if the farthest point of the box is within the radius,
+// it is a corner or on the meridian opposite to the center
+func (c *Circle) ContainsBox(box BoundingBox) bool {
+	points := corners(box)
+	if opposite := c.Center.Longitude - math.Copysign(180, c.Center.Longitude); opposite >= box.SouthWest.Longitude && opposite <= box.NorthEast.Longitude {
+		points = append(points,
+			Point{Longitude: opposite, Latitude: box.SouthWest.Latitude},
+			Point{Longitude: opposite, Latitude: box.NorthEast.Latitude},
+		)
+	}
+
+	for _, p := range points {
+		if Distance(c.Center, p) > c.Radius {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectsBox returns true if the box nearest point is within the radius
+func (c *Circle) IntersectsBox(box BoundingBox) bool {
+	return distanceToBox(c.Center, box) <= c.Radius
+}
+
+// -----------------------------------------------------------------------------
+
+// Polyline is the area within Buffer meters of a path, such as a road corridor.
+// Segments are great circle arcs. Intersections are checked in a local
+// equirectangular projection, accurate for segments and buffers up to a few
+// kilometers, containment with great circle distances.
+type Polyline struct {
+	Points []Point
+	Buffer float64
+}
+
+// NewPolyline returns the buffered polyline as a Shape
+func NewPolyline(points []Point, buffer float64) (Shape, error) {
+	if len(points) == 0 {
+		return nil, ErrInvalidPolyline
+	}
+	if buffer <= 0 {
+		return nil, ErrInvalidRadius
+	}
+
+	return &Polyline{
+		Points: points,
+		Buffer: buffer,
+	}, nil
+}
+
+// Bounds returns the bounding box of the points grown by the buffer
+func (l *Polyline) Bounds() BoundingBox {
+	return distanceBounds(pathBounds(l.Points), l.Buffer)
+}
+
+// ContainsBox returns true if the box is within the buffer of a single segment
+func (l *Polyline) ContainsBox(box BoundingBox) bool {
+	if boxSpan(box) > polylineMaxSpan {
+		return false
+	}
+
+	// The buffer of a segment is convex, so is the great circle hull of the
+	// box corners. Box edges along parallels bulge out of the hull, the
+	// buffer is shrunk accordingly.
+	buffer := l.Buffer - parallelBulge(box)
+	boxCorners := corners(box)
+	for _, segment := range l.segments() {
+		inside := true
+		for _, corner := range boxCorners {
+			if segmentDistance(corner, segment[0], segment[1]) > buffer {
+				inside = false
+				break
+			}
+		}
+		if inside {
+			return true
+		}
+	}
+	return false
+}
+
+// IntersectsBox returns true if a segment is within the buffer of the box
+func (l *Polyline) IntersectsBox(box BoundingBox) bool {
+	if !boxesIntersect(l.Bounds(), box) {
+		return false
+	}
+	// Projection is too distorted for large boxes
+	if boxSpan(box) > polylineMaxSpan {
+		return true
+	}
+
+	proj := newProjection(boxCenter(box))
+	sw, ne := proj.project(box.SouthWest), proj.project(box.NorthEast)
+	for _, segment := range l.segments() {
+		a, b := proj.project(segment[0]), proj.project(segment[1])
+		if segmentBoxDistance(a, b, sw, ne) <= l.Buffer {
+			return true
+		}
+	}
+	return false
+}
+
+// polylineMaxSpan is the largest box, in degrees, checked in projection
+const polylineMaxSpan = 1.0
+
+func (l *Polyline) segments() [][2]Point {
+	if len(l.Points) == 1 {
+		return [][2]Point{{l.Points[0], l.Points[0]}}
+	}
+	segments := make([][2]Point, 0, len(l.Points)-1)
+	for i := 1; i < len(l.Points); i++ {
+		segments = append(segments, [2]Point{l.Points[i-1], l.Points[i]})
+	}
+	return segments
+}
+
+// -----------------------------------------------------------------------------
+
+// Distance returns the great circle distance in meters between two points
+func Distance(p1, p2 Point) float64 {
+	lat1, lat2 := toRadians(p1.Latitude), toRadians(p2.Latitude)
+	dLat := lat2 - lat1
+	dLon := toRadians(p2.Longitude - p1.Longitude)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// segmentDistance returns the great circle distance in meters from p to the
+// great circle arc between a and b
+func segmentDistance(p, a, b Point) float64 {
+	dap := Distance(a, p)
+	dab := Distance(a, b)
+	if dab == 0 {
+		return dap
+	}
+
+	// Cross-track and along-track angular distances
+	d13 := dap / EarthRadius
+	angle := bearing(a, p) - bearing(a, b)
+	if math.Cos(angle) <= 0 {
+		// p is behind a
+		return dap
+	}
+	xt := math.Asin(math.Max(-1, math.Min(1, math.Sin(d13)*math.Sin(angle))))
+	at := math.Acos(math.Max(-1, math.Min(1, math.Cos(d13)/math.Cos(xt))))
+	if at*EarthRadius > dab {
+		return Distance(b, p)
+	}
+	return math.Abs(xt) * EarthRadius
+}
+
+// bearing returns the initial great circle bearing from p1 to p2 in radians
+func bearing(p1, p2 Point) float64 {
+	lat1, lat2 := toRadians(p1.Latitude), toRadians(p2.Latitude)
+	dLon := toRadians(p2.Longitude - p1.Longitude)
+	return math.Atan2(math.Sin(dLon)*math.Cos(lat2), math.Cos(lat1)*math.Sin(lat2)-math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon))
+}
+
+// parallelBulge returns the largest distance in meters between a box edge
+// along a parallel and the great circle arc between its ends, reached at the
+// middle of the edge
+func parallelBulge(box BoundingBox) float64 {
+	halfLon := toRadians(box.NorthEast.Longitude-box.SouthWest.Longitude) / 2
+	bulge := 0.0
+	for _, lat := range []float64{box.SouthWest.Latitude, box.NorthEast.Latitude} {
+		phi := toRadians(lat)
+		arc := math.Atan(math.Tan(phi) / math.Cos(halfLon))
+		bulge = math.Max(bulge, math.Abs(arc-phi)*EarthRadius)
+	}
+	return bulge
+}
+
+// distanceToBox returns the great circle distance from p to the nearest point of the box
+func distanceToBox(p Point, box BoundingBox) float64 {
+	lat := math.Max(box.SouthWest.Latitude, math.Min(box.NorthEast.Latitude, p.Latitude))
+
+	// Nearest point is on the meridian of p when the box spans its longitude
+	if p.Longitude >= box.SouthWest.Longitude && p.Longitude <= box.NorthEast.Longitude {
+		return Distance(p, Point{Longitude: p.Longitude, Latitude: lat})
+	}
+
+	// Otherwise on a vertical edge, distance along a meridian is minimal at the
+	// foot of the great circle perpendicular, clamped to the edge
+	min := math.Inf(1)
+	for _, lon := range []float64{box.SouthWest.Longitude, box.NorthEast.Longitude} {
+		dLon := toRadians(lon - p.Longitude)
+		foot := toDegrees(math.Atan2(math.Sin(toRadians(p.Latitude)), math.Cos(toRadians(p.Latitude))*math.Cos(dLon)))
+		foot = math.Max(box.SouthWest.Latitude, math.Min(box.NorthEast.Latitude, foot))
+		min = math.Min(min, Distance(p, Point{Longitude: lon, Latitude: foot}))
+	}
+	return min
+}
+
+// distanceBounds grows the box by distance meters, to all longitudes when it
+// reaches a pole
+func distanceBounds(box BoundingBox, distance float64) BoundingBox {
+	dLat := toDegrees(distance / EarthRadius)
+
+	south := box.SouthWest.Latitude - dLat
+	north := box.NorthEast.Latitude + dLat
+	world := BoundingBox{
+		SouthWest: Point{Longitude: -180, Latitude: math.Max(-90, south)},
+		NorthEast: Point{Longitude: 180, Latitude: math.Min(90, north)},
+	}
+	if south <= -90 || north >= 90 {
+		return world
+	}
+
+	// Longitude degrees shrink with the highest latitude reached
+	maxLat := math.Max(math.Abs(south), math.Abs(north))
+	dLon := dLat / math.Cos(toRadians(maxLat))
+
+	west := box.SouthWest.Longitude - dLon
+	east := box.NorthEast.Longitude + dLon
+	if east-west >= 360 {
+		return world
+	}
+
+	return BoundingBox{
+		SouthWest: Point{Longitude: wrapLongitude(west), Latitude: south},
+		NorthEast: Point{Longitude: wrapLongitude(east), Latitude: north},
+	}
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func toDegrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// -----------------------------------------------------------------------------
+
+func pointsBounds(points []Point) BoundingBox {
+	box := BoundingBox{SouthWest: points[0], NorthEast: points[0]}
+	for _, p := range points[1:] {
+		box.SouthWest.Longitude = math.Min(box.SouthWest.Longitude, p.Longitude)
+		box.SouthWest.Latitude = math.Min(box.SouthWest.Latitude, p.Latitude)
+		box.NorthEast.Longitude = math.Max(box.NorthEast.Longitude, p.Longitude)
+		box.NorthEast.Latitude = math.Max(box.NorthEast.Latitude, p.Latitude)
+	}
+	return box
+}
+
+// pathBounds returns the bounds of a path whose segments take the shortest way
+// around the antimeridian, longitudes may be beyond [-180, 180]
+func pathBounds(points []Point) BoundingBox {
+	unwrapped := make([]Point, len(points))
+	unwrapped[0] = points[0]
+	for i := 1; i < len(points); i++ {
+		unwrapped[i] = Point{
+			Longitude: unwrapped[i-1].Longitude + wrapLongitude(points[i].Longitude-points[i-1].Longitude),
+			Latitude:  points[i].Latitude,
+		}
+	}
+	return pointsBounds(unwrapped)
+}
+
+func corners(box BoundingBox) []Point {
+	return []Point{
+		box.SouthWest,
+		{Longitude: box.NorthEast.Longitude, Latitude: box.SouthWest.Latitude},
+		box.NorthEast,
+		{Longitude: box.SouthWest.Longitude, Latitude: box.NorthEast.Latitude},
+	}
+}
+
+func boxCenter(box BoundingBox) Point {
+	return Point{
+		Longitude: (box.SouthWest.Longitude + box.NorthEast.Longitude) / 2,
+		Latitude:  (box.SouthWest.Latitude + box.NorthEast.Latitude) / 2,
+	}
+}
+
+func boxSpan(box BoundingBox) float64 {
+	return math.Max(box.NorthEast.Longitude-box.SouthWest.Longitude, box.NorthEast.Latitude-box.SouthWest.Latitude)
+}
+
+// boxesIntersect returns true if the boxes overlap, b1 may cross the antimeridian
+func boxesIntersect(b1, b2 BoundingBox) bool {
+	if b1.CrossesAntimeridian() {
+		west, east := b1, b1
+		west.NorthEast.Longitude = 180
+		east.SouthWest.Longitude = -180
+		return boxesIntersect(west, b2) || boxesIntersect(east, b2)
+	}
+	return b1.SouthWest.Longitude <= b2.NorthEast.Longitude && b2.SouthWest.Longitude <= b1.NorthEast.Longitude &&
+		b1.SouthWest.Latitude <= b2.NorthEast.Latitude && b2.SouthWest.Latitude <= b1.NorthEast.Latitude
+}
+
+func boxContainsPoint(box BoundingBox, p Point) bool {
+	return p.Longitude >= box.SouthWest.Longitude && p.Longitude <= box.NorthEast.Longitude &&
+		p.Latitude >= box.SouthWest.Latitude && p.Latitude <= box.NorthEast.Latitude
+}
+
+// ringContainsPoint is the even-odd rule
+func ringContainsPoint(ring []Point, p Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Latitude > p.Latitude) != (b.Latitude > p.Latitude) &&
+			p.Longitude < (b.Longitude-a.Longitude)*(p.Latitude-a.Latitude)/(b.Latitude-a.Latitude)+a.Longitude {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func ringCrossesBox(ring []Point, box BoundingBox) bool {
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		if segmentIntersectsBox(ring[j], ring[i], box) {
+			return true
+		}
+	}
+	return false
+}
+
+// ringContainsBox returns true if no edge touches the box and a corner is inside the ring
+func ringContainsBox(ring []Point, box BoundingBox) bool {
+	return !ringCrossesBox(ring, box) && ringContainsPoint(ring, box.SouthWest)
+}
+
+// ringIntersectsBox returns true if an edge touches the box or the box is inside the ring
+func ringIntersectsBox(ring []Point, box BoundingBox) bool {
+	return ringCrossesBox(ring, box) || ringContainsPoint(ring, box.SouthWest)
+}
+
+// segmentIntersectsBox clips the segment with the closed box (Liang-Barsky)
+func segmentIntersectsBox(a, b Point, box BoundingBox) bool {
+	if boxContainsPoint(box, a) || boxContainsPoint(box, b) {
+		return true
+	}
+
+	t0, t1 := 0.0, 1.0
+	dx, dy := b.Longitude-a.Longitude, b.Latitude-a.Latitude
+	clip := func(p, q float64) bool {
+		if p == 0 {
+			return q >= 0
+		}
+		r := q / p
+		if p < 0 {
+			if r > t1 {
+				return false
+			}
+			t0 = math.Max(t0, r)
+		} else {
+			if r < t0 {
+				return false
+			}
+			t1 = math.Min(t1, r)
+		}
+		return true
+	}
+
+	return clip(-dx, a.Longitude-box.SouthWest.Longitude) &&
+		clip(dx, box.NorthEast.Longitude-a.Longitude) &&
+		clip(-dy, a.Latitude-box.SouthWest.Latitude) &&
+		clip(dy, box.NorthEast.Latitude-a.Latitude) &&
+		t0 <= t1
+}
+
+// -----------------------------------------------------------------------------
+
+// vec is a point in a local projection, in meters
+type vec struct {
+	x, y float64
+}
+
+// projection is a local equirectangular projection
+type projection struct {
+	origin Point
+	scale  float64
+}
+
+func newProjection(origin Point) projection {
+	return projection{
+		origin: origin,
+		scale:  math.Cos(toRadians(origin.Latitude)),
+	}
+}
+
+func (p projection) project(pt Point) vec {
+	return vec{
+		x: EarthRadius * toRadians(wrapLongitude(pt.Longitude-p.origin.Longitude)) * p.scale,
+		y: EarthRadius * toRadians(pt.Latitude-p.origin.Latitude),
+	}
+}
+
+func pointSegmentDistance(p, a, b vec) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, ((p.x-a.x)*dx+(p.y-a.y)*dy)/length))
+	}
+	return math.Hypot(p.x-(a.x+t*dx), p.y-(a.y+t*dy))
+}
+
+// segmentBoxDistance returns the distance between a segment and a box given by its corners
+func segmentBoxDistance(a, b, sw, ne vec) float64 {
+	box := BoundingBox{
+		SouthWest: Point{Longitude: sw.x, Latitude: sw.y},
+		NorthEast: Point{Longitude: ne.x, Latitude: ne.y},
+	}
+	if segmentIntersectsBox(Point{Longitude: a.x, Latitude: a.y}, Point{Longitude: b.x, Latitude: b.y}, box) {
+		return 0
+	}
+
+	// Otherwise the nearest points include a segment end or a box corner
+	min := math.Min(pointBoxDistance(a, sw, ne), pointBoxDistance(b, sw, ne))
+	for _, c := range []vec{sw, {ne.x, sw.y}, ne, {sw.x, ne.y}} {
+		min = math.Min(min, pointSegmentDistance(c, a, b))
+	}
+	return min
+}
+
+func pointBoxDistance(p, sw, ne vec) float64 {
+	dx := math.Max(0, math.Max(sw.x-p.x, p.x-ne.x))
+	dy := math.Max(0, math.Max(sw.y-p.y, p.y-ne.y))
+	return math.Hypot(dx, dy)
+}
@@ -0,0 +1,120 @@
+package sfc_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/scraly/go.common/pkg/sfc"
+)
+
+func box(west, south, east, north float64) sfc.BoundingBox {
+	return sfc.BoundingBox{
+		SouthWest: sfc.Point{Longitude: west, Latitude: south},
+		NorthEast: sfc.Point{Longitude: east, Latitude: north},
+	}
+}
+
+func TestDistance(t *testing.T) {
+	RegisterTestingT(t)
+
+	toulouse := sfc.Point{Longitude: 1.444, Latitude: 43.604}
+	paris := sfc.Point{Longitude: 2.352, Latitude: 48.857}
+
+	Expect(sfc.Distance(toulouse, paris)).To(BeNumerically("~", 588000, 2000))
+	Expect(sfc.Distance(toulouse, toulouse)).To(BeZero())
+}
+
+func TestPolygon(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, err := sfc.NewPolygon([]sfc.Point{{Longitude: 0, Latitude: 0}, {Longitude: 1, Latitude: 1}})
+	Expect(err).To(Equal(sfc.ErrInvalidPolygon))
+
+	polygon, err := sfc.NewPolygon(
+		[]sfc.Point{{Longitude: 0, Latitude: 0}, {Longitude: 10, Latitude: 0}, {Longitude: 10, Latitude: 10}, {Longitude: 0, Latitude: 10}},
+		[]sfc.Point{{Longitude: 4, Latitude: 4}, {Longitude: 6, Latitude: 4}, {Longitude: 6, Latitude: 6}, {Longitude: 4, Latitude: 6}},
+	)
+	Expect(err).To(BeNil())
+	Expect(polygon.Bounds()).To(Equal(box(0, 0, 10, 10)))
+
+	// Inside, away from the hole
+	Expect(polygon.ContainsBox(box(1, 1, 2, 2))).To(BeTrue())
+	Expect(polygon.IntersectsBox(box(1, 1, 2, 2))).To(BeTrue())
+
+	// Across the exterior ring
+	Expect(polygon.ContainsBox(box(9, 9, 11, 11))).To(BeFalse())
+	Expect(polygon.IntersectsBox(box(9, 9, 11, 11))).To(BeTrue())
+
+	// Across the hole
+	Expect(polygon.ContainsBox(box(3, 3, 5, 5))).To(BeFalse())
+	Expect(polygon.IntersectsBox(box(3, 3, 5, 5))).To(BeTrue())
+
+	// Inside the hole
+	Expect(polygon.IntersectsBox(box(4.5, 4.5, 5.5, 5.5))).To(BeFalse())
+
+	// Outside
+	Expect(polygon.IntersectsBox(box(11, 11, 12, 12))).To(BeFalse())
+
+	// Around the whole polygon
+	Expect(polygon.IntersectsBox(box(-1, -1, 11, 11))).To(BeTrue())
+}
+
+func TestCircle(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, err := sfc.NewCircle(sfc.Point{}, 0)
+	Expect(err).To(Equal(sfc.ErrInvalidRadius))
+
+	// 1 degree of latitude is about 111km
+	circle, err := sfc.NewCircle(sfc.Point{Longitude: 0, Latitude: 45}, 111200)
+	Expect(err).To(BeNil())
+
+	bounds := circle.Bounds()
+	Expect(bounds.SouthWest.Latitude).To(BeNumerically("~", 44, 0.01))
+	Expect(bounds.NorthEast.Latitude).To(BeNumerically("~", 46, 0.01))
+	Expect(bounds.SouthWest.Longitude).To(BeNumerically("<", -1.4))
+
+	Expect(circle.ContainsBox(box(-0.1, 44.9, 0.1, 45.1))).To(BeTrue())
+	Expect(circle.ContainsBox(box(-0.5, 44.5, 1, 46))).To(BeFalse())
+	Expect(circle.IntersectsBox(box(-0.5, 44.5, 1, 46))).To(BeTrue())
+
+	// Nearest point of the box is inside an edge, not a corner
+	Expect(circle.IntersectsBox(box(1.3, 44, 3, 46))).To(BeTrue())
+	Expect(circle.IntersectsBox(box(1.5, 44, 3, 46))).To(BeFalse())
+
+	// Reaching a pole covers all longitudes
+	polar, _ := sfc.NewCircle(sfc.Point{Longitude: 0, Latitude: 89.5}, 111200)
+	Expect(polar.Bounds()).To(Equal(box(-180, polar.Bounds().SouthWest.Latitude, 180, 90)))
+	Expect(polar.IntersectsBox(box(170, 89, 180, 90))).To(BeTrue())
+}
+
+func TestPolyline(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, err := sfc.NewPolyline(nil, 100)
+	Expect(err).To(Equal(sfc.ErrInvalidPolyline))
+	_, err = sfc.NewPolyline([]sfc.Point{{}}, -1)
+	Expect(err).To(Equal(sfc.ErrInvalidRadius))
+
+	// Along the equator, 0.001 degree is about 111m
+	polyline, err := sfc.NewPolyline([]sfc.Point{{Longitude: 0, Latitude: 0}, {Longitude: 0.1, Latitude: 0}, {Longitude: 0.1, Latitude: 0.1}}, 500)
+	Expect(err).To(BeNil())
+
+	Expect(polyline.ContainsBox(box(0.01, -0.001, 0.02, 0.001))).To(BeTrue())
+	Expect(polyline.ContainsBox(box(0.01, -0.001, 0.02, 0.01))).To(BeFalse())
+	Expect(polyline.IntersectsBox(box(0.01, 0.004, 0.02, 0.01))).To(BeTrue())
+	Expect(polyline.IntersectsBox(box(0.01, 0.005, 0.02, 0.01))).To(BeFalse())
+
+	// Corner of the path
+	Expect(polyline.ContainsBox(box(0.099, 0.099, 0.101, 0.101))).To(BeTrue())
+	Expect(polyline.IntersectsBox(box(0.05, 0.05, 0.06, 0.06))).To(BeFalse())
+
+	// Southern corners of a large box are about 1% farther than projected at
+	// the box center latitude
+	meridian, err := sfc.NewPolyline([]sfc.Point{{Longitude: 0, Latitude: 59}, {Longitude: 0, Latitude: 61}}, 50000)
+	Expect(err).To(BeNil())
+	Expect(meridian.ContainsBox(box(0.1, 59.6, 0.8949, 60.4))).To(BeFalse())
+	Expect(meridian.ContainsBox(box(0.1, 59.6, 0.85, 60.4))).To(BeTrue())
+	Expect(meridian.IntersectsBox(box(0.1, 59.6, 0.8949, 60.4))).To(BeTrue())
+}
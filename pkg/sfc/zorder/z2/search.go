@@ -4,13 +4,19 @@ import (
 	"fmt"
 
 	"github.com/scraly/go.common/pkg/sfc"
+	"github.com/scraly/go.common/pkg/sfc/utils"
 	"github.com/scraly/go.common/pkg/sfc/zorder"
 	zranges "github.com/scraly/go.common/pkg/sfc/zorder/zrange"
 )
 
+// shapeMaxRecurse is deeper than for bounding boxes, so that cells along the
+// shape edges are small enough to be contained or excluded
+const shapeMaxRecurse = 10
+
 // Z2Search struct
 type Z2Search struct { // nolint: golint
-	curve zorder.SpaceFillingCurve
+	curve     zorder.SpaceFillingCurve
+	precision uint
 }
 
 // NewSearch constructs a Z2Search and returns it as sfc.Z2Search
@@ -25,15 +31,65 @@ func NewSearch(precision uint) (sfc.Z2Search, error) {
 	}
 
 	return &Z2Search{
-		curve: z2Sfc,
+		curve:     z2Sfc,
+		precision: precision,
 	}, nil
 }
 
-// GetZ2Ranges returns all Z2 Index Ranges corresponding to bounding box bbox
+// GetZ2Ranges returns all Z2 Index Ranges corresponding to bounding box bbox, a box crossing the antimeridian is searched in two parts
 func (z2search *Z2Search) GetZ2Ranges(bbox sfc.BoundingBox) ([]*sfc.IndexRange, error) {
-	z2Sfc := z2search.curve
+	boxes, errSplit := bbox.Split()
+	if errSplit != nil {
+		return nil, errSplit
+	}
+
+	result := make([]*sfc.IndexRange, 0)
+	for _, box := range boxes {
+		zrange, errZRange := z2search.zrange(box)
+		if errZRange != nil {
+			return nil, errZRange
+		}
+
+		ranges, errRanges := zranges.CalculateRanges(NewZ2(), []zorder.ZRange{*zrange}, 64, 0, 7)
+		if errRanges != nil {
+			return nil, errRanges
+		}
+		result = append(result, ranges...)
+	}
+
+	return zranges.MergeRanges(result), nil
+
+}
+
+// GetZ2RangesForShape returns all Z2 Index Ranges covering shape, ranges are contained when all their cells are inside the shape
+func (z2search *Z2Search) GetZ2RangesForShape(shape sfc.Shape) ([]*sfc.IndexRange, error) {
+	boxes, errSplit := shape.Bounds().Split()
+	if errSplit != nil {
+		return nil, errSplit
+	}
+
+	region := newShapeRegion(shape, z2search.precision)
+
+	result := make([]*sfc.IndexRange, 0)
+	for _, box := range boxes {
+		zrange, errZRange := z2search.zrange(box)
+		if errZRange != nil {
+			return nil, errZRange
+		}
+
+		ranges, errRanges := zranges.CalculateRegionRanges(NewZ2(), []zorder.ZRange{*zrange}, region, 64, 0, shapeMaxRecurse)
+		if errRanges != nil {
+			return nil, errRanges
+		}
+		result = append(result, ranges...)
+	}
+
+	return zranges.MergeRanges(result), nil
+}
 
-	zbounds := make([]zorder.ZRange, 0)
+// zrange returns the Z2 range between the corners of bbox
+func (z2search *Z2Search) zrange(bbox sfc.BoundingBox) (*zorder.ZRange, error) {
+	z2Sfc := z2search.curve
 
 	z2nMin, errZ2nMin := z2Sfc.Index(bbox.SouthWest.Longitude, bbox.SouthWest.Latitude)
 	if errZ2nMin != nil {
@@ -45,22 +101,56 @@ func (z2search *Z2Search) GetZ2Ranges(bbox sfc.BoundingBox) ([]*sfc.IndexRange,
 		return nil, errZ2nMax
 	}
 
-	zrange, errZRange := zorder.NewZRange(z2nMin.GetZValue(), z2nMax.GetZValue())
-	if errZRange != nil {
-		return nil, errZRange
-	}
-	zbounds = append(zbounds, *zrange)
+	return zorder.NewZRange(z2nMin.GetZValue(), z2nMax.GetZValue())
+}
 
-	result, errRanges := zranges.CalculateRanges(NewZ2(), zbounds, 64, 0, 7)
-	if errRanges != nil {
-		return nil, errRanges
+// GetSpaceFillingCurve returns the z2 spce filling curve
+func (z2search *Z2Search) GetSpaceFillingCurve() zorder.SpaceFillingCurve {
+	return z2search.curve
+}
+
+// shapeRegion relates the cells of a Z2 quadrant to a shape
+type shapeRegion struct {
+	shape    sfc.Shape
+	z2       zorder.Z2N
+	maxIndex uint
+	lonStep  float64
+	latStep  float64
+}
+
+func newShapeRegion(shape sfc.Shape, precision uint) zranges.Region {
+	bins := uint(1) << precision
+	return &shapeRegion{
+		shape:    shape,
+		z2:       NewZ2(),
+		maxIndex: bins - 1,
+		lonStep:  360 / float64(bins),
+		latStep:  180 / float64(bins),
 	}
+}
 
-	return result, nil
+func (r *shapeRegion) Contains(quadrant zorder.ZRange) bool {
+	box, ok := r.box(quadrant)
+	return ok && r.shape.ContainsBox(box)
+}
 
+func (r *shapeRegion) Overlaps(quadrant zorder.ZRange) bool {
+	box, ok := r.box(quadrant)
+	return ok && r.shape.IntersectsBox(box)
 }
 
-// GetSpaceFillingCurve returns the z2 spce filling curve
-func (z2search *Z2Search) GetSpaceFillingCurve() zorder.SpaceFillingCurve {
-	return z2search.curve
+// box returns the area covered by the quadrant cells, ok is false when the
+// quadrant is beyond the normalized values
+func (r *shapeRegion) box(quadrant zorder.ZRange) (sfc.BoundingBox, bool) {
+	x0, y0 := r.z2.UnApply(quadrant.Min)
+	x1, y1 := r.z2.UnApply(quadrant.Max)
+	if x0 > r.maxIndex || y0 > r.maxIndex {
+		return sfc.BoundingBox{}, false
+	}
+	x1, y1 = utils.MinUint(x1, r.maxIndex), utils.MinUint(y1, r.maxIndex)
+
+	return sfc.BoundingBox{
+		SouthWest: sfc.Point{Longitude: -180 + float64(x0)*r.lonStep, Latitude: -90 + float64(y0)*r.latStep},
+		NorthEast: sfc.Point{Longitude: -180 + float64(x1+1)*r.lonStep, Latitude: -90 + float64(y1+1)*r.latStep},
+	}, true
 }
@@ -176,6 +176,181 @@ func TestSearch(t *testing.T) {
 	}
 }
 
+func TestSearchShape(t *testing.T) {
+	toulouse := sfc.Point{Longitude: 1.444, Latitude: 43.604}
+
+	polygon, _ := sfc.NewPolygon(
+		[]sfc.Point{{Longitude: 1.3, Latitude: 43.5}, {Longitude: 1.6, Latitude: 43.5}, {Longitude: 1.45, Latitude: 43.75}},
+		[]sfc.Point{{Longitude: 1.42, Latitude: 43.56}, {Longitude: 1.48, Latitude: 43.56}, {Longitude: 1.48, Latitude: 43.6}, {Longitude: 1.42, Latitude: 43.6}},
+	)
+	circle, _ := sfc.NewCircle(toulouse, 5000)
+	polyline, _ := sfc.NewPolyline([]sfc.Point{{Longitude: 1.35, Latitude: 43.55}, {Longitude: 1.444, Latitude: 43.604}, {Longitude: 1.5, Latitude: 43.7}}, 500)
+
+	tests := []struct {
+		name  string
+		shape sfc.Shape
+	}{
+		{name: "polygon", shape: polygon},
+		{name: "circle", shape: circle},
+		{name: "polyline", shape: polyline},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			search, _ := z2.NewSearch(z2.NormalizerMaxPrecision)
+			result, err := search.GetZ2RangesForShape(tt.shape)
+			Expect(err).To(BeNil())
+			Expect(result).ToNot(BeEmpty())
+			Expect(contained(result)).To(BeTrue())
+
+			// Shape ranges cover less cells than its bounding box ranges
+			bbox := tt.shape.Bounds()
+			bboxResult, _ := search.GetZ2Ranges(bbox)
+			Expect(cells(result)).To(BeNumerically("<", cells(bboxResult)))
+
+			curve := search.GetSpaceFillingCurve()
+			stepx := (bbox.NorthEast.Longitude - bbox.SouthWest.Longitude) / 50
+			stepy := (bbox.NorthEast.Latitude - bbox.SouthWest.Latitude) / 50
+			for x := bbox.SouthWest.Longitude; x <= bbox.NorthEast.Longitude; x = x + stepx {
+				for y := bbox.SouthWest.Latitude; y <= bbox.NorthEast.Latitude; y = y + stepy {
+					point := sfc.Point{Longitude: x, Latitude: y}
+					z2n, _ := curve.Index(x, y)
+					indexRange := findRange(z2n, result)
+
+					if tt.shape.ContainsBox(sfc.BoundingBox{SouthWest: point, NorthEast: point}) {
+						Expect(indexRange).ToNot(BeNil())
+					} else if indexRange != nil {
+						Expect(indexRange.Contained).To(BeFalse())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSearchAntimeridian(t *testing.T) {
+	tests := []struct {
+		name    string
+		bbox    sfc.BoundingBox
+		inside  []sfc.Point
+		outside []sfc.Point
+	}{
+		{
+			name: "fiji",
+			bbox: sfc.BoundingBox{
+				SouthWest: sfc.Point{Longitude: 176, Latitude: -21},
+				NorthEast: sfc.Point{Longitude: -178, Latitude: -12},
+			},
+			inside:  []sfc.Point{{Longitude: 178.44, Latitude: -18.14}, {Longitude: 179.99, Latitude: -16.8}, {Longitude: -179.99, Latitude: -16.8}, {Longitude: -178.5, Latitude: -20}},
+			outside: []sfc.Point{{Longitude: 0, Latitude: -16.8}, {Longitude: 160, Latitude: -16.8}, {Longitude: -150, Latitude: -16.8}},
+		},
+		{
+			name: "bering strait",
+			bbox: sfc.BoundingBox{
+				SouthWest: sfc.Point{Longitude: 160, Latitude: 60},
+				NorthEast: sfc.Point{Longitude: 200, Latitude: 70},
+			},
+			inside:  []sfc.Point{{Longitude: 172, Latitude: 64.5}, {Longitude: -168, Latitude: 65.7}, {Longitude: 180, Latitude: 65}},
+			outside: []sfc.Point{{Longitude: 0, Latitude: 65}, {Longitude: 100, Latitude: 65}, {Longitude: -100, Latitude: 65}},
+		},
+		{
+			name: "arctic",
+			bbox: sfc.BoundingBox{
+				SouthWest: sfc.Point{Longitude: -180, Latitude: 80},
+				NorthEast: sfc.Point{Longitude: 180, Latitude: 95},
+			},
+			inside:  []sfc.Point{{Longitude: 0, Latitude: 90}, {Longitude: -179.9, Latitude: 85}, {Longitude: 179.9, Latitude: 85}},
+			outside: []sfc.Point{{Longitude: 0, Latitude: 45}, {Longitude: 179.9, Latitude: 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			search, _ := z2.NewSearch(z2.NormalizerMaxPrecision)
+			result, err := search.GetZ2Ranges(tt.bbox)
+			Expect(err).To(BeNil())
+
+			// Ranges are sorted and disjoint
+			for i := 1; i < len(result); i++ {
+				Expect(result[i].Lower).To(BeNumerically(">", result[i-1].Upper))
+			}
+
+			curve := search.GetSpaceFillingCurve()
+			for _, p := range tt.inside {
+				z2n, _ := curve.Index(p.Longitude, p.Latitude)
+				Expect(isInRange(z2n, result)).To(BeTrue())
+			}
+			for _, p := range tt.outside {
+				z2n, _ := curve.Index(p.Longitude, p.Latitude)
+				Expect(isInRange(z2n, result)).To(BeFalse())
+			}
+		})
+	}
+}
+
+func TestSearchShapeAntimeridian(t *testing.T) {
+	RegisterTestingT(t)
+
+	// Taveuni, Fiji
+	circle, _ := sfc.NewCircle(sfc.Point{Longitude: 179.95, Latitude: -16.8}, 20000)
+
+	search, _ := z2.NewSearch(z2.NormalizerMaxPrecision)
+	result, err := search.GetZ2RangesForShape(circle)
+	Expect(err).To(BeNil())
+	Expect(contained(result)).To(BeTrue())
+
+	curve := search.GetSpaceFillingCurve()
+	for _, lon := range []float64{179.8, 179.95, 180, -180, -179.9} {
+		z2n, _ := curve.Index(lon, -16.8)
+		Expect(findRange(z2n, result)).ToNot(BeNil())
+	}
+	z2n, _ := curve.Index(-179, -16.8)
+	Expect(findRange(z2n, result)).To(BeNil())
+}
+
+func BenchmarkGetZ2RangesForShape(b *testing.B) {
+	b.ReportAllocs()
+
+	search, _ := z2.NewSearch(z2.NormalizerMaxPrecision)
+	circle, _ := sfc.NewCircle(sfc.Point{Longitude: 1.444, Latitude: 43.604}, 5000)
+
+	for n := 0; n < b.N; n++ {
+		search.GetZ2RangesForShape(circle)
+	}
+}
+
+// cells returns the number of z values in ranges
+func cells(ranges []*sfc.IndexRange) uint64 {
+	count := uint64(0)
+	for _, indexRange := range ranges {
+		count += indexRange.Upper - indexRange.Lower + 1
+	}
+	return count
+}
+
+// contained returns true if any range is contained
+func contained(ranges []*sfc.IndexRange) bool {
+	for _, indexRange := range ranges {
+		if indexRange.Contained {
+			return true
+		}
+	}
+	return false
+}
+
+func findRange(z2n zorder.Z2N, ranges []*sfc.IndexRange) *sfc.IndexRange {
+	for _, indexRange := range ranges {
+		if indexRange.Lower <= z2n.GetZValue() && z2n.GetZValue() <= indexRange.Upper {
+			return indexRange
+		}
+	}
+	return nil
+}
+
 func isInRange(z2n zorder.Z2N, ranges []*sfc.IndexRange) bool {
 
 	for _, indexRange := range ranges {
@@ -7,9 +7,14 @@ import (
 	"github.com/scraly/go.common/pkg/sfc"
 	"github.com/scraly/go.common/pkg/sfc/utils"
 	"github.com/scraly/go.common/pkg/sfc/zorder"
+	"github.com/scraly/go.common/pkg/sfc/zorder/normalizer"
 	zranges "github.com/scraly/go.common/pkg/sfc/zorder/zrange"
 )
 
+// shapeMaxRecurse is deeper than for bounding boxes, so that cells along the
+// shape edges are small enough to be contained or excluded
+const shapeMaxRecurse = 10
+
 // Z3Search struct
 type Z3Search struct { // nolint: golint
 	curve zorder.SpaceTimeFillingCurve
@@ -27,44 +32,168 @@ func NewSearch() (sfc.Z3Search, error) {
 	}, nil
 }
 
-// GetZ3Ranges returns all Z3 Index Ranges corresponding to bounding box bbox and time frame (dateMin, dateMax)
+// GetZ3Ranges returns all Z3 Index Ranges corresponding to bounding box bbox and time frame (dateMin, dateMax), a box crossing the antimeridian is searched in two parts
 func (z3search *Z3Search) GetZ3Ranges(bbox sfc.BoundingBox, dateMin, dateMax time.Time) ([]*sfc.IndexRange, []*utils.WeekTimeRange, error) {
-	z3Sfc := z3search.curve
+	weekTimeRanges, errWeekTimeRanges := utils.GetWeekTimeRangeFromDateRange(dateMin, dateMax)
+	if errWeekTimeRanges != nil {
+		return nil, nil, errWeekTimeRanges
+	}
+
+	boxes, errSplit := bbox.Split()
+	if errSplit != nil {
+		return nil, nil, errSplit
+	}
+
+	result := make([]*sfc.IndexRange, 0)
+	for _, box := range boxes {
+		zbounds, errZBounds := z3search.zbounds(box, weekTimeRanges)
+		if errZBounds != nil {
+			return nil, nil, errZBounds
+		}
+
+		ranges, errRanges := zranges.CalculateRanges(NewZ3(), zbounds, 64, 0, 7)
+		if errRanges != nil {
+			return nil, nil, errRanges
+		}
+		result = append(result, ranges...)
+	}
+
+	return zranges.MergeRanges(result), weekTimeRanges, nil
 
+}
+
+// GetZ3RangesForShape returns all Z3 Index Ranges covering shape and time frame (dateMin, dateMax), ranges are contained when all their cells are inside both
+func (z3search *Z3Search) GetZ3RangesForShape(shape sfc.Shape, dateMin, dateMax time.Time) ([]*sfc.IndexRange, []*utils.WeekTimeRange, error) {
 	weekTimeRanges, errWeekTimeRanges := utils.GetWeekTimeRangeFromDateRange(dateMin, dateMax)
 	if errWeekTimeRanges != nil {
 		return nil, nil, errWeekTimeRanges
 	}
 
+	boxes, errSplit := shape.Bounds().Split()
+	if errSplit != nil {
+		return nil, nil, errSplit
+	}
+
+	region, errRegion := newShapeRegion(shape, weekTimeRanges)
+	if errRegion != nil {
+		return nil, nil, errRegion
+	}
+
+	result := make([]*sfc.IndexRange, 0)
+	for _, box := range boxes {
+		zbounds, errZBounds := z3search.zbounds(box, weekTimeRanges)
+		if errZBounds != nil {
+			return nil, nil, errZBounds
+		}
+
+		ranges, errRanges := zranges.CalculateRegionRanges(NewZ3(), zbounds, region, 64, 0, shapeMaxRecurse)
+		if errRanges != nil {
+			return nil, nil, errRanges
+		}
+		result = append(result, ranges...)
+	}
+
+	return zranges.MergeRanges(result), weekTimeRanges, nil
+}
+
+// zbounds returns a Z3 range per week time range of bbox
+func (z3search *Z3Search) zbounds(bbox sfc.BoundingBox, weekTimeRanges []*utils.WeekTimeRange) ([]zorder.ZRange, error) {
+	z3Sfc := z3search.curve
+
 	zbounds := make([]zorder.ZRange, 0)
 	for _, weekTimeRange := range weekTimeRanges {
 		z3nMin, errZ3nMin := z3Sfc.Index(bbox.SouthWest.Longitude, bbox.SouthWest.Latitude, uint64(math.Floor(weekTimeRange.MinWeekDate.Seconds)))
 		if errZ3nMin != nil {
-			return nil, nil, errZ3nMin
+			return nil, errZ3nMin
 		}
 
 		z3nMax, errZ3nMax := z3Sfc.Index(bbox.NorthEast.Longitude, bbox.NorthEast.Latitude, uint64(math.Floor(weekTimeRange.MaxWeekDate.Seconds)))
 		if errZ3nMax != nil {
-			return nil, nil, errZ3nMax
+			return nil, errZ3nMax
 		}
 
 		zrange, errZRange := zorder.NewZRange(z3nMin.GetZValue(), z3nMax.GetZValue())
 		if errZRange != nil {
-			return nil, nil, errZRange
+			return nil, errZRange
 		}
 		zbounds = append(zbounds, *zrange)
 	}
 
-	result, errRanges := zranges.CalculateRanges(NewZ3(), zbounds, 64, 0, 7)
-	if errRanges != nil {
-		return nil, nil, errRanges
-	}
-
-	return result, weekTimeRanges, nil
-
+	return zbounds, nil
 }
 
 // GetSpaceTimeFillingCurve returns the z3 spce filling curve
 func (z3search *Z3Search) GetSpaceTimeFillingCurve() zorder.SpaceTimeFillingCurve {
 	return z3search.curve
 }
+
+// timeCells is a range of normalized time values
+type timeCells struct {
+	min, max uint
+}
+
+// shapeRegion relates the cells of a Z3 quadrant to a shape and week time ranges
+type shapeRegion struct {
+	shape    sfc.Shape
+	z3       zorder.Z3N
+	times    []timeCells
+	maxIndex uint
+	lonStep  float64
+	latStep  float64
+}
+
+func newShapeRegion(shape sfc.Shape, weekTimeRanges []*utils.WeekTimeRange) (zranges.Region, error) {
+	timeNormalizer, errTimeNormalizer := normalizer.NewNormalizer(0, 604800, NormalizerMaxPrecision)
+	if errTimeNormalizer != nil {
+		return nil, errTimeNormalizer
+	}
+
+	times := make([]timeCells, 0, len(weekTimeRanges))
+	for _, weekTimeRange := range weekTimeRanges {
+		times = append(times, timeCells{
+			min: timeNormalizer.Normalize(math.Floor(weekTimeRange.MinWeekDate.Seconds)),
+			max: timeNormalizer.Normalize(math.Floor(weekTimeRange.MaxWeekDate.Seconds)),
+		})
+	}
+
+	bins := uint(1) << NormalizerMaxPrecision
+	return &shapeRegion{
+		shape:    shape,
+		z3:       NewZ3(),
+		times:    times,
+		maxIndex: bins - 1,
+		lonStep:  360 / float64(bins),
+		latStep:  180 / float64(bins),
+	}, nil
+}
+
+func (r *shapeRegion) Contains(quadrant zorder.ZRange) bool {
+	box, t0, t1 := r.cells(quadrant)
+	for _, times := range r.times {
+		if times.min <= t0 && t1 <= times.max {
+			return r.shape.ContainsBox(box)
+		}
+	}
+	return false
+}
+
+func (r *shapeRegion) Overlaps(quadrant zorder.ZRange) bool {
+	box, t0, t1 := r.cells(quadrant)
+	for _, times := range r.times {
+		if times.min <= t1 && t0 <= times.max {
+			return r.shape.IntersectsBox(box)
+		}
+	}
+	return false
+}
+
+// cells returns the area and time cells covered by the quadrant
+func (r *shapeRegion) cells(quadrant zorder.ZRange) (sfc.BoundingBox, uint, uint) {
+	x0, y0, t0 := r.z3.UnApply(quadrant.Min)
+	x1, y1, t1 := r.z3.UnApply(quadrant.Max)
+
+	return sfc.BoundingBox{
+		SouthWest: sfc.Point{Longitude: -180 + float64(x0)*r.lonStep, Latitude: -90 + float64(y0)*r.latStep},
+		NorthEast: sfc.Point{Longitude: -180 + float64(x1+1)*r.lonStep, Latitude: -90 + float64(y1+1)*r.latStep},
+	}, t0, t1
+}
@@ -292,6 +292,94 @@ func TestZ3Loose(t *testing.T) {
 
 }
 
+func TestSearchShape(t *testing.T) {
+	toulouse := sfc.Point{Longitude: 1.444, Latitude: 43.604}
+	dateMin := time.Date(2018, 9, 10, 7, 0, 0, 0, time.UTC)
+	dateMax := time.Date(2018, 9, 10, 19, 0, 0, 0, time.UTC)
+
+	polygon, _ := sfc.NewPolygon(
+		[]sfc.Point{{Longitude: 1.3, Latitude: 43.5}, {Longitude: 1.6, Latitude: 43.5}, {Longitude: 1.45, Latitude: 43.75}},
+		[]sfc.Point{{Longitude: 1.42, Latitude: 43.56}, {Longitude: 1.48, Latitude: 43.56}, {Longitude: 1.48, Latitude: 43.6}, {Longitude: 1.42, Latitude: 43.6}},
+	)
+	circle, _ := sfc.NewCircle(toulouse, 5000)
+	polyline, _ := sfc.NewPolyline([]sfc.Point{{Longitude: 1.35, Latitude: 43.55}, {Longitude: 1.444, Latitude: 43.604}, {Longitude: 1.5, Latitude: 43.7}}, 500)
+
+	tests := []struct {
+		name  string
+		shape sfc.Shape
+	}{
+		{name: "polygon", shape: polygon},
+		{name: "circle", shape: circle},
+		{name: "polyline", shape: polyline},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			search, _ := z3.NewSearch()
+			result, weekTimeRanges, err := search.GetZ3RangesForShape(tt.shape, dateMin, dateMax)
+			Expect(err).To(BeNil())
+			Expect(result).ToNot(BeEmpty())
+
+			bbox := tt.shape.Bounds()
+			stfc := search.GetSpaceTimeFillingCurve()
+			stepx := (bbox.NorthEast.Longitude - bbox.SouthWest.Longitude) / 10
+			stepy := (bbox.NorthEast.Latitude - bbox.SouthWest.Latitude) / 10
+			for x := bbox.SouthWest.Longitude; x <= bbox.NorthEast.Longitude; x = x + stepx {
+				for y := bbox.SouthWest.Latitude; y <= bbox.NorthEast.Latitude; y = y + stepy {
+					point := sfc.Point{Longitude: x, Latitude: y}
+					inside := tt.shape.ContainsBox(sfc.BoundingBox{SouthWest: point, NorthEast: point})
+
+					for _, weekTimeRange := range weekTimeRanges {
+						for s := weekTimeRange.MinWeekDate.Seconds; s <= weekTimeRange.MaxWeekDate.Seconds; s = s + 7200 {
+							z3n, _ := stfc.Index(x, y, uint64(math.Round(s)))
+							indexRange := findRange(z3n, result)
+
+							if inside {
+								Expect(indexRange).ToNot(BeNil())
+							} else if indexRange != nil {
+								Expect(indexRange.Contained).To(BeFalse())
+							}
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSearchAntimeridian(t *testing.T) {
+	RegisterTestingT(t)
+
+	// Fiji
+	bbox := sfc.BoundingBox{
+		SouthWest: sfc.Point{Longitude: 176, Latitude: -21},
+		NorthEast: sfc.Point{Longitude: -178, Latitude: -12},
+	}
+	dateMin := time.Date(2018, 9, 10, 7, 0, 0, 0, time.UTC)
+	dateMax := time.Date(2018, 9, 10, 19, 0, 0, 0, time.UTC)
+
+	search, _ := z3.NewSearch()
+	result, weekTimeRanges, err := search.GetZ3Ranges(bbox, dateMin, dateMax)
+	Expect(err).To(BeNil())
+
+	for i := 1; i < len(result); i++ {
+		Expect(result[i].Lower).To(BeNumerically(">", result[i-1].Upper))
+	}
+
+	stfc := search.GetSpaceTimeFillingCurve()
+	seconds := uint64(math.Round((weekTimeRanges[0].MinWeekDate.Seconds + weekTimeRanges[0].MaxWeekDate.Seconds) / 2))
+	for _, p := range []sfc.Point{{Longitude: 178.44, Latitude: -18.14}, {Longitude: 179.99, Latitude: -16.8}, {Longitude: -179.99, Latitude: -16.8}, {Longitude: -178.5, Latitude: -20}} {
+		z3n, _ := stfc.Index(p.Longitude, p.Latitude, seconds)
+		Expect(isInRange(z3n, stfc, result)).To(BeTrue())
+	}
+	for _, p := range []sfc.Point{{Longitude: 0, Latitude: -16.8}, {Longitude: 160, Latitude: -16.8}, {Longitude: -150, Latitude: -16.8}} {
+		z3n, _ := stfc.Index(p.Longitude, p.Latitude, seconds)
+		Expect(isInRange(z3n, stfc, result)).To(BeFalse())
+	}
+}
+
 func BenchmarkGetZ3Ranges(b *testing.B) {
 	b.ReportAllocs()
 
@@ -316,3 +404,12 @@ func isInRange(z3n zorder.Z3N, stfc zorder.SpaceTimeFillingCurve, ranges []*sfc.
 
 	return false
 }
+
+func findRange(z3n zorder.Z3N, ranges []*sfc.IndexRange) *sfc.IndexRange {
+	for _, indexRange := range ranges {
+		if indexRange.Lower <= z3n.GetZValue() && z3n.GetZValue() <= indexRange.Upper {
+			return indexRange
+		}
+	}
+	return nil
+}
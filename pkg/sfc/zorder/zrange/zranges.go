@@ -16,8 +16,48 @@ type workItem struct {
 	stop bool
 }
 
+// Region tells how a quadrant of the curve relates to the searched area
+type Region interface {
+	// Contains returns true if every value of the quadrant is in the region
+	Contains(quadrant api.ZRange) bool
+	// Overlaps returns true if some value of the quadrant may be in the region
+	Overlaps(quadrant api.ZRange) bool
+}
+
+type boundsRegion struct {
+	zn      api.ZN
+	zbounds []api.ZRange
+}
+
+// NewBoundsRegion returns the region covered by the zbounds boxes
+func NewBoundsRegion(zn api.ZN, zbounds []api.ZRange) Region {
+	return &boundsRegion{
+		zn:      zn,
+		zbounds: zbounds,
+	}
+}
+
+func (r *boundsRegion) Contains(quadrant api.ZRange) bool {
+	return IsContained(r.zn, r.zbounds, quadrant)
+}
+
+func (r *boundsRegion) Overlaps(quadrant api.ZRange) bool {
+	return IsOverlapped(r.zn, r.zbounds, quadrant)
+}
+
 // CalculateRanges method returns
 func CalculateRanges(zn api.ZN, zbounds []api.ZRange, precision, maxRanges, maxRecurse int) ([]*sfc.IndexRange, error) {
+	return calculateRanges(zn, zbounds, NewBoundsRegion(zn, zbounds), precision, maxRanges, maxRecurse, false)
+}
+
+// CalculateRegionRanges returns the ranges covering the region, zbounds must
+// enclose the region and give the quadrant to start from. Adjacent ranges are
+// only merged when both are contained or both are not.
+func CalculateRegionRanges(zn api.ZN, zbounds []api.ZRange, region Region, precision, maxRanges, maxRecurse int) ([]*sfc.IndexRange, error) {
+	return calculateRanges(zn, zbounds, region, precision, maxRanges, maxRecurse, true)
+}
+
+func calculateRanges(zn api.ZN, zbounds []api.ZRange, region Region, precision, maxRanges, maxRecurse int, keepContained bool) ([]*sfc.IndexRange, error) {
 	ranges := make([]*sfc.IndexRange, 0)
 
 	zBoundsValues := make([]uint64, len(zbounds)*2)
@@ -36,7 +76,7 @@ func CalculateRanges(zn api.ZN, zbounds []api.ZRange, precision, maxRanges, maxR
 
 	remaining := utils.NewRingqueue()
 
-	iRange := CheckValue(zn, zbounds, commonPrefix, 0, offset, precision, remaining)
+	iRange := checkRegionValue(region, commonPrefix, 0, offset, precision, remaining)
 	if iRange != nil {
 		ranges = append(ranges, iRange)
 	}
@@ -72,7 +112,7 @@ func CalculateRanges(zn api.ZN, zbounds []api.ZRange, precision, maxRanges, maxR
 			prefix := next.(workItem).min
 			quadrant := 0
 			for quadrant < zn.GetQuadrants() {
-				indexRange := CheckValue(zn, zbounds, prefix, uint64(quadrant), offset, precision, remaining)
+				indexRange := checkRegionValue(region, prefix, uint64(quadrant), offset, precision, remaining)
 				if indexRange != nil {
 					ranges = append(ranges, indexRange)
 				}
@@ -95,6 +135,17 @@ func CalculateRanges(zn api.ZN, zbounds []api.ZRange, precision, maxRanges, maxR
 	}
 
 	// we've got all our ranges - now reduce them down by merging overlapping values
+	return mergeRanges(ranges, keepContained), nil
+}
+
+// MergeRanges sorts ranges and merges the overlapping ones, such as ranges of
+// several searches. Adjacent ranges are only merged when both are contained or
+// both are not.
+func MergeRanges(ranges []*sfc.IndexRange) []*sfc.IndexRange {
+	return mergeRanges(ranges, true)
+}
+
+func mergeRanges(ranges []*sfc.IndexRange, keepContained bool) []*sfc.IndexRange {
 	sort.SliceStable(ranges, func(i, j int) bool {
 
 		if ranges[i].Lower == ranges[j].Lower {
@@ -105,13 +156,17 @@ func CalculateRanges(zn api.ZN, zbounds []api.ZRange, precision, maxRanges, maxR
 
 	})
 
+	if len(ranges) == 0 {
+		return ranges
+	}
+
 	currentRange := ranges[0]
 	result := make([]*sfc.IndexRange, 0)
 
 	i := 1
 	for i <= len(ranges)-1 {
 		rangeZ := ranges[i]
-		if rangeZ.Lower <= currentRange.Upper+1 {
+		if rangeZ.Lower <= currentRange.Upper || (rangeZ.Lower == currentRange.Upper+1 && (!keepContained || rangeZ.Contained == currentRange.Contained)) {
 			// merge the two ranges
 			currentRange = &sfc.IndexRange{
 				Lower:     currentRange.Lower,
@@ -128,7 +183,7 @@ func CalculateRanges(zn api.ZN, zbounds []api.ZRange, precision, maxRanges, maxR
 
 	result = append(result, currentRange)
 
-	return result, nil
+	return result
 }
 
 // LongestCommonPrefix calculates the longes common prefix for all uint64 contained in values
@@ -194,6 +249,10 @@ func IsOverlapped(zn api.ZN, zbounds []api.ZRange, rangeZ api.ZRange) bool {
 
 // CheckValue checks a single value and either: eliminates it as out of bounds or adds it to our results as fully matching, or queues up it's children for further processing
 func CheckValue(zn api.ZN, zbounds []api.ZRange, prefix uint64, quadrant uint64, offset uint, precision int, remaining *utils.Ringqueue) *sfc.IndexRange {
+	return checkRegionValue(NewBoundsRegion(zn, zbounds), prefix, quadrant, offset, precision, remaining)
+}
+
+func checkRegionValue(region Region, prefix uint64, quadrant uint64, offset uint, precision int, remaining *utils.Ringqueue) *sfc.IndexRange {
 	min := prefix | (quadrant << offset)     // QR + 000...
 	max := min | ((uint64(1) << offset) - 1) // QR + 111...
 
@@ -203,7 +262,7 @@ func CheckValue(zn api.ZN, zbounds []api.ZRange, prefix uint64, quadrant uint64,
 		fmt.Println(errRange)
 	}
 
-	if IsContained(zn, zbounds, *quadrantRange) || (offset < uint(64-precision)) {
+	if region.Contains(*quadrantRange) || (offset < uint(64-precision)) {
 		// whole range matches, happy day
 		indexRange := &sfc.IndexRange{
 			Lower:     quadrantRange.Min,
@@ -211,7 +270,7 @@ func CheckValue(zn api.ZN, zbounds []api.ZRange, prefix uint64, quadrant uint64,
 			Contained: true,
 		}
 		return indexRange
-	} else if IsOverlapped(zn, zbounds, *quadrantRange) {
+	} else if region.Overlaps(*quadrantRange) {
 		// some portion of this range is excluded
 		// queue up each sub-range for processing
 		remaining.Add(workItem{
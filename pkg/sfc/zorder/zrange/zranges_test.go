@@ -389,3 +389,25 @@ func TestCalculateRangesZ2(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeRanges(t *testing.T) {
+	RegisterTestingT(t)
+
+	result := zranges.MergeRanges([]*sfc.IndexRange{
+		{Lower: 20, Upper: 29, Contained: true},
+		{Lower: 0, Upper: 9, Contained: true},
+		{Lower: 10, Upper: 15, Contained: true},
+		{Lower: 16, Upper: 19, Contained: false},
+		{Lower: 25, Upper: 40, Contained: false},
+		{Lower: 50, Upper: 60, Contained: true},
+	})
+
+	Expect(result).To(Equal([]*sfc.IndexRange{
+		{Lower: 0, Upper: 15, Contained: true},
+		{Lower: 16, Upper: 19, Contained: false},
+		{Lower: 20, Upper: 40, Contained: false},
+		{Lower: 50, Upper: 60, Contained: true},
+	}))
+
+	Expect(zranges.MergeRanges([]*sfc.IndexRange{})).To(BeEmpty())
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+// Package blindindex computes keyed hashes of values encrypted at rest, so that
+// records can be looked up by equality while their values keep a randomized
+// encryption.
+//
+// A blind index leaks which records share a value, and its frequency, to
+// anyone reading the index column; it does not reveal the value without the
+// index key. Low entropy values (booleans, birth years, small enumerations) can
+// still be recovered by counting, never index them. The index key must be
+// distinct from the data encryption key, and each indexed field must use its
+// own context so that equal values of different fields do not match.
+package blindindex
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+)
+
+// MinKeySize is the minimum size of the index key
+const MinKeySize = 32
+
+// MinSize is the minimum size of an index
+const MinSize = 4
+
+// Indexer computes blind indexes
+type Indexer interface {
+	// Index returns the blind index of the value, the context authenticated
+	// data separates indexes of different fields and must not be record
+	// specific
+	Index(data []byte, context value.Context) []byte
+}
+
+type hmacIndexer struct {
+	key       []byte
+	size      int
+	normalize func([]byte) []byte
+}
+
+// New returns an HMAC-SHA256 blind indexer keyed with key
+func New(key []byte, opts ...Option) (Indexer, error) {
+	// Default Options
+	options := &Options{
+		Size: DefaultSize,
+	}
+
+	// Overrides with option
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if len(key) < MinKeySize {
+		return nil, fmt.Errorf("blindindex: key must be at least %d bytes", MinKeySize)
+	}
+	if options.Size < MinSize || options.Size > sha256.Size {
+		return nil, fmt.Errorf("blindindex: invalid index size %d", options.Size)
+	}
+
+	return &hmacIndexer{
+		key:       append([]byte(nil), key...),
+		size:      options.Size,
+		normalize: options.Normalize,
+	}, nil
+}
+
+func (i *hmacIndexer) Index(data []byte, context value.Context) []byte {
+	if i.normalize != nil {
+		data = i.normalize(data)
+	}
+
+	// Context is length prefixed so that it cannot be confused with the value
+	var ad []byte
+	if context != nil {
+		ad = context.AuthenticatedData()
+	}
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(ad)))
+
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write(length[:])
+	mac.Write(ad)
+	mac.Write(data)
+
+	return mac.Sum(nil)[:i.size]
+}
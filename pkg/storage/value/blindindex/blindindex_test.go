@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package blindindex
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+)
+
+func TestIndex(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	email := value.DefaultContext([]byte("users.email"))
+	plate := value.DefaultContext([]byte("vehicles.plate"))
+
+	indexer, err := New(key, WithSize(16), WithNormalizer(bytes.ToLower))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := indexer.Index([]byte("john@example.com"), email)
+	if len(index) != 16 {
+		t.Fatalf("unexpected index size: %d", len(index))
+	}
+	if !bytes.Equal(index, indexer.Index([]byte("John@Example.com"), email)) {
+		t.Fatalf("normalized values should share an index")
+	}
+	if bytes.Equal(index, indexer.Index([]byte("jane@example.com"), email)) {
+		t.Fatalf("different values should not share an index")
+	}
+	if bytes.Equal(index, indexer.Index([]byte("john@example.com"), plate)) {
+		t.Fatalf("fields should not share an index")
+	}
+
+	other, err := New(bytes.Repeat([]byte("o"), 32), WithSize(16), WithNormalizer(bytes.ToLower))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(index, other.Index([]byte("john@example.com"), email)) {
+		t.Fatalf("index should depend on the key")
+	}
+
+	if _, err := New(key[:16]); err == nil {
+		t.Fatalf("expected error on short key")
+	}
+	if _, err := New(key, WithSize(64)); err == nil {
+		t.Fatalf("expected error on invalid size")
+	}
+}
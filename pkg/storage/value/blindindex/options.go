@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package blindindex
+
+// DefaultSize is the default size of an index, the full HMAC-SHA256 output
+const DefaultSize = 32
+
+// Options contains all values that are needed for blind indexes.
+type Options struct {
+	// Size truncates indexes to the given number of bytes, shorter indexes
+	// collide more often, leaking less about equal values
+	Size int
+	// Normalize canonicalizes values before indexing, such as lower casing
+	// emails, so that equivalent values share an index
+	Normalize func([]byte) []byte
+}
+
+// Option configures the blind index.
+type Option func(*Options)
+
+// WithSize truncates indexes to the given number of bytes.
+func WithSize(size int) Option {
+	return func(o *Options) {
+		o.Size = size
+	}
+}
+
+// WithNormalizer canonicalizes values before indexing.
+func WithNormalizer(fn func([]byte) []byte) Option {
+	return func(o *Options) {
+		o.Normalize = fn
+	}
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package value
+
+type chainTransformer struct {
+	transformers []Transformer
+}
+
+var _ Transformer = &chainTransformer{}
+
+// NewChainTransformer composes transformers into a pipeline. Values are transformed to storage by each
+// transformer in order, such as compress then encrypt, and from storage in the reverse order. The value
+// is stale when any transformer reports it stale.
+func NewChainTransformer(transformers ...Transformer) Transformer {
+	return &chainTransformer{
+		transformers: transformers,
+	}
+}
+
+// TransformFromStorage applies the transformers in reverse order.
+func (t *chainTransformer) TransformFromStorage(data []byte, context Context) ([]byte, bool, error) {
+	stale := false
+	for i := len(t.transformers) - 1; i >= 0; i-- {
+		result, s, err := t.transformers[i].TransformFromStorage(data, context)
+		if err != nil {
+			return nil, false, err
+		}
+		data, stale = result, stale || s
+	}
+	return data, stale, nil
+}
+
+// TransformToStorage applies the transformers in order.
+func (t *chainTransformer) TransformToStorage(data []byte, context Context) ([]byte, error) {
+	for _, transformer := range t.transformers {
+		result, err := transformer.TransformToStorage(data, context)
+		if err != nil {
+			return nil, err
+		}
+		data = result
+	}
+	return data, nil
+}
@@ -0,0 +1,300 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+// Package compress transforms values for storage using snappy, gzip or zstd
+// compression. Compression must happen before encryption, chain it with
+// value.NewChainTransformer.
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+)
+
+// Algorithm identifies the compression of a value, it is the first byte of
+// values written by NewTransformer
+type Algorithm byte
+
+const (
+	// None stores the value as is
+	None Algorithm = iota
+	// Snappy block format
+	Snappy
+	// Gzip stream
+	Gzip
+	// Zstd frame
+	Zstd
+)
+
+var (
+	// ErrUnknownAlgorithm is raised when a value header names an unsupported algorithm
+	ErrUnknownAlgorithm = errors.New("compress: unknown compression algorithm")
+	// ErrMissingHeader is raised when an empty value is read
+	ErrMissingHeader = errors.New("compress: missing compression header")
+	// ErrTooLarge is raised when a decompressed value exceeds the maximum size
+	ErrTooLarge = errors.New("compress: decompressed value exceeds maximum size")
+)
+
+// String returns the algorithm name
+func (a Algorithm) String() string {
+	switch a {
+	case None:
+		return "none"
+	case Snappy:
+		return "snappy"
+	case Gzip:
+		return "gzip"
+	case Zstd:
+		return "zstd"
+	}
+	return fmt.Sprintf("Algorithm(%d)", byte(a))
+}
+
+// -----------------------------------------------------------------------------
+
+// encoder compresses a value
+type encoder func(data []byte) ([]byte, error)
+
+type headerTransformer struct {
+	alg     Algorithm
+	encode  encoder
+	minSize int
+	maxSize int
+}
+
+// NewTransformer returns a transformer compressing values with the algorithm.
+// Values start with a one byte header naming the algorithm, so that values
+// written with any algorithm remain readable and are reported stale. Values
+// smaller than the minimum size, or that do not shrink, are stored as is.
+func NewTransformer(alg Algorithm, opts ...Option) (value.Transformer, error) {
+	options := buildOptions(opts)
+
+	encode, err := newEncoder(alg, options.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	return &headerTransformer{
+		alg:     alg,
+		encode:  encode,
+		minSize: options.MinSize,
+		maxSize: options.MaxSize,
+	}, nil
+}
+
+func (t *headerTransformer) TransformFromStorage(data []byte, context value.Context) ([]byte, bool, error) {
+	if len(data) == 0 {
+		return nil, false, ErrMissingHeader
+	}
+	alg := Algorithm(data[0])
+
+	result, err := decode(alg, data[1:], t.maxSize)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return result, alg != t.alg && alg != None, nil
+}
+
+func (t *headerTransformer) TransformToStorage(data []byte, context value.Context) ([]byte, error) {
+	if t.alg != None && len(data) >= t.minSize {
+		compressed, err := t.encode(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(compressed) < len(data) {
+			return append([]byte{byte(t.alg)}, compressed...), nil
+		}
+	}
+
+	return append([]byte{byte(None)}, data...), nil
+}
+
+// -----------------------------------------------------------------------------
+
+type rawTransformer struct {
+	alg     Algorithm
+	encode  encoder
+	maxSize int
+}
+
+// NewRawTransformer returns a transformer compressing values with the
+// algorithm without header, for formats predating it.
+func NewRawTransformer(alg Algorithm, opts ...Option) (value.Transformer, error) {
+	options := buildOptions(opts)
+
+	encode, err := newEncoder(alg, options.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rawTransformer{
+		alg:     alg,
+		encode:  encode,
+		maxSize: options.MaxSize,
+	}, nil
+}
+
+func (t *rawTransformer) TransformFromStorage(data []byte, context value.Context) ([]byte, bool, error) {
+	result, err := decode(t.alg, data, t.maxSize)
+	return result, false, err
+}
+
+func (t *rawTransformer) TransformToStorage(data []byte, context value.Context) ([]byte, error) {
+	return t.encode(data)
+}
+
+// -----------------------------------------------------------------------------
+
+func buildOptions(opts []Option) *Options {
+	// Default Options
+	options := &Options{
+		Level:   DefaultLevel,
+		MinSize: DefaultMinSize,
+		MaxSize: DefaultMaxSize,
+	}
+
+	// Overrides with option
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+func newEncoder(alg Algorithm, level int) (encoder, error) {
+	switch alg {
+	case None:
+		return func(data []byte) ([]byte, error) { return data, nil }, nil
+	case Snappy:
+		return func(data []byte) ([]byte, error) { return snappy.Encode(nil, data), nil }, nil
+	case Gzip:
+		if level != DefaultLevel && (level < gzip.HuffmanOnly || level > gzip.BestCompression) {
+			return nil, fmt.Errorf("compress: invalid gzip level %d", level)
+		}
+		return func(data []byte) ([]byte, error) { return gzipEncode(data, level) }, nil
+	case Zstd:
+		zopts := []zstd.EOption{}
+		if level != DefaultLevel {
+			zopts = append(zopts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		// Encoder is safe for concurrent EncodeAll calls
+		encoder, err := zstd.NewWriter(nil, zopts...)
+		if err != nil {
+			return nil, err
+		}
+		return func(data []byte) ([]byte, error) { return encoder.EncodeAll(data, nil), nil }, nil
+	}
+	return nil, ErrUnknownAlgorithm
+}
+
+// decode decompresses the value, up to maxSize bytes when positive
+func decode(alg Algorithm, data []byte, maxSize int) ([]byte, error) {
+	switch alg {
+	case None:
+		return data, nil
+	case Snappy:
+		// Snappy blocks start with the decoded length
+		size, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if maxSize > 0 && size > maxSize {
+			return nil, ErrTooLarge
+		}
+		return snappy.Decode(nil, data)
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		if maxSize <= 0 {
+			return ioutil.ReadAll(r)
+		}
+		// Read one more byte to detect values exceeding the limit
+		result, err := ioutil.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(result) > maxSize {
+			return nil, ErrTooLarge
+		}
+		return result, nil
+	case Zstd:
+		decoder, err := sharedZstdDecoder(maxSize)
+		if err != nil {
+			return nil, err
+		}
+		result, err := decoder.DecodeAll(data, nil)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+			return nil, ErrTooLarge
+		}
+		return result, err
+	}
+	return nil, ErrUnknownAlgorithm
+}
+
+func gzipEncode(data []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var (
+	zstdDecodersMu sync.Mutex
+	zstdDecoders   = map[int]*zstd.Decoder{}
+)
+
+// sharedZstdDecoder returns the decoder used by all transformers with the same
+// maximum size, it is safe for concurrent DecodeAll calls
+func sharedZstdDecoder(maxSize int) (*zstd.Decoder, error) {
+	if maxSize < 0 {
+		maxSize = 0
+	}
+
+	zstdDecodersMu.Lock()
+	defer zstdDecodersMu.Unlock()
+
+	if decoder, ok := zstdDecoders[maxSize]; ok {
+		return decoder, nil
+	}
+
+	dopts := []zstd.DOption{}
+	if maxSize > 0 {
+		dopts = append(dopts, zstd.WithDecoderMaxMemory(uint64(maxSize)))
+	}
+	decoder, err := zstd.NewReader(nil, dopts...)
+	if err != nil {
+		return nil, err
+	}
+	zstdDecoders[maxSize] = decoder
+
+	return decoder, nil
+}
@@ -0,0 +1,197 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package compress
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/golang/snappy"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+	aestransformer "github.com/scraly/go.common/pkg/storage/value/encrypt/aes"
+)
+
+var jsonValue = bytes.Repeat([]byte(`{"kty":"EC","crv":"P-256","x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU"},`), 20)
+
+func TestRoundTrip(t *testing.T) {
+	random := make([]byte, 1024)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, alg := range []Algorithm{None, Snappy, Gzip, Zstd} {
+		transformer, err := NewTransformer(alg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, data := range [][]byte{{}, []byte("short"), jsonValue, random} {
+			out, err := transformer.TransformToStorage(data, nil)
+			if err != nil {
+				t.Fatalf("%s: %v", alg, err)
+			}
+
+			switch {
+			case alg != None && bytes.Equal(data, jsonValue):
+				if Algorithm(out[0]) != alg || len(out) >= len(data) {
+					t.Fatalf("%s: value should be compressed, got %d bytes", alg, len(out))
+				}
+			default:
+				if Algorithm(out[0]) != None || !bytes.Equal(data, out[1:]) {
+					t.Fatalf("%s: value should be stored as is", alg)
+				}
+			}
+
+			from, stale, err := transformer.TransformFromStorage(out, nil)
+			if err != nil || stale || !bytes.Equal(data, from) {
+				t.Fatalf("%s: unexpected data: %t %v", alg, stale, err)
+			}
+		}
+	}
+}
+
+func TestAlgorithmChange(t *testing.T) {
+	gzip, err := NewTransformer(Gzip, WithLevel(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zstd, err := NewTransformer(Zstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := gzip.TransformToStorage(jsonValue, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, stale, err := zstd.TransformFromStorage(out, nil)
+	if err != nil || !stale || !bytes.Equal(jsonValue, from) {
+		t.Fatalf("unexpected data: %t %v", stale, err)
+	}
+
+	if _, _, err := zstd.TransformFromStorage([]byte{42, 1, 2}, nil); err != ErrUnknownAlgorithm {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := zstd.TransformFromStorage(nil, nil); err != ErrMissingHeader {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := NewTransformer(Gzip, WithLevel(42)); err == nil {
+		t.Fatalf("expected error on invalid level")
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	const maxSize = 4096
+	ctx := value.DefaultContext("key")
+	bomb := make([]byte, 1<<20)
+
+	for _, alg := range []Algorithm{Snappy, Gzip, Zstd} {
+		writer, err := NewTransformer(alg, WithMaxSize(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader, err := NewTransformer(alg, WithMaxSize(maxSize))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Value under the limit
+		stored, err := writer.TransformToStorage(bomb[:maxSize], ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out, _, err := reader.TransformFromStorage(stored, ctx); err != nil || len(out) != maxSize {
+			t.Fatalf("%s: unexpected result under the limit: %d bytes, %v", alg, len(out), err)
+		}
+
+		// Highly compressible value above the limit
+		stored, err = writer.TransformToStorage(bomb, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := reader.TransformFromStorage(stored, ctx); err != ErrTooLarge {
+			t.Fatalf("%s: expected %v, got %v", alg, ErrTooLarge, err)
+		}
+
+		// Raw transformers share the limit
+		raw, err := NewRawTransformer(alg, WithMaxSize(maxSize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := raw.TransformFromStorage(stored[1:], ctx); err != ErrTooLarge {
+			t.Fatalf("%s: expected %v for raw transformer, got %v", alg, ErrTooLarge, err)
+		}
+	}
+}
+
+func TestRawSnappy(t *testing.T) {
+	transformer, err := NewRawTransformer(Snappy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := transformer.TransformToStorage(jsonValue, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(snappy.Encode(nil, jsonValue), out) {
+		t.Fatalf("raw transformer should produce a snappy block")
+	}
+}
+
+func TestCompressThenEncrypt(t *testing.T) {
+	block, err := aes.NewCipher(bytes.Repeat([]byte("a"), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compression, err := NewTransformer(Zstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context := value.DefaultContext([]byte("authenticated_data"))
+	transformer := value.NewChainTransformer(compression, aestransformer.NewGCMTransformer(block))
+
+	out, err := transformer.TransformToStorage(jsonValue, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) >= len(jsonValue) {
+		t.Fatalf("value should be compressed before encryption, got %d bytes", len(out))
+	}
+
+	from, stale, err := transformer.TransformFromStorage(out, context)
+	if err != nil || stale || !bytes.Equal(jsonValue, from) {
+		t.Fatalf("unexpected data: %t %v", stale, err)
+	}
+}
+
+func benchmarkWrite(b *testing.B, alg Algorithm) {
+	transformer, err := NewTransformer(alg)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(jsonValue)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := transformer.TransformToStorage(jsonValue, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSnappyWrite(b *testing.B) { benchmarkWrite(b, Snappy) }
+func BenchmarkGzipWrite(b *testing.B)   { benchmarkWrite(b, Gzip) }
+func BenchmarkZstdWrite(b *testing.B)   { benchmarkWrite(b, Zstd) }
@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package compress
+
+const (
+	// DefaultLevel uses the default compression level of the algorithm
+	DefaultLevel = -1
+	// DefaultMinSize is the default size under which values are not compressed
+	DefaultMinSize = 64
+	// DefaultMaxSize is the default maximum size of a decompressed value
+	DefaultMaxSize = 64 << 20
+)
+
+// Options contains all values that are needed for compression transformers.
+type Options struct {
+	// Level is the gzip or zstd compression level
+	Level int
+	// MinSize is the size under which values are stored uncompressed, ignored
+	// by raw transformers
+	MinSize int
+	// MaxSize is the maximum size of a decompressed value, no limit when not
+	// positive
+	MaxSize int
+}
+
+// Option configures the compression transformer.
+type Option func(*Options)
+
+// WithLevel sets the gzip or zstd compression level.
+func WithLevel(level int) Option {
+	return func(o *Options) {
+		o.Level = level
+	}
+}
+
+// WithMinSize sets the size under which values are stored uncompressed.
+func WithMinSize(size int) Option {
+	return func(o *Options) {
+		o.MinSize = size
+	}
+}
+
+// WithMaxSize sets the maximum size of a decompressed value, so that a small
+// crafted value cannot exhaust memory. Zero disables the limit.
+func WithMaxSize(size int) Option {
+	return func(o *Options) {
+		o.MaxSize = size
+	}
+}
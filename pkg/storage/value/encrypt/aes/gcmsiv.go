@@ -0,0 +1,309 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package aes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+)
+
+const (
+	gcmsivNonceSize = 12
+	gcmsivTagSize   = 16
+)
+
+var errGCMSIVOpen = errors.New("aes: message authentication failed")
+
+// gcmsiv implements AEAD encryption of the provided values with AES-GCM-SIV (RFC 8452).
+// The authenticated data provided as part of the value.Context method must match when the same
+// value is set to and loaded from storage.
+//
+// Unlike AES-GCM, a repeated nonce only reveals whether the same value was encrypted twice under
+// the same nonce and authenticated data, and each nonce derives its own record keys, so that a key
+// can encrypt far more values with random 96-bit nonces before it has to be rotated.
+type gcmsiv struct {
+	aead cipher.AEAD
+}
+
+// NewGCMSIVTransformer takes the given 16 or 32 bytes key and performs nonce-misuse-resistant
+// encryption and decryption on the given data.
+func NewGCMSIVTransformer(key []byte) (value.Transformer, error) {
+	aead, err := newGCMSIV(key)
+	if err != nil {
+		return nil, err
+	}
+	return &gcmsiv{aead: aead}, nil
+}
+
+func (t *gcmsiv) TransformFromStorage(data []byte, context value.Context) ([]byte, bool, error) {
+	nonceSize := t.aead.NonceSize()
+	if len(data) < nonceSize+t.aead.Overhead() {
+		return nil, false, fmt.Errorf("the stored data was shorter than the required size")
+	}
+	result, err := t.aead.Open(nil, data[:nonceSize], data[nonceSize:], context.AuthenticatedData())
+	return result, false, err
+}
+
+func (t *gcmsiv) TransformToStorage(data []byte, context value.Context) ([]byte, error) {
+	nonceSize := t.aead.NonceSize()
+	result := make([]byte, nonceSize, nonceSize+t.aead.Overhead()+len(data))
+	if _, err := io.ReadFull(rand.Reader, result); err != nil {
+		return nil, fmt.Errorf("unable to read sufficient random bytes")
+	}
+	return t.aead.Seal(result, result[:nonceSize], data, context.AuthenticatedData()), nil
+}
+
+// -----------------------------------------------------------------------------
+
+// aesgcmsiv is the RFC 8452 AEAD
+type aesgcmsiv struct {
+	block  cipher.Block
+	keyLen int
+}
+
+func newGCMSIV(key []byte) (cipher.AEAD, error) {
+	switch len(key) {
+	case 16, 32:
+	default:
+		return nil, fmt.Errorf("aes: invalid AES-GCM-SIV key size %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &aesgcmsiv{block: block, keyLen: len(key)}, nil
+}
+
+func (a *aesgcmsiv) NonceSize() int { return gcmsivNonceSize }
+
+func (a *aesgcmsiv) Overhead() int { return gcmsivTagSize }
+
+// recordKeys derives the per nonce authentication and encryption keys: RFC 8452 Section 4
+func (a *aesgcmsiv) recordKeys(nonce []byte) ([]byte, cipher.Block, error) {
+	var in, out [aes.BlockSize]byte
+	copy(in[4:], nonce)
+
+	keys := make([]byte, 0, 16+a.keyLen)
+	for i := 0; i < 2+a.keyLen/8; i++ {
+		binary.LittleEndian.PutUint32(in[:4], uint32(i))
+		a.block.Encrypt(out[:], in[:])
+		keys = append(keys, out[:8]...)
+	}
+
+	block, err := aes.NewCipher(keys[16:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return keys[:16], block, nil
+}
+
+// tag computes the expected tag of the plaintext
+func (a *aesgcmsiv) tag(authKey []byte, encBlock cipher.Block, nonce, plaintext, additionalData []byte) []byte {
+	var p polyval
+	p.init(authKey)
+	p.update(additionalData)
+	p.update(plaintext)
+
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(additionalData))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plaintext))*8)
+	p.update(lengths[:])
+
+	s := p.sum()
+	for i := range nonce {
+		s[i] ^= nonce[i]
+	}
+	s[15] &= 0x7f
+
+	tag := make([]byte, gcmsivTagSize)
+	encBlock.Encrypt(tag, s[:])
+	return tag
+}
+
+// ctr applies the AES-GCM-SIV counter mode, the first 32 bits of the counter are little endian
+func ctr(encBlock cipher.Block, tag, dst, src []byte) {
+	var counter, keystream [aes.BlockSize]byte
+	copy(counter[:], tag)
+	counter[15] |= 0x80
+
+	for len(src) > 0 {
+		encBlock.Encrypt(keystream[:], counter[:])
+		binary.LittleEndian.PutUint32(counter[:4], binary.LittleEndian.Uint32(counter[:4])+1)
+
+		n := len(src)
+		if n > aes.BlockSize {
+			n = aes.BlockSize
+		}
+		for i := 0; i < n; i++ {
+			dst[i] = src[i] ^ keystream[i]
+		}
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+func (a *aesgcmsiv) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != gcmsivNonceSize {
+		panic("aes: incorrect nonce length given to AES-GCM-SIV")
+	}
+
+	authKey, encBlock, err := a.recordKeys(nonce)
+	if err != nil {
+		panic(err)
+	}
+	tag := a.tag(authKey, encBlock, nonce, plaintext, additionalData)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+gcmsivTagSize)
+	ctr(encBlock, tag, out, plaintext)
+	copy(out[len(plaintext):], tag)
+
+	return ret
+}
+
+func (a *aesgcmsiv) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != gcmsivNonceSize {
+		panic("aes: incorrect nonce length given to AES-GCM-SIV")
+	}
+	if len(ciphertext) < gcmsivTagSize {
+		return nil, errGCMSIVOpen
+	}
+
+	authKey, encBlock, err := a.recordKeys(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := ciphertext[len(ciphertext)-gcmsivTagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-gcmsivTagSize]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	ctr(encBlock, tag, out, ciphertext)
+
+	if subtle.ConstantTimeCompare(a.tag(authKey, encBlock, nonce, out, additionalData), tag) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errGCMSIVOpen
+	}
+
+	return ret, nil
+}
+
+// sliceForAppend extends in by n bytes, returning the whole slice and the extension
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
+
+// -----------------------------------------------------------------------------
+
+// polyval is the RFC 8452 universal hash, elements are little endian 128-bit
+// polynomials over GF(2) modulo x^128 + x^127 + x^126 + x^121 + 1. Products
+// use constant-time carry-less multiplications, without key dependent table
+// lookups or branches.
+type polyval struct {
+	h [2]uint64
+	s [2]uint64
+}
+
+func (p *polyval) init(key []byte) {
+	p.h = [2]uint64{binary.LittleEndian.Uint64(key[:8]), binary.LittleEndian.Uint64(key[8:16])}
+	p.s = [2]uint64{}
+}
+
+// update absorbs data zero padded to a multiple of the block size
+func (p *polyval) update(data []byte) {
+	var block [16]byte
+	for len(data) > 0 {
+		n := copy(block[:], data)
+		for i := n; i < len(block); i++ {
+			block[i] = 0
+		}
+		data = data[n:]
+
+		p.s[0] ^= binary.LittleEndian.Uint64(block[:8])
+		p.s[1] ^= binary.LittleEndian.Uint64(block[8:])
+		p.s = dot(p.s, p.h)
+	}
+}
+
+func (p *polyval) sum() [16]byte {
+	var out [16]byte
+	binary.LittleEndian.PutUint64(out[:8], p.s[0])
+	binary.LittleEndian.PutUint64(out[8:], p.s[1])
+	return out
+}
+
+// dot returns a * b * x^-128, the POLYVAL field multiplication
+func dot(a, b [2]uint64) [2]uint64 {
+	// Karatsuba 256-bit product
+	h0, l0 := clmul(a[0], b[0])
+	h1, l1 := clmul(a[1], b[1])
+	hm, lm := clmul(a[0]^a[1], b[0]^b[1])
+	hm ^= h0 ^ h1
+	lm ^= l0 ^ l1
+
+	c0, c1, c2, c3 := l0, h0^lm, l1^hm, h1
+
+	// Montgomery reduction, each low word w is cleared by adding w * P, with
+	// P = 1 mod x^64 the product only updates the upper words
+	c1 ^= c0<<63 ^ c0<<62 ^ c0<<57
+	c2 ^= c0 ^ c0>>1 ^ c0>>2 ^ c0>>7
+	c2 ^= c1<<63 ^ c1<<62 ^ c1<<57
+	c3 ^= c1 ^ c1>>1 ^ c1>>2 ^ c1>>7
+
+	return [2]uint64{c2, c3}
+}
+
+// clmul returns the 128-bit carry-less product of x and y, the high half is
+// the low half of the bit reversed operands product
+func clmul(x, y uint64) (hi, lo uint64) {
+	lo = bmul64(x, y)
+	hi = bits.Reverse64(bmul64(bits.Reverse64(x), bits.Reverse64(y))) >> 1
+	return hi, lo
+}
+
+// bmul64 returns the low 64 bits of the carry-less product of x and y. Bits
+// are split in four interleaved sets, so that the integer multiplications
+// carries never reach the kept bits of the same set.
+func bmul64(x, y uint64) uint64 {
+	const (
+		m0 = 0x1111111111111111
+		m1 = 0x2222222222222222
+		m2 = 0x4444444444444444
+		m3 = 0x8888888888888888
+	)
+
+	x0, x1, x2, x3 := x&m0, x&m1, x&m2, x&m3
+	y0, y1, y2, y3 := y&m0, y&m1, y&m2, y&m3
+
+	z0 := (x0 * y0) ^ (x1 * y3) ^ (x2 * y2) ^ (x3 * y1)
+	z1 := (x0 * y1) ^ (x1 * y0) ^ (x2 * y3) ^ (x3 * y2)
+	z2 := (x0 * y2) ^ (x1 * y1) ^ (x2 * y0) ^ (x3 * y3)
+	z3 := (x0 * y3) ^ (x1 * y2) ^ (x2 * y1) ^ (x3 * y0)
+
+	return z0&m0 | z1&m1 | z2&m2 | z3&m3
+}
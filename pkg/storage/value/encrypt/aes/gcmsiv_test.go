@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package aes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// RFC 8452 Appendix A
+func TestPolyval(t *testing.T) {
+	var p polyval
+	p.init(mustDecodeHex(t, "25629347589242761d31f826ba4b757b"))
+	p.update(mustDecodeHex(t, "4f4f95668c83dfb6401762bb2d01a262d1a24ddd2721d006bbe45f20d3c9f362"))
+	sum := p.sum()
+	if expected := "f7a3b47b846119fae5b7866cf5e5b77e"; hex.EncodeToString(sum[:]) != expected {
+		t.Fatalf("unexpected POLYVAL: %x, expected %s", sum, expected)
+	}
+}
+
+// referenceDot computes a * b * x^-128 bit by bit
+func referenceDot(a, b [2]uint64) [2]uint64 {
+	var r [2]uint64
+	for i := 0; i < 128; i++ {
+		if (a[i/64]>>(uint(i)%64))&1 == 1 {
+			r[0] ^= b[0]
+			r[1] ^= b[1]
+		}
+		// b = b * x
+		carry := b[1] >> 63
+		b[1] = b[1]<<1 | b[0]>>63
+		b[0] <<= 1
+		if carry == 1 {
+			b[1] ^= 0xc200000000000000
+			b[0] ^= 1
+		}
+	}
+
+	// r = r * x^-128
+	for i := 0; i < 128; i++ {
+		odd := r[0] & 1
+		if odd == 1 {
+			r[0] ^= 1
+			r[1] ^= 0xc200000000000000
+		}
+		r[0] = r[0]>>1 | r[1]<<63
+		r[1] = r[1]>>1 | odd<<63
+	}
+	return r
+}
+
+func TestPolyvalDot(t *testing.T) {
+	var buf [32]byte
+	for i := 0; i < 1000; i++ {
+		if _, err := rand.Read(buf[:]); err != nil {
+			t.Fatal(err)
+		}
+		a := [2]uint64{binary.LittleEndian.Uint64(buf[0:]), binary.LittleEndian.Uint64(buf[8:])}
+		b := [2]uint64{binary.LittleEndian.Uint64(buf[16:]), binary.LittleEndian.Uint64(buf[24:])}
+		if got, expected := dot(a, b), referenceDot(a, b); got != expected {
+			t.Fatalf("dot(%x, %x) = %x, expected %x", a, b, got, expected)
+		}
+	}
+
+	// All bits set maximizes the carries of the integer multiplications
+	ones := [2]uint64{^uint64(0), ^uint64(0)}
+	if got, expected := dot(ones, ones), referenceDot(ones, ones); got != expected {
+		t.Fatalf("dot(%x, %x) = %x, expected %x", ones, ones, got, expected)
+	}
+}
+
+// RFC 8452 Appendix C
+func TestGCMSIVVectors(t *testing.T) {
+	testCases := []struct {
+		key, nonce, plaintext, aad, result string
+	}{
+		{"01000000000000000000000000000000", "030000000000000000000000", "", "", "dc20e2d83f25705bb49e439eca56de25"},
+		{"01000000000000000000000000000000", "030000000000000000000000", "0100000000000000", "", "b5d839330ac7b786578782fff6013b815b287c22493a364c"},
+		{"01000000000000000000000000000000", "030000000000000000000000", "010000000000000000000000", "", "7323ea61d05932260047d942a4978db357391a0bc4fdec8b0d106639"},
+		{"0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000", "", "", "07f5f4169bbf55a8400cd47ea6fd400f"},
+		{"0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000", "0100000000000000", "", "c2ef328e5c71c83b843122130f7364b761e0b97427e3df28"},
+	}
+	for i, test := range testCases {
+		aead, err := newGCMSIV(mustDecodeHex(t, test.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce, plaintext, aad := mustDecodeHex(t, test.nonce), mustDecodeHex(t, test.plaintext), mustDecodeHex(t, test.aad)
+
+		result := aead.Seal(nil, nonce, plaintext, aad)
+		if hex.EncodeToString(result) != test.result {
+			t.Errorf("%d: unexpected result: %x, expected %s", i, result, test.result)
+			continue
+		}
+
+		opened, err := aead.Open(nil, nonce, result, aad)
+		if err != nil || !bytes.Equal(plaintext, opened) {
+			t.Errorf("%d: unexpected plaintext: %x %v", i, opened, err)
+		}
+
+		result[0] ^= 0x01
+		if _, err := aead.Open(nil, nonce, result, aad); err == nil {
+			t.Errorf("%d: expected error on tampered value", i)
+		}
+	}
+}
+
+func TestGCMSIVTransformer(t *testing.T) {
+	transformer, err := NewGCMSIVTransformer(bytes.Repeat([]byte("a"), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewGCMSIVTransformer(bytes.Repeat([]byte("a"), 24)); err == nil {
+		t.Fatalf("expected error on AES-192 key")
+	}
+
+	context := value.DefaultContext([]byte("authenticated_data"))
+	for _, size := range []int{0, 1, 16, 17, 1024} {
+		data := bytes.Repeat([]byte("b"), size)
+		out, err := transformer.TransformToStorage(data, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from, stale, err := transformer.TransformFromStorage(out, context)
+		if err != nil || stale || !bytes.Equal(data, from) {
+			t.Fatalf("%d: unexpected data: %t %q %v", size, stale, from, err)
+		}
+		if _, _, err := transformer.TransformFromStorage(out, value.DefaultContext([]byte("other_data"))); err == nil {
+			t.Fatalf("%d: expected error with a different context", size)
+		}
+	}
+}
+
+func TestGCMSIVCounterWrap(t *testing.T) {
+	// The 32-bit counter wraps without carrying into the rest of the block
+	aead, err := newGCMSIV(bytes.Repeat([]byte("k"), 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, encBlock, err := aead.(*aesgcmsiv).recordKeys(make([]byte, 12))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag := bytes.Repeat([]byte{0xff}, 16)
+	out := make([]byte, 32)
+	ctr(encBlock, tag, out, make([]byte, 32))
+
+	second := append([]byte(nil), tag...)
+	binary.LittleEndian.PutUint32(second[:4], 0)
+	expected := make([]byte, 16)
+	encBlock.Encrypt(expected, second)
+	if !bytes.Equal(expected, out[16:]) {
+		t.Fatalf("unexpected keystream after counter wrap")
+	}
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package aes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+)
+
+var errSIVOpen = errors.New("aes: message authentication failed")
+
+// siv implements deterministic AEAD encryption of the provided values with AES-SIV (RFC 5297).
+// The authenticated data provided as part of the value.Context method must match when the same
+// value is set to and loaded from storage.
+//
+// The same value encrypted with the same key and authenticated data always gives the same cipher
+// text, so that encrypted columns can be looked up by equality. This leaks which records share a
+// value and the frequency of each value: use it only for high entropy values, never use record
+// specific authenticated data (such as the record ID) for searchable fields, and prefer a blind
+// index with randomized encryption when only lookups are needed.
+type siv struct {
+	mac   cipher.Block
+	block cipher.Block
+}
+
+// NewSIVTransformer takes the given 32, 48 or 64 bytes key and performs deterministic encryption
+// and decryption on the given data. The first half of the key authenticates, the second half
+// encrypts.
+func NewSIVTransformer(key []byte) (value.Transformer, error) {
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, fmt.Errorf("aes: invalid AES-SIV key size %d", len(key))
+	}
+
+	mac, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key[len(key)/2:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &siv{mac: mac, block: block}, nil
+}
+
+func (t *siv) TransformFromStorage(data []byte, context value.Context) ([]byte, bool, error) {
+	if len(data) < aes.BlockSize {
+		return nil, false, fmt.Errorf("the stored data was shorter than the required size")
+	}
+	v := data[:aes.BlockSize]
+
+	result := make([]byte, len(data)-aes.BlockSize)
+	sivCTR(t.block, v, result, data[aes.BlockSize:])
+
+	if subtle.ConstantTimeCompare(s2v(t.mac, context.AuthenticatedData(), result), v) != 1 {
+		return nil, false, errSIVOpen
+	}
+
+	return result, false, nil
+}
+
+func (t *siv) TransformToStorage(data []byte, context value.Context) ([]byte, error) {
+	v := s2v(t.mac, context.AuthenticatedData(), data)
+
+	result := make([]byte, aes.BlockSize+len(data))
+	copy(result, v)
+	sivCTR(t.block, v, result[aes.BlockSize:], data)
+
+	return result, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// s2v computes the synthetic IV of the plaintext with a single header: RFC 5297 Section 2.4
+func s2v(mac cipher.Block, ad, plaintext []byte) []byte {
+	var zero [aes.BlockSize]byte
+	d := cmac(mac, zero[:])
+	d = xorBlock(dbl(d), cmac(mac, ad))
+
+	var t []byte
+	if len(plaintext) >= aes.BlockSize {
+		// xorend
+		t = append([]byte{}, plaintext...)
+		offset := len(t) - aes.BlockSize
+		for i := range d {
+			t[offset+i] ^= d[i]
+		}
+	} else {
+		padded := make([]byte, aes.BlockSize)
+		copy(padded, plaintext)
+		padded[len(plaintext)] = 0x80
+		t = xorBlock(dbl(d), padded)
+	}
+
+	return cmac(mac, t)
+}
+
+// sivCTR applies AES-CTR from the synthetic IV with bits 31 and 63 cleared
+func sivCTR(block cipher.Block, v, dst, src []byte) {
+	iv := append([]byte{}, v...)
+	iv[8] &= 0x7f
+	iv[12] &= 0x7f
+	cipher.NewCTR(block, iv).XORKeyStream(dst, src)
+}
+
+// cmac is AES-CMAC: RFC 4493
+func cmac(block cipher.Block, msg []byte) []byte {
+	var zero [aes.BlockSize]byte
+	l := make([]byte, aes.BlockSize)
+	block.Encrypt(l, zero[:])
+	k1 := dbl(l)
+	k2 := dbl(k1)
+
+	// Last block is complete and xored with K1, or padded and xored with K2
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	var last []byte
+	if n > 0 && len(msg)%aes.BlockSize == 0 {
+		last = xorBlock(msg[(n-1)*aes.BlockSize:], k1)
+	} else {
+		if n == 0 {
+			n = 1
+		}
+		padded := make([]byte, aes.BlockSize)
+		rest := copy(padded, msg[(n-1)*aes.BlockSize:])
+		padded[rest] = 0x80
+		last = xorBlock(padded, k2)
+	}
+
+	x := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		x = xorBlock(x, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		block.Encrypt(x, x)
+	}
+	x = xorBlock(x, last)
+	block.Encrypt(x, x)
+
+	return x
+}
+
+// dbl multiplies by x in GF(2^128) with the big endian RFC 5297 convention
+func dbl(in []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	for i := 0; i < aes.BlockSize-1; i++ {
+		out[i] = in[i]<<1 | in[i+1]>>7
+	}
+	out[aes.BlockSize-1] = in[aes.BlockSize-1] << 1
+	if in[0]&0x80 != 0 {
+		out[aes.BlockSize-1] ^= 0x87
+	}
+	return out
+}
+
+func xorBlock(a, b []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	for i := range out {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package aes
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+)
+
+// RFC 4493 Section 4
+func TestCMAC(t *testing.T) {
+	block, err := aes.NewCipher(mustDecodeHex(t, "2b7e151628aed2a6abf7158809cf4f3c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		msg, mac string
+	}{
+		{"", "bb1d6929e95937287fa37d129b756746"},
+		{"6bc1bee22e409f96e93d7e117393172a", "070a16b46b4d4144f79bdd9dd04a287c"},
+	}
+	for i, test := range testCases {
+		if mac := cmac(block, mustDecodeHex(t, test.msg)); hex.EncodeToString(mac) != test.mac {
+			t.Errorf("%d: unexpected CMAC: %x, expected %s", i, mac, test.mac)
+		}
+	}
+}
+
+// RFC 5297 Appendix A.1
+func TestSIVVector(t *testing.T) {
+	transformer, err := NewSIVTransformer(mustDecodeHex(t, "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	context := value.DefaultContext(mustDecodeHex(t, "101112131415161718191a1b1c1d1e1f2021222324252627"))
+	plaintext := mustDecodeHex(t, "112233445566778899aabbccddee")
+
+	out, err := transformer.TransformToStorage(plaintext, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c"; hex.EncodeToString(out) != expected {
+		t.Fatalf("unexpected output: %x, expected %s", out, expected)
+	}
+
+	from, stale, err := transformer.TransformFromStorage(out, context)
+	if err != nil || stale || !bytes.Equal(plaintext, from) {
+		t.Fatalf("unexpected data: %t %x %v", stale, from, err)
+	}
+}
+
+func TestSIVTransformer(t *testing.T) {
+	transformer, err := NewSIVTransformer(bytes.Repeat([]byte("a"), 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSIVTransformer(bytes.Repeat([]byte("a"), 16)); err == nil {
+		t.Fatalf("expected error on AES-128 key")
+	}
+
+	context := value.DefaultContext([]byte("users.email"))
+	for _, size := range []int{0, 1, 15, 16, 17, 1024} {
+		data := bytes.Repeat([]byte("b"), size)
+		out, err := transformer.TransformToStorage(data, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Deterministic for a given context only
+		again, err := transformer.TransformToStorage(data, context)
+		if err != nil || !bytes.Equal(out, again) {
+			t.Fatalf("%d: encryption should be deterministic", size)
+		}
+		other, err := transformer.TransformToStorage(data, value.DefaultContext([]byte("users.plate")))
+		if err != nil || bytes.Equal(out, other) {
+			t.Fatalf("%d: encryption should depend on the context", size)
+		}
+
+		from, stale, err := transformer.TransformFromStorage(out, context)
+		if err != nil || stale || !bytes.Equal(data, from) {
+			t.Fatalf("%d: unexpected data: %t %q %v", size, stale, from, err)
+		}
+		if _, _, err := transformer.TransformFromStorage(out, value.DefaultContext([]byte("users.plate"))); err == nil {
+			t.Fatalf("%d: expected error with a different context", size)
+		}
+
+		out[len(out)-1] ^= 0x01
+		if _, _, err := transformer.TransformFromStorage(out, context); err == nil {
+			t.Fatalf("%d: expected error on tampered value", size)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+// Package awskms provides an envelope service using AWS KMS
+package awskms
+
+import (
+	"fmt"
+
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/envelope"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+type kmsService struct {
+	client  kmsiface.KMSAPI
+	keyID   string
+	version *envelope.VersionTracker
+}
+
+// New returns an envelope service encrypting DEKs with the KMS key keyID,
+// which can be a key id, an ARN or an alias.
+//
+// The key version is the key ARN: values are reported stale once the alias
+// targets another key (manual rotation). Automatic rotation of the key
+// material is transparent to KMS callers and never makes values stale.
+func New(client kmsiface.KMSAPI, keyID string, opts ...Option) (envelope.VersionedService, error) {
+	if client == nil {
+		return nil, fmt.Errorf("awskms: KMS client must not be nil")
+	}
+
+	// Default Options
+	options := &Options{
+		RefreshInterval: DefaultRefreshInterval,
+	}
+
+	// Overrides with option
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	s := &kmsService{
+		client: client,
+		keyID:  keyID,
+	}
+	s.version = envelope.NewVersionTracker(options.RefreshInterval, s.currentKey)
+
+	return s, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *kmsService) Encrypt(data []byte) ([]byte, error) {
+	out, err := s.client.Encrypt(&kms.EncryptInput{
+		KeyId:     aws.String(s.keyID),
+		Plaintext: data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("awskms: Unable to encrypt key: %v", err)
+	}
+
+	keyARN := aws.StringValue(out.KeyId)
+	s.version.Observe(keyARN)
+
+	return envelope.EncodeKeyVersion(keyARN, out.CiphertextBlob), nil
+}
+
+func (s *kmsService) Decrypt(data []byte) ([]byte, error) {
+	keyARN, ciphertext, err := envelope.DecodeKeyVersion(data)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.client.Decrypt(&kms.DecryptInput{
+		KeyId:          aws.String(keyARN),
+		CiphertextBlob: ciphertext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("awskms: Unable to decrypt key: %v", err)
+	}
+
+	return out.Plaintext, nil
+}
+
+func (s *kmsService) IsStale(data []byte) bool {
+	keyARN, _, err := envelope.DecodeKeyVersion(data)
+	if err != nil {
+		return false
+	}
+
+	return s.version.IsStale(keyARN)
+}
+
+// -----------------------------------------------------------------------------
+
+// currentKey resolves the ARN of the key targeted by keyID
+func (s *kmsService) currentKey() (string, error) {
+	out, err := s.client.DescribeKey(&kms.DescribeKeyInput{
+		KeyId: aws.String(s.keyID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("awskms: Unable to describe key: %v", err)
+	}
+	if out.KeyMetadata == nil {
+		return "", fmt.Errorf("awskms: Key %s not found", s.keyID)
+	}
+
+	return aws.StringValue(out.KeyMetadata.Arn), nil
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package awskms
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/aes"
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/envelope"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKMS resolves an alias to a key ARN, ciphertexts are prefixed by the key ARN
+type fakeKMS struct {
+	kmsiface.KMSAPI
+	sync.Mutex
+	target string
+}
+
+func (f *fakeKMS) current() string {
+	f.Lock()
+	defer f.Unlock()
+	return f.target
+}
+
+func (f *fakeKMS) rotate(target string) {
+	f.Lock()
+	defer f.Unlock()
+	f.target = target
+}
+
+func (f *fakeKMS) Encrypt(in *kms.EncryptInput) (*kms.EncryptOutput, error) {
+	target := f.current()
+	return &kms.EncryptOutput{
+		KeyId:          aws.String(target),
+		CiphertextBlob: append([]byte(target+"|"), in.Plaintext...),
+	}, nil
+}
+
+func (f *fakeKMS) Decrypt(in *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	prefix := []byte(aws.StringValue(in.KeyId) + "|")
+	if !bytes.HasPrefix(in.CiphertextBlob, prefix) {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return &kms.DecryptOutput{
+		KeyId:     in.KeyId,
+		Plaintext: in.CiphertextBlob[len(prefix):],
+	}, nil
+}
+
+func (f *fakeKMS) DescribeKey(in *kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error) {
+	return &kms.DescribeKeyOutput{
+		KeyMetadata: &kms.KeyMetadata{Arn: aws.String(f.current())},
+	}, nil
+}
+
+func TestRotation(t *testing.T) {
+	client := &fakeKMS{target: "arn:aws:kms:eu-west-1:111122223333:key/1"}
+	service, err := New(client, "alias/envelope", WithRefreshInterval(0))
+	require.NoError(t, err)
+	transformer, err := envelope.NewEnvelopeTransformer(service, 0, aes.NewGCMTransformer)
+	require.NoError(t, err)
+
+	ctx := value.DefaultContext("key")
+	stored, err := transformer.TransformToStorage([]byte("toto"), ctx)
+	require.NoError(t, err)
+	_, stale, err := transformer.TransformFromStorage(stored, ctx)
+	require.NoError(t, err)
+	require.False(t, stale)
+
+	// Alias now targets a new key
+	client.rotate("arn:aws:kms:eu-west-1:111122223333:key/2")
+	require.Eventually(t, func() bool {
+		out, stale, err := transformer.TransformFromStorage(stored, ctx)
+		require.NoError(t, err)
+		require.Equal(t, []byte("toto"), out)
+		return stale
+	}, 5*time.Second, time.Millisecond, "Value should be stale after rotation")
+
+	// Old values are still decrypted without cache
+	other, err := envelope.NewEnvelopeTransformer(service, 0, aes.NewGCMTransformer)
+	require.NoError(t, err)
+	out, _, err := other.TransformFromStorage(stored, ctx)
+	require.NoError(t, err)
+	require.Equal(t, []byte("toto"), out)
+}
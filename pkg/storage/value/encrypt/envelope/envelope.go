@@ -88,7 +88,17 @@ func (t *envelopeTransformer) TransformFromStorage(data []byte, context value.Co
 			return nil, false, err
 		}
 	}
-	return transformer.TransformFromStorage(encData, context)
+	out, stale, err := transformer.TransformFromStorage(encData, context)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// DEK encrypted with a previous KEK version must be rewrapped
+	if versioned, ok := t.envelopeService.(VersionedService); ok && !stale {
+		stale = versioned.IsStale(encKey)
+	}
+
+	return out, stale, nil
 }
 
 // TransformToStorage encrypts data to be written to disk using envelope encryption.
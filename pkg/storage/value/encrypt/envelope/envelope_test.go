@@ -21,6 +21,7 @@ import (
 
 	"github.com/scraly/go.common/pkg/storage/value"
 	aestransformer "github.com/scraly/go.common/pkg/storage/value/encrypt/aes"
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/xchacha20poly1305"
 )
 
 const (
@@ -174,6 +175,22 @@ func BenchmarkAESGCMRead(b *testing.B) {
 	benchmarkRead(b, aesGCMTransformer, 1024)
 }
 
+func BenchmarkAESGCMSIVRead(b *testing.B) {
+	aesGCMSIVTransformer, err := aestransformer.NewGCMSIVTransformer(bytes.Repeat([]byte("a"), 32))
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkRead(b, aesGCMSIVTransformer, 1024)
+}
+
+func BenchmarkXChaCha20Poly1305Read(b *testing.B) {
+	var key [32]byte
+	copy(key[:], bytes.Repeat([]byte("a"), 32))
+
+	xchachaTransformer := xchacha20poly1305.NewXChaCha20Poly1305Transformer(key)
+	benchmarkRead(b, xchachaTransformer, 1024)
+}
+
 func benchmarkRead(b *testing.B, transformer value.Transformer, valueLength int) {
 	context := value.DefaultContext([]byte(testContextText))
 	v := bytes.Repeat([]byte("0123456789abcdef"), valueLength/16)
@@ -0,0 +1,33 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package gcpkms
+
+import "time"
+
+// DefaultRefreshInterval is the default delay between key version checks
+const DefaultRefreshInterval = time.Minute
+
+// Options contains all values that are needed for the KMS service.
+type Options struct {
+	// RefreshInterval is the delay between key version checks, used to
+	// report values encrypted with a previous version as stale
+	RefreshInterval time.Duration
+}
+
+// Option configures the KMS service.
+type Option func(*Options)
+
+// WithRefreshInterval sets the delay between key version checks.
+func WithRefreshInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.RefreshInterval = interval
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+// Package gcpkms provides an envelope service using Google Cloud KMS
+package gcpkms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/envelope"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	gax "github.com/googleapis/gax-go/v2"
+)
+
+// Client is the Cloud KMS client subset used by the service, implemented by
+// the cloud.google.com/go/kms/apiv1 KeyManagementClient
+type Client interface {
+	Encrypt(context.Context, *kmspb.EncryptRequest, ...gax.CallOption) (*kmspb.EncryptResponse, error)
+	Decrypt(context.Context, *kmspb.DecryptRequest, ...gax.CallOption) (*kmspb.DecryptResponse, error)
+	GetCryptoKey(context.Context, *kmspb.GetCryptoKeyRequest, ...gax.CallOption) (*kmspb.CryptoKey, error)
+}
+
+type kmsService struct {
+	client  Client
+	name    string
+	version *envelope.VersionTracker
+}
+
+// New returns an envelope service encrypting DEKs with the crypto key name
+// (projects/*/locations/*/keyRings/*/cryptoKeys/*). Values encrypted with a
+// crypto key version other than the primary one are reported stale.
+func New(client Client, name string, opts ...Option) (envelope.VersionedService, error) {
+	if client == nil {
+		return nil, fmt.Errorf("gcpkms: KMS client must not be nil")
+	}
+
+	// Default Options
+	options := &Options{
+		RefreshInterval: DefaultRefreshInterval,
+	}
+
+	// Overrides with option
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	s := &kmsService{
+		client: client,
+		name:   name,
+	}
+	s.version = envelope.NewVersionTracker(options.RefreshInterval, s.primaryVersion)
+
+	return s, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *kmsService) Encrypt(data []byte) ([]byte, error) {
+	resp, err := s.client.Encrypt(context.Background(), &kmspb.EncryptRequest{
+		Name:      s.name,
+		Plaintext: data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gcpkms: Unable to encrypt key: %v", err)
+	}
+
+	// Response name is the primary crypto key version used
+	s.version.Observe(resp.Name)
+
+	return envelope.EncodeKeyVersion(resp.Name, resp.Ciphertext), nil
+}
+
+func (s *kmsService) Decrypt(data []byte) ([]byte, error) {
+	_, ciphertext, err := envelope.DecodeKeyVersion(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decryption selects the version from the ciphertext
+	resp, err := s.client.Decrypt(context.Background(), &kmspb.DecryptRequest{
+		Name:       s.name,
+		Ciphertext: ciphertext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gcpkms: Unable to decrypt key: %v", err)
+	}
+
+	return resp.Plaintext, nil
+}
+
+func (s *kmsService) IsStale(data []byte) bool {
+	version, _, err := envelope.DecodeKeyVersion(data)
+	if err != nil {
+		return false
+	}
+
+	return s.version.IsStale(version)
+}
+
+// -----------------------------------------------------------------------------
+
+// primaryVersion reads the crypto key primary version name
+func (s *kmsService) primaryVersion() (string, error) {
+	key, err := s.client.GetCryptoKey(context.Background(), &kmspb.GetCryptoKeyRequest{
+		Name: s.name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("gcpkms: Unable to read crypto key: %v", err)
+	}
+	if key.Primary == nil {
+		return "", fmt.Errorf("gcpkms: Crypto key %s has no primary version", s.name)
+	}
+
+	return key.Primary.Name, nil
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package gcpkms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/aes"
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/envelope"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	gax "github.com/googleapis/gax-go/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const keyName = "projects/p/locations/global/keyRings/r/cryptoKeys/envelope"
+
+// fakeKMS encrypts with the primary version, ciphertexts are prefixed by the version name
+type fakeKMS struct {
+	sync.Mutex
+	primary string
+}
+
+func (f *fakeKMS) current() string {
+	f.Lock()
+	defer f.Unlock()
+	return f.primary
+}
+
+func (f *fakeKMS) rotate(primary string) {
+	f.Lock()
+	defer f.Unlock()
+	f.primary = primary
+}
+
+func (f *fakeKMS) Encrypt(_ context.Context, req *kmspb.EncryptRequest, _ ...gax.CallOption) (*kmspb.EncryptResponse, error) {
+	primary := f.current()
+	return &kmspb.EncryptResponse{
+		Name:       primary,
+		Ciphertext: append([]byte(primary+"|"), req.Plaintext...),
+	}, nil
+}
+
+func (f *fakeKMS) Decrypt(_ context.Context, req *kmspb.DecryptRequest, _ ...gax.CallOption) (*kmspb.DecryptResponse, error) {
+	parts := bytes.SplitN(req.Ciphertext, []byte("|"), 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return &kmspb.DecryptResponse{
+		Plaintext:   parts[1],
+		UsedPrimary: string(parts[0]) == f.current(),
+	}, nil
+}
+
+func (f *fakeKMS) GetCryptoKey(_ context.Context, req *kmspb.GetCryptoKeyRequest, _ ...gax.CallOption) (*kmspb.CryptoKey, error) {
+	return &kmspb.CryptoKey{
+		Name:    req.Name,
+		Primary: &kmspb.CryptoKeyVersion{Name: f.current()},
+	}, nil
+}
+
+func TestRotation(t *testing.T) {
+	client := &fakeKMS{primary: keyName + "/cryptoKeyVersions/1"}
+	service, err := New(client, keyName, WithRefreshInterval(0))
+	require.NoError(t, err)
+	transformer, err := envelope.NewEnvelopeTransformer(service, 0, aes.NewGCMTransformer)
+	require.NoError(t, err)
+
+	ctx := value.DefaultContext("key")
+	stored, err := transformer.TransformToStorage([]byte("toto"), ctx)
+	require.NoError(t, err)
+	_, stale, err := transformer.TransformFromStorage(stored, ctx)
+	require.NoError(t, err)
+	require.False(t, stale)
+
+	// Primary version is refreshed in background
+	client.rotate(keyName + "/cryptoKeyVersions/2")
+	require.Eventually(t, func() bool {
+		out, stale, err := transformer.TransformFromStorage(stored, ctx)
+		require.NoError(t, err)
+		require.Equal(t, []byte("toto"), out)
+		return stale
+	}, 5*time.Second, time.Millisecond, "Value should be stale after rotation")
+}
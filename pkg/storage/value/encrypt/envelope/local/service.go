@@ -0,0 +1,228 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+// Package local provides a file backed envelope service, the KEK versions are
+// stored in clear so it is meant for development and tests only.
+package local
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/envelope"
+)
+
+// Service is a file backed KEK service
+type Service interface {
+	envelope.VersionedService
+	// Rotate adds a new KEK version used for all future encryptions
+	Rotate() error
+}
+
+// keyFile is the persisted KEK versions
+type keyFile struct {
+	Primary int            `json:"primary"`
+	Keys    map[int][]byte `json:"keys"`
+}
+
+type localService struct {
+	sync.RWMutex
+
+	path string
+	keys *keyFile
+}
+
+// New returns a KEK service backed by the file at path, it is created with a
+// random KEK if it does not exist.
+func New(path string) (Service, error) {
+	s := &localService{
+		path: path,
+	}
+
+	err := s.load()
+	switch {
+	case os.IsNotExist(err):
+		s.keys = &keyFile{
+			Keys: make(map[int][]byte),
+		}
+		if err := s.Rotate(); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *localService) Encrypt(data []byte) ([]byte, error) {
+	s.RLock()
+	version, kek := s.keys.Primary, s.keys.Keys[s.keys.Primary]
+	s.RUnlock()
+
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return envelope.EncodeKeyVersion(strconv.Itoa(version), aead.Seal(nonce, nonce, data, nil)), nil
+}
+
+func (s *localService) Decrypt(data []byte) ([]byte, error) {
+	encoded, ciphertext, err := envelope.DecodeKeyVersion(data)
+	if err != nil {
+		return nil, err
+	}
+	version, err := strconv.Atoi(encoded)
+	if err != nil {
+		return nil, envelope.ErrInvalidKeyVersion
+	}
+
+	kek, err := s.key(version)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("local: ciphertext too short")
+	}
+
+	return aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], nil)
+}
+
+func (s *localService) IsStale(data []byte) bool {
+	encoded, _, err := envelope.DecodeKeyVersion(data)
+	if err != nil {
+		return false
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	return encoded != strconv.Itoa(s.keys.Primary)
+}
+
+func (s *localService) Rotate() error {
+	kek := make([]byte, 32)
+	if _, err := rand.Read(kek); err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	keys := &keyFile{
+		Primary: s.keys.Primary + 1,
+		Keys:    make(map[int][]byte, len(s.keys.Keys)+1),
+	}
+	for version, k := range s.keys.Keys {
+		keys.Keys[version] = k
+	}
+	keys.Keys[keys.Primary] = kek
+
+	if err := s.save(keys); err != nil {
+		return err
+	}
+	s.keys = keys
+
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// key returns the KEK version, the file is reloaded for versions added by
+// another process
+func (s *localService) key(version int) ([]byte, error) {
+	s.RLock()
+	kek, ok := s.keys.Keys[version]
+	s.RUnlock()
+	if ok {
+		return kek, nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if kek, ok := s.keys.Keys[version]; ok {
+		return kek, nil
+	}
+
+	return nil, fmt.Errorf("local: unknown key version %d", version)
+}
+
+func (s *localService) load() error {
+	payload, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	keys := &keyFile{}
+	if err := json.Unmarshal(payload, keys); err != nil {
+		return fmt.Errorf("local: unable to decode key file: %v", err)
+	}
+	if _, ok := keys.Keys[keys.Primary]; !ok {
+		return fmt.Errorf("local: primary key version %d not found", keys.Primary)
+	}
+	s.keys = keys
+
+	return nil
+}
+
+// save replaces the key file atomically using a rename
+func (s *localService) save(keys *keyFile) error {
+	payload, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(payload); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func newGCM(kek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
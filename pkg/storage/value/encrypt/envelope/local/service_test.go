@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/aes"
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/envelope"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envelope-local")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kek.json")
+
+	service, err := New(path)
+	require.NoError(t, err)
+	transformer, err := envelope.NewEnvelopeTransformer(service, 0, aes.NewGCMTransformer)
+	require.NoError(t, err)
+
+	ctx := value.DefaultContext("key")
+	data := []byte("toto")
+
+	stored, err := transformer.TransformToStorage(data, ctx)
+	require.NoError(t, err)
+	out, stale, err := transformer.TransformFromStorage(stored, ctx)
+	require.NoError(t, err)
+	require.Equal(t, data, out)
+	require.False(t, stale, "Value should not be stale before rotation")
+
+	require.NoError(t, service.Rotate())
+	out, stale, err = transformer.TransformFromStorage(stored, ctx)
+	require.NoError(t, err)
+	require.Equal(t, data, out)
+	require.True(t, stale, "Value should be stale after rotation")
+
+	// Rewrapped value is fresh
+	stored, err = transformer.TransformToStorage(out, ctx)
+	require.NoError(t, err)
+	_, stale, err = transformer.TransformFromStorage(stored, ctx)
+	require.NoError(t, err)
+	require.False(t, stale)
+
+	// Another instance reads the persisted versions
+	reopened, err := New(path)
+	require.NoError(t, err)
+	other, err := envelope.NewEnvelopeTransformer(reopened, 0, aes.NewGCMTransformer)
+	require.NoError(t, err)
+	out, _, err = other.TransformFromStorage(stored, ctx)
+	require.NoError(t, err)
+	require.Equal(t, data, out)
+
+	// Versions rotated by another instance are reloaded
+	require.NoError(t, reopened.Rotate())
+	stored, err = other.TransformToStorage(data, ctx)
+	require.NoError(t, err)
+	out, _, err = transformer.TransformFromStorage(stored, ctx)
+	require.NoError(t, err)
+	require.Equal(t, data, out)
+
+	// Tampered key version
+	_, err = service.Decrypt(envelope.EncodeKeyVersion("42", []byte("invalid")))
+	require.Error(t, err)
+}
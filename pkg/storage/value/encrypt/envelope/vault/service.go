@@ -0,0 +1,143 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+// Package vault provides an envelope service using the Vault Transit secret engine
+package vault
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/scraly/go.common/pkg/secret"
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/envelope"
+)
+
+type transitService struct {
+	secret  *secret.Secret
+	mount   string
+	keyName string
+	version *envelope.VersionTracker
+}
+
+// New returns an envelope service encrypting DEKs with the transit key
+// keyName of the Transit engine mounted at mount.
+func New(s *secret.Secret, mount, keyName string, opts ...Option) (envelope.VersionedService, error) {
+	if s == nil || s.Client == nil {
+		return nil, fmt.Errorf("vault: Secret client must not be nil")
+	}
+
+	// Default Options
+	options := &Options{
+		RefreshInterval: DefaultRefreshInterval,
+	}
+
+	// Overrides with option
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	t := &transitService{
+		secret:  s,
+		mount:   strings.Trim(mount, "/"),
+		keyName: keyName,
+	}
+	t.version = envelope.NewVersionTracker(options.RefreshInterval, t.latestVersion)
+
+	return t, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (t *transitService) Encrypt(data []byte) ([]byte, error) {
+	s, err := t.secret.Client.Logical().Write(path.Join(t.mount, "encrypt", t.keyName), map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(data),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("vault: Unable to encrypt key: %v", err)
+	}
+	if s == nil || s.Data == nil {
+		return nil, fmt.Errorf("vault: Empty encryption response")
+	}
+
+	ciphertext, ok := s.Data["ciphertext"].(string)
+	if !ok {
+		return nil, fmt.Errorf("vault: Invalid encryption response")
+	}
+
+	// Ciphertext carries the key version used, which is the latest one
+	if version, err := ciphertextVersion(ciphertext); err == nil {
+		t.version.Observe(version)
+	}
+
+	return []byte(ciphertext), nil
+}
+
+func (t *transitService) Decrypt(data []byte) ([]byte, error) {
+	s, err := t.secret.Client.Logical().Write(path.Join(t.mount, "decrypt", t.keyName), map[string]interface{}{
+		"ciphertext": string(data),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("vault: Unable to decrypt key: %v", err)
+	}
+	if s == nil || s.Data == nil {
+		return nil, fmt.Errorf("vault: Empty decryption response")
+	}
+
+	plaintext, ok := s.Data["plaintext"].(string)
+	if !ok {
+		return nil, fmt.Errorf("vault: Invalid decryption response")
+	}
+
+	return base64.StdEncoding.DecodeString(plaintext)
+}
+
+func (t *transitService) IsStale(data []byte) bool {
+	version, err := ciphertextVersion(string(data))
+	if err != nil {
+		return false
+	}
+
+	return t.version.IsStale(version)
+}
+
+// -----------------------------------------------------------------------------
+
+// latestVersion reads the transit key latest version
+func (t *transitService) latestVersion() (string, error) {
+	s, err := t.secret.Client.Logical().Read(path.Join(t.mount, "keys", t.keyName))
+	if err != nil {
+		return "", fmt.Errorf("vault: Unable to read transit key: %v", err)
+	}
+	if s == nil || s.Data == nil {
+		return "", fmt.Errorf("vault: Transit key %s not found", t.keyName)
+	}
+
+	switch v := s.Data["latest_version"].(type) {
+	case json.Number:
+		return v.String(), nil
+	case float64:
+		return fmt.Sprintf("%d", int64(v)), nil
+	}
+
+	return "", fmt.Errorf("vault: Invalid transit key latest version")
+}
+
+// ciphertextVersion extracts N from a vault:vN:<ciphertext> value
+func ciphertextVersion(ciphertext string) (string, error) {
+	parts := strings.SplitN(ciphertext, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" || !strings.HasPrefix(parts[1], "v") {
+		return "", envelope.ErrInvalidKeyVersion
+	}
+
+	return strings.TrimPrefix(parts[1], "v"), nil
+}
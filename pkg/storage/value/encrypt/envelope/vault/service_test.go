@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package vault
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/scraly/go.common/pkg/secret"
+	"github.com/scraly/go.common/pkg/storage/value"
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/aes"
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/envelope"
+
+	"github.com/dchest/uniuri"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCiphertextVersion(t *testing.T) {
+	version, err := ciphertextVersion("vault:v12:Zm9v")
+	require.NoError(t, err)
+	require.Equal(t, "12", version)
+
+	_, err = ciphertextVersion("Zm9v")
+	require.Equal(t, envelope.ErrInvalidKeyVersion, err)
+}
+
+// TestRotation runs against the Vault server given by VAULT_ADDR and VAULT_TOKEN,
+// using the Transit engine mounted at VAULT_TRANSIT_MOUNT (defaults to transit)
+func TestRotation(t *testing.T) {
+	addr, token := os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		t.Skip("VAULT_ADDR and VAULT_TOKEN are required")
+	}
+	mount := os.Getenv("VAULT_TRANSIT_MOUNT")
+	if mount == "" {
+		mount = "transit"
+	}
+
+	s, err := secret.New(token, addr)
+	require.NoError(t, err)
+
+	keyName := "envelope-test-" + uniuri.NewLen(8)
+	_, err = s.Client.Logical().Write(path.Join(mount, "keys", keyName), nil)
+	require.NoError(t, err)
+
+	service, err := New(s, mount, keyName, WithRefreshInterval(0))
+	require.NoError(t, err)
+	transformer, err := envelope.NewEnvelopeTransformer(service, 0, aes.NewGCMTransformer)
+	require.NoError(t, err)
+
+	ctx := value.DefaultContext("key")
+	stored, err := transformer.TransformToStorage([]byte("toto"), ctx)
+	require.NoError(t, err)
+	_, stale, err := transformer.TransformFromStorage(stored, ctx)
+	require.NoError(t, err)
+	require.False(t, stale)
+
+	_, err = s.Client.Logical().Write(path.Join(mount, "keys", keyName, "rotate"), nil)
+	require.NoError(t, err)
+
+	// Latest version is refreshed in background
+	require.Eventually(t, func() bool {
+		out, stale, err := transformer.TransformFromStorage(stored, ctx)
+		require.NoError(t, err)
+		require.Equal(t, []byte("toto"), out)
+		return stale
+	}, 5*time.Second, 10*time.Millisecond, "Value should be stale after rotation")
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package envelope
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+)
+
+// VersionedService is implemented by services tracking the KEK version used to
+// encrypt each DEK, so that values can be rewrapped after a KEK rotation.
+type VersionedService interface {
+	Service
+	// IsStale returns true if the encrypted DEK was not encrypted with the
+	// current KEK version. It returns false when the version is unknown.
+	IsStale(encryptedKey []byte) bool
+}
+
+// ErrInvalidKeyVersion is raised when the encrypted DEK has no valid KEK version prefix
+var ErrInvalidKeyVersion = errors.New("envelope: invalid key version prefix")
+
+// EncodeKeyVersion prefixes the encrypted DEK with the KEK version, for
+// services whose ciphertext does not carry it.
+func EncodeKeyVersion(version string, ciphertext []byte) []byte {
+	out := make([]byte, 2, 2+len(version)+len(ciphertext))
+	binary.BigEndian.PutUint16(out, uint16(len(version)))
+	out = append(out, version...)
+	return append(out, ciphertext...)
+}
+
+// DecodeKeyVersion splits an encrypted DEK built by EncodeKeyVersion.
+func DecodeKeyVersion(data []byte) (string, []byte, error) {
+	if len(data) < 2 {
+		return "", nil, ErrInvalidKeyVersion
+	}
+	size := int(binary.BigEndian.Uint16(data[:2]))
+	if size == 0 || 2+size > len(data) {
+		return "", nil, ErrInvalidKeyVersion
+	}
+
+	return string(data[2 : 2+size]), data[2+size:], nil
+}
+
+// -----------------------------------------------------------------------------
+
+// ErrUnknownKeyVersion is raised when the current KEK version has not been fetched yet
+var ErrUnknownKeyVersion = errors.New("envelope: current key version is not known yet")
+
+const (
+	// minRetryDelay is the delay before retrying a failed version fetch, doubled on each failure
+	minRetryDelay = time.Second
+)
+
+// VersionTracker caches the current KEK version of a remote service. The
+// version is refreshed in the background, so that callers never wait for the
+// remote service.
+type VersionTracker struct {
+	sync.RWMutex
+
+	interval time.Duration
+	fetch    func() (string, error)
+
+	current    string
+	checkedAt  time.Time
+	refreshing bool
+
+	// last fetch error and the time after which the fetch is retried
+	err      error
+	failures uint
+	retryAt  time.Time
+}
+
+// NewVersionTracker returns a tracker refreshing the current version with
+// fetch when it is older than interval.
+func NewVersionTracker(interval time.Duration, fetch func() (string, error)) *VersionTracker {
+	return &VersionTracker{
+		interval: interval,
+		fetch:    fetch,
+	}
+}
+
+// Current returns the cached KEK version, and starts a background refresh
+// when it is older than interval. Until the first fetch succeeds, it returns
+// the last fetch error or ErrUnknownKeyVersion.
+func (t *VersionTracker) Current() (string, error) {
+	t.RLock()
+	current, err := t.current, t.err
+	expired := !t.refreshing && t.isExpired(time.Now())
+	t.RUnlock()
+
+	if expired {
+		t.startRefresh()
+	}
+
+	switch {
+	case current != "":
+		return current, nil
+	case err != nil:
+		return "", err
+	}
+	return "", ErrUnknownKeyVersion
+}
+
+// Observe records the version returned by an encryption, which is the current one.
+func (t *VersionTracker) Observe(version string) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.current, t.checkedAt = version, time.Now()
+	t.err, t.failures = nil, 0
+}
+
+// IsStale returns true if version is known to differ from the current one.
+func (t *VersionTracker) IsStale(version string) bool {
+	current, err := t.Current()
+	return err == nil && version != current
+}
+
+// -----------------------------------------------------------------------------
+
+// isExpired returns true if the version must be fetched, failed fetches are
+// retried with an exponential backoff. Must be called under lock.
+func (t *VersionTracker) isExpired(now time.Time) bool {
+	if t.err != nil {
+		return !now.Before(t.retryAt)
+	}
+	return t.current == "" || now.Sub(t.checkedAt) >= t.interval
+}
+
+func (t *VersionTracker) startRefresh() {
+	t.Lock()
+	if t.refreshing || !t.isExpired(time.Now()) {
+		t.Unlock()
+		return
+	}
+	t.refreshing = true
+	t.Unlock()
+
+	go t.refresh()
+}
+
+func (t *VersionTracker) refresh() {
+	version, err := t.fetch()
+
+	t.Lock()
+	defer t.Unlock()
+
+	t.refreshing = false
+	if err != nil {
+		t.err = err
+		t.retryAt = time.Now().Add(t.retryDelay())
+		t.failures++
+		return
+	}
+	t.current, t.checkedAt = version, time.Now()
+	t.err, t.failures = nil, 0
+}
+
+// retryDelay returns the backoff delay after the current failures, capped to
+// the refresh interval. Must be called under lock.
+func (t *VersionTracker) retryDelay() time.Duration {
+	delay := minRetryDelay
+	for i := uint(0); i < t.failures && delay < t.interval; i++ {
+		delay *= 2
+	}
+	if delay > t.interval && t.interval > minRetryDelay {
+		delay = t.interval
+	}
+	return delay
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package envelope
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it returns true or the test times out
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatal("condition not reached")
+}
+
+func TestVersionTrackerBackground(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	tracker := NewVersionTracker(time.Hour, func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "v2", nil
+	})
+
+	// Fetch in progress does not block readers
+	if _, err := tracker.Current(); err != ErrUnknownKeyVersion {
+		t.Fatalf("Current() error = %v, want %v", err, ErrUnknownKeyVersion)
+	}
+	if tracker.IsStale("v1") {
+		t.Fatal("unknown current version should not mark values as stale")
+	}
+	close(release)
+
+	waitFor(t, func() bool {
+		current, err := tracker.Current()
+		return err == nil && current == "v2"
+	})
+	if !tracker.IsStale("v1") || tracker.IsStale("v2") {
+		t.Fatal("staleness should be checked against the fetched version")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("fetch called %d times, want 1", n)
+	}
+
+	// Observed version is used without fetching
+	tracker.Observe("v3")
+	if current, _ := tracker.Current(); current != "v3" {
+		t.Fatalf("Current() = %s, want v3", current)
+	}
+}
+
+func TestVersionTrackerFailure(t *testing.T) {
+	var calls int32
+	errFetch := errors.New("unavailable")
+	tracker := NewVersionTracker(time.Hour, func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "", errFetch
+	})
+
+	tracker.Current()
+	waitFor(t, func() bool {
+		_, err := tracker.Current()
+		return err == errFetch
+	})
+
+	// Failure is cached until the retry delay
+	for i := 0; i < 10; i++ {
+		if tracker.IsStale("v1") {
+			t.Fatal("unknown current version should not mark values as stale")
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("fetch called %d times, want 1", n)
+	}
+
+	// Backoff grows with failures and is capped to the interval
+	tracker.Lock()
+	tracker.failures = 1
+	first := tracker.retryDelay()
+	tracker.failures = 2
+	second := tracker.retryDelay()
+	tracker.failures = 100
+	capped := tracker.retryDelay()
+	tracker.Unlock()
+	if second <= first || capped != time.Hour {
+		t.Fatalf("retry delays = %v, %v, %v", first, second, capped)
+	}
+}
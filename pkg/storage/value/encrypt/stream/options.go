@@ -0,0 +1,35 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package stream
+
+const (
+	// DefaultSegmentSize is the default plaintext size of a segment
+	DefaultSegmentSize = 64 * 1024
+	// MaxSegmentSize bounds the memory used by readers and writers
+	MaxSegmentSize = 16 * 1024 * 1024
+)
+
+// Options contains all values that are needed for stream transformers.
+type Options struct {
+	// SegmentSize is the plaintext size of each encrypted segment, readers
+	// use the size recorded in the stream header
+	SegmentSize int
+}
+
+// Option configures the stream transformer.
+type Option func(*Options)
+
+// WithSegmentSize sets the plaintext size of encrypted segments.
+func WithSegmentSize(size int) Option {
+	return func(o *Options) {
+		o.SegmentSize = size
+	}
+}
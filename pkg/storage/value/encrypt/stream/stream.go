@@ -0,0 +1,342 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+// Package stream transforms large values for storage at rest using the STREAM
+// online authenticated encryption construction (Hoang, Reyhanitabar, Rogaway,
+// Vizár, 2015) with AES-GCM or XChaCha20-Poly1305 segments.
+//
+// A stream starts with a header holding the algorithm, the segment size, a
+// random salt and a random nonce prefix. Each stream is encrypted with its own
+// key derived by HKDF-SHA256 from the master key, the salt, the header and the
+// authenticated data of the value.Context. Segment nonces are the nonce prefix
+// followed by the big endian segment counter and a last segment flag, so that
+// reordered, duplicated or truncated segments are detected.
+package stream
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	version = 1
+
+	algAESGCM            = 1
+	algXChaCha20Poly1305 = 2
+
+	saltSize = 32
+	// version, algorithm and segment size
+	fixedHeaderSize = 6
+	// segment counter and last segment flag
+	nonceSuffixSize = 5
+)
+
+var (
+	// ErrInvalidHeader is raised when the stream header is malformed or does
+	// not match the transformer
+	ErrInvalidHeader = errors.New("stream: invalid stream header")
+	// ErrTooManySegments is raised when the segment counter would overflow
+	ErrTooManySegments = errors.New("stream: too many segments")
+)
+
+type streamTransformer struct {
+	key         []byte
+	alg         byte
+	segmentSize int
+	newAEAD     func(key []byte) (cipher.AEAD, error)
+}
+
+// NewGCMTransformer returns a stream transformer using AES-GCM segments, the
+// key must be 16, 24 or 32 bytes long.
+func NewGCMTransformer(key []byte, opts ...Option) (value.StreamTransformer, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("stream: invalid AES key size %d", len(key))
+	}
+
+	return newTransformer(key, algAESGCM, func(k []byte) (cipher.AEAD, error) {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	}, opts...)
+}
+
+// NewXChaCha20Poly1305Transformer returns a stream transformer using
+// XChaCha20-Poly1305 segments, the key must be 32 bytes long.
+func NewXChaCha20Poly1305Transformer(key []byte, opts ...Option) (value.StreamTransformer, error) {
+	if len(key) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("stream: invalid XChaCha20-Poly1305 key size %d", len(key))
+	}
+
+	return newTransformer(key, algXChaCha20Poly1305, chacha20poly1305.NewX, opts...)
+}
+
+func newTransformer(key []byte, alg byte, newAEAD func([]byte) (cipher.AEAD, error), opts ...Option) (value.StreamTransformer, error) {
+	// Default Options
+	options := &Options{
+		SegmentSize: DefaultSegmentSize,
+	}
+
+	// Overrides with option
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if options.SegmentSize <= 0 || options.SegmentSize > MaxSegmentSize {
+		return nil, fmt.Errorf("stream: invalid segment size %d", options.SegmentSize)
+	}
+
+	return &streamTransformer{
+		key:         append([]byte(nil), key...),
+		alg:         alg,
+		segmentSize: options.SegmentSize,
+		newAEAD:     newAEAD,
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (t *streamTransformer) WriterToStorage(w io.Writer, context value.Context) (io.WriteCloser, error) {
+	header := make([]byte, fixedHeaderSize+saltSize)
+	header[0], header[1] = version, t.alg
+	binary.BigEndian.PutUint32(header[2:fixedHeaderSize], uint32(t.segmentSize))
+	if _, err := io.ReadFull(rand.Reader, header[fixedHeaderSize:]); err != nil {
+		return nil, err
+	}
+
+	aead, err := t.streamAEAD(header, context)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce[:len(nonce)-nonceSuffixSize]); err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(nonce[:len(nonce)-nonceSuffixSize]); err != nil {
+		return nil, err
+	}
+
+	return &writer{
+		w:     w,
+		aead:  aead,
+		nonce: nonce,
+		buf:   make([]byte, 0, t.segmentSize),
+		out:   make([]byte, 0, t.segmentSize+aead.Overhead()),
+	}, nil
+}
+
+func (t *streamTransformer) ReaderFromStorage(r io.Reader, context value.Context) (io.Reader, bool, error) {
+	header := make([]byte, fixedHeaderSize+saltSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, false, ErrInvalidHeader
+	}
+	if header[0] != version || header[1] != t.alg {
+		return nil, false, ErrInvalidHeader
+	}
+	segmentSize := int(binary.BigEndian.Uint32(header[2:fixedHeaderSize]))
+	if segmentSize <= 0 || segmentSize > MaxSegmentSize {
+		return nil, false, ErrInvalidHeader
+	}
+
+	aead, err := t.streamAEAD(header, context)
+	if err != nil {
+		return nil, false, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(r, nonce[:len(nonce)-nonceSuffixSize]); err != nil {
+		return nil, false, ErrInvalidHeader
+	}
+
+	return &reader{
+		r:     r,
+		aead:  aead,
+		nonce: nonce,
+		in:    make([]byte, segmentSize+aead.Overhead()+1),
+		plain: make([]byte, 0, segmentSize),
+	}, false, nil
+}
+
+// streamAEAD derives the stream key, binding it to the header and the context
+func (t *streamTransformer) streamAEAD(header []byte, context value.Context) (cipher.AEAD, error) {
+	info := append(append([]byte("go.common/stream"), header[:fixedHeaderSize]...), context.AuthenticatedData()...)
+	kdf := hkdf.New(sha256.New, t.key, header[fixedHeaderSize:], info)
+
+	key := make([]byte, len(t.key))
+	if _, err := io.ReadFull(kdf, key); err != nil {
+		return nil, err
+	}
+
+	return t.newAEAD(key)
+}
+
+// -----------------------------------------------------------------------------
+
+// setNonce completes the segment nonce with the counter and the last segment flag
+func setNonce(nonce []byte, counter uint32, last bool) {
+	suffix := nonce[len(nonce)-nonceSuffixSize:]
+	binary.BigEndian.PutUint32(suffix, counter)
+	suffix[4] = 0
+	if last {
+		suffix[4] = 1
+	}
+}
+
+type writer struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	nonce   []byte
+	buf     []byte
+	out     []byte
+	counter uint32
+	err     error
+}
+
+func (s *writer) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// A full segment is sealed only once more data arrives, the last
+		// segment is known on Close
+		if len(s.buf) == cap(s.buf) {
+			if s.err = s.seal(false); s.err != nil {
+				return written, s.err
+			}
+		}
+
+		n := copy(s.buf[len(s.buf):cap(s.buf)], p)
+		s.buf = s.buf[:len(s.buf)+n]
+		p = p[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+func (s *writer) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	if s.err = s.seal(true); s.err != nil {
+		return s.err
+	}
+	s.err = errors.New("stream: write to closed writer")
+
+	return nil
+}
+
+func (s *writer) seal(last bool) error {
+	if s.counter == math.MaxUint32 {
+		return ErrTooManySegments
+	}
+
+	setNonce(s.nonce, s.counter, last)
+	s.out = s.aead.Seal(s.out[:0], s.nonce, s.buf, nil)
+	if _, err := s.w.Write(s.out); err != nil {
+		return err
+	}
+
+	s.buf = s.buf[:0]
+	s.counter++
+
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+type reader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	nonce   []byte
+	in      []byte
+	pending int
+	plain   []byte
+	pos     int
+	counter uint32
+	done    bool
+	err     error
+}
+
+func (s *reader) Read(p []byte) (int, error) {
+	for s.pos == len(s.plain) {
+		switch {
+		case s.err != nil:
+			return 0, s.err
+		case s.done:
+			return 0, io.EOF
+		}
+		s.err = s.open()
+	}
+
+	n := copy(p, s.plain[s.pos:])
+	s.pos += n
+
+	return n, nil
+}
+
+func (s *reader) open() error {
+	if s.counter == math.MaxUint32 {
+		return ErrTooManySegments
+	}
+
+	// One byte more than a segment is read to know whether it is the last one
+	n, err := io.ReadFull(s.r, s.in[s.pending:])
+	total := s.pending + n
+	last := false
+	switch err {
+	case nil:
+		total--
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+
+	setNonce(s.nonce, s.counter, last)
+	plain, err := s.aead.Open(s.plain[:0], s.nonce, s.in[:total], nil)
+	if err != nil {
+		return err
+	}
+	s.plain, s.pos = plain, 0
+	s.counter++
+
+	if last {
+		s.done = true
+	} else {
+		s.in[0] = s.in[total]
+		s.pending = 1
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package stream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+)
+
+const testSegmentSize = 64
+
+func newTransformers(t *testing.T) map[string]value.StreamTransformer {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := NewGCMTransformer(key, WithSegmentSize(testSegmentSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xchacha, err := NewXChaCha20Poly1305Transformer(key, WithSegmentSize(testSegmentSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]value.StreamTransformer{"gcm": gcm, "xchacha20poly1305": xchacha}
+}
+
+func encrypt(t *testing.T, transformer value.StreamTransformer, data []byte, context value.Context) []byte {
+	out := &bytes.Buffer{}
+	w, err := transformer.WriterToStorage(out, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Odd sized writes cross segment boundaries
+	for len(data) > 0 {
+		n := 37
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func decrypt(transformer value.StreamTransformer, data []byte, context value.Context) ([]byte, error) {
+	r, _, err := transformer.ReaderFromStorage(bytes.NewReader(data), context)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func TestRoundTrip(t *testing.T) {
+	context := value.DefaultContext([]byte("authenticated_data"))
+
+	for name, transformer := range newTransformers(t) {
+		for _, size := range []int{0, 1, testSegmentSize - 1, testSegmentSize, testSegmentSize + 1, 3 * testSegmentSize, 1000} {
+			data := make([]byte, size)
+			if _, err := rand.Read(data); err != nil {
+				t.Fatal(err)
+			}
+
+			stored := encrypt(t, transformer, data, context)
+			out, err := decrypt(transformer, stored, context)
+			if err != nil {
+				t.Fatalf("%s/%d: unable to decrypt: %v", name, size, err)
+			}
+			if !bytes.Equal(data, out) {
+				t.Fatalf("%s/%d: unexpected plaintext", name, size)
+			}
+
+			if _, err := decrypt(transformer, stored, value.DefaultContext([]byte("other_data"))); err == nil {
+				t.Fatalf("%s/%d: expected error with a different context", name, size)
+			}
+		}
+	}
+}
+
+func TestTampering(t *testing.T) {
+	context := value.DefaultContext([]byte("authenticated_data"))
+	data := make([]byte, 3*testSegmentSize+10)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, transformer := range newTransformers(t) {
+		stored := encrypt(t, transformer, data, context)
+		segment := testSegmentSize + 16
+		start := len(stored) - (3*segment + 10 + 16)
+
+		flipped := append([]byte(nil), stored...)
+		flipped[start+segment+5] ^= 0x01
+
+		reordered := append([]byte(nil), stored[:start]...)
+		reordered = append(reordered, stored[start+segment:start+2*segment]...)
+		reordered = append(reordered, stored[start:start+segment]...)
+		reordered = append(reordered, stored[start+2*segment:]...)
+
+		header := append([]byte(nil), stored...)
+		header[2] ^= 0x01
+
+		cases := map[string][]byte{
+			"flipped":           flipped,
+			"reordered":         reordered,
+			"header":            header,
+			"truncated":         stored[:len(stored)-5],
+			"segment truncated": stored[:start+3*segment],
+			"last dropped":      stored[:start+2*segment],
+			"empty":             stored[:start],
+		}
+		for tampering, input := range cases {
+			out, err := decrypt(transformer, input, context)
+			if err == nil {
+				t.Errorf("%s/%s: expected error, got %d bytes", name, tampering, len(out))
+			}
+		}
+	}
+}
+
+func TestPrefixStream(t *testing.T) {
+	context := value.DefaultContext([]byte("authenticated_data"))
+	transformers := newTransformers(t)
+	data := []byte("a value streamed to storage")
+
+	previous := value.NewPrefixStreamTransformers(nil,
+		value.PrefixStreamTransformer{Prefix: []byte("enc:stream:old:"), Transformer: transformers["gcm"]},
+	)
+	current := value.NewPrefixStreamTransformers(nil,
+		value.PrefixStreamTransformer{Prefix: []byte("enc:stream:new:"), Transformer: transformers["xchacha20poly1305"]},
+		value.PrefixStreamTransformer{Prefix: []byte("enc:stream:old:"), Transformer: transformers["gcm"]},
+	)
+
+	stored := encrypt(t, previous, data, context)
+	if !bytes.HasPrefix(stored, []byte("enc:stream:old:")) {
+		t.Fatalf("unexpected prefix: %q", stored[:15])
+	}
+
+	r, stale, err := current.ReaderFromStorage(bytes.NewReader(stored), context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stale {
+		t.Fatalf("value written with a previous key should be stale")
+	}
+	out, err := ioutil.ReadAll(r)
+	if err != nil || !bytes.Equal(data, out) {
+		t.Fatalf("unexpected plaintext: %q %v", out, err)
+	}
+
+	if _, _, err := previous.ReaderFromStorage(bytes.NewReader(encrypt(t, current, data, context)), context); err == nil {
+		t.Fatalf("expected error on unknown prefix")
+	}
+}
+
+func benchmarkWrite(b *testing.B, newTransformer func([]byte, ...Option) (value.StreamTransformer, error), size int) {
+	transformer, err := newTransformer(make([]byte, 32))
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("0123456789abcdef"), size/16)
+	context := value.DefaultContext([]byte("authenticated_data"))
+
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w, err := transformer.WriterToStorage(ioutil.Discard, context)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Copy(w, bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGCMWrite_1MB(b *testing.B) {
+	benchmarkWrite(b, NewGCMTransformer, 1024*1024)
+}
+
+func BenchmarkXChaCha20Poly1305Write_1MB(b *testing.B) {
+	benchmarkWrite(b, NewXChaCha20Poly1305Transformer, 1024*1024)
+}
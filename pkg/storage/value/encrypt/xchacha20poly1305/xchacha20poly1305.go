@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+// Package xchacha20poly1305 transforms values for storage at rest using XChaCha20-Poly1305.
+package xchacha20poly1305
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// xchacha implements AEAD encryption of the provided values given a 32 byte secret key.
+// The authenticated data provided as part of the value.Context method must match when the same
+// value is set to and loaded from storage.
+//
+// The 192-bit random nonce (placed at the beginning of the cipher text) is large enough for
+// random nonce collisions to be negligible, so that unlike AES-GCM the number of values
+// encrypted with a single key is not bounded by the birthday limit of the nonce.
+type xchacha struct {
+	aead cipher.AEAD
+}
+
+// NewXChaCha20Poly1305Transformer takes the given key and performs encryption and decryption on
+// the given data.
+func NewXChaCha20Poly1305Transformer(key [chacha20poly1305.KeySize]byte) value.Transformer {
+	// Only fails on invalid key size
+	aead, _ := chacha20poly1305.NewX(key[:])
+	return &xchacha{aead: aead}
+}
+
+func (t *xchacha) TransformFromStorage(data []byte, context value.Context) ([]byte, bool, error) {
+	nonceSize := t.aead.NonceSize()
+	if len(data) < nonceSize+t.aead.Overhead() {
+		return nil, false, fmt.Errorf("the stored data was shorter than the required size")
+	}
+	result, err := t.aead.Open(nil, data[:nonceSize], data[nonceSize:], context.AuthenticatedData())
+	return result, false, err
+}
+
+func (t *xchacha) TransformToStorage(data []byte, context value.Context) ([]byte, error) {
+	nonceSize := t.aead.NonceSize()
+	result := make([]byte, nonceSize, nonceSize+t.aead.Overhead()+len(data))
+	if _, err := io.ReadFull(rand.Reader, result); err != nil {
+		return nil, fmt.Errorf("unable to read sufficient random bytes")
+	}
+	return t.aead.Seal(result, result[:nonceSize], data, context.AuthenticatedData()), nil
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+// Package fields encrypts struct fields tagged with `encrypt:"true"` through a
+// value.Transformer. Each field is authenticated with its path and the record
+// identifier, so that encrypted values cannot be swapped between fields or
+// records.
+//
+//	type Driver struct {
+//		ID      string
+//		Email   string `encrypt:"true"`
+//		License struct {
+//			Number []byte `encrypt:"true"`
+//		}
+//	}
+//
+// Tagged fields must be string, *string or []byte. Nested structs and pointers
+// to structs are walked, string fields hold the base64 encoded cipher text.
+package fields
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+)
+
+// TagName is the struct tag marking encrypted fields
+const TagName = "encrypt"
+
+var (
+	// ErrNotStructPointer is raised when the record is not a pointer to a struct
+	ErrNotStructPointer = errors.New("fields: record must be a non nil pointer to a struct")
+	// ErrUnsupportedType is raised when a tagged field is not a string or a byte slice
+	ErrUnsupportedType = errors.New("fields: encrypted field must be string, *string or []byte")
+)
+
+// Codec encrypts and decrypts tagged fields in place
+type Codec interface {
+	// Encrypt replaces tagged fields of the record with their cipher text,
+	// the record is left unchanged on error
+	Encrypt(record interface{}, recordID string) error
+	// Decrypt replaces tagged fields of the record with their plain text,
+	// stale is true if any field should be encrypted again. The record is left
+	// unchanged on error.
+	Decrypt(record interface{}, recordID string) (stale bool, err error)
+	// Field binds a *string, **string or *[]byte to database/sql with the encryption
+	// context of the field
+	Field(target interface{}, recordID, path string) *Field
+}
+
+// Context returns the authenticated data of a field
+func Context(recordID, path string) value.Context {
+	return value.DefaultContext(fmt.Sprintf("%s\x00%s", path, recordID))
+}
+
+// -----------------------------------------------------------------------------
+
+type codec struct {
+	transformer value.Transformer
+}
+
+// New returns a codec encrypting fields with the transformer
+func New(transformer value.Transformer) Codec {
+	return &codec{
+		transformer: transformer,
+	}
+}
+
+func (c *codec) Encrypt(record interface{}, recordID string) error {
+	var updates []update
+	err := walk(record, func(field reflect.Value, path string) error {
+		plain, ok, err := get(field)
+		if err != nil || !ok {
+			return err
+		}
+
+		out, err := c.transformer.TransformToStorage(plain, Context(recordID, path))
+		if err != nil {
+			return fmt.Errorf("fields: unable to encrypt %s: %v", path, err)
+		}
+
+		updates = append(updates, update{field: field, data: out})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	apply(updates, true)
+	return nil
+}
+
+func (c *codec) Decrypt(record interface{}, recordID string) (bool, error) {
+	var updates []update
+	stale := false
+	err := walk(record, func(field reflect.Value, path string) error {
+		data, ok, err := get(field)
+		if err != nil || !ok {
+			return err
+		}
+		if field.Kind() != reflect.Slice {
+			if data, err = base64.StdEncoding.DecodeString(string(data)); err != nil {
+				return fmt.Errorf("fields: malformed %s: %v", path, err)
+			}
+		}
+
+		out, s, err := c.transformer.TransformFromStorage(data, Context(recordID, path))
+		if err != nil {
+			return fmt.Errorf("fields: unable to decrypt %s: %v", path, err)
+		}
+		stale = stale || s
+
+		updates = append(updates, update{field: field, data: out})
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	apply(updates, false)
+	return stale, nil
+}
+
+func (c *codec) Field(target interface{}, recordID, path string) *Field {
+	return &Field{
+		transformer: c.transformer,
+		context:     Context(recordID, path),
+		target:      target,
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// walk calls fn for each tagged field of the record
+func walk(record interface{}, fn func(reflect.Value, string) error) error {
+	v := reflect.ValueOf(record)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ErrNotStructPointer
+	}
+	return walkStruct(v.Elem(), "", fn)
+}
+
+func walkStruct(v reflect.Value, prefix string, fn func(reflect.Value, string) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		// Unexported fields can not be set
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		path := sf.Name
+		if prefix != "" {
+			path = prefix + "." + sf.Name
+		}
+		field := v.Field(i)
+
+		if sf.Tag.Get(TagName) == "true" {
+			if err := fn(field, path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch {
+		case field.Kind() == reflect.Struct:
+			if err := walkStruct(field, path, fn); err != nil {
+				return err
+			}
+		case field.Kind() == reflect.Ptr && !field.IsNil() && field.Elem().Kind() == reflect.Struct:
+			if err := walkStruct(field.Elem(), path, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// get returns the field content, ok is false for nil pointers and slices
+func get(field reflect.Value) ([]byte, bool, error) {
+	switch {
+	case field.Kind() == reflect.String:
+		return []byte(field.String()), true, nil
+	case field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.String:
+		if field.IsNil() {
+			return nil, false, nil
+		}
+		return []byte(field.Elem().String()), true, nil
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8:
+		return field.Bytes(), !field.IsNil(), nil
+	}
+	return nil, false, ErrUnsupportedType
+}
+
+// update is a transformed field content, fields are only set once all of them
+// are transformed
+type update struct {
+	field reflect.Value
+	data  []byte
+}
+
+func apply(updates []update, encrypted bool) {
+	for _, u := range updates {
+		set(u.field, u.data, encrypted)
+	}
+}
+
+// set stores data in the field, string fields hold base64 encoded cipher text
+func set(field reflect.Value, data []byte, encrypted bool) {
+	s := string(data)
+	if encrypted {
+		s = base64.StdEncoding.EncodeToString(data)
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Ptr:
+		field.Set(reflect.New(field.Type().Elem()))
+		field.Elem().SetString(s)
+	case reflect.Slice:
+		field.SetBytes(data)
+	}
+}
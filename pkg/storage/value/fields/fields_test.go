@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package fields
+
+import (
+	"bytes"
+	"crypto/aes"
+	"errors"
+	"testing"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+	aestransformer "github.com/scraly/go.common/pkg/storage/value/encrypt/aes"
+)
+
+type license struct {
+	Number []byte `encrypt:"true"`
+	Class  string
+}
+
+type driverRecord struct {
+	ID       string
+	Email    string  `encrypt:"true"`
+	Phone    *string `encrypt:"true"`
+	Nickname *string `encrypt:"true"`
+	License  license
+	Previous *license
+	secret   string
+}
+
+func newTestCodec(t *testing.T) Codec {
+	block, err := aes.NewCipher(bytes.Repeat([]byte("a"), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(aestransformer.NewGCMTransformer(block))
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	codec := newTestCodec(t)
+	phone := "+33600000000"
+	record := &driverRecord{
+		ID:       "d-1",
+		Email:    "john@example.com",
+		Phone:    &phone,
+		License:  license{Number: []byte("AB-123-CD"), Class: "B"},
+		Previous: &license{Number: []byte("XY-987-ZT"), Class: "A"},
+		secret:   "untouched",
+	}
+
+	if err := codec.Encrypt(record, record.ID); err != nil {
+		t.Fatal(err)
+	}
+	if record.Email == "john@example.com" || *record.Phone == phone || bytes.Equal(record.License.Number, []byte("AB-123-CD")) || bytes.Equal(record.Previous.Number, []byte("XY-987-ZT")) {
+		t.Fatalf("tagged fields should be encrypted: %+v", record)
+	}
+	if phone != "+33600000000" {
+		t.Fatalf("pointed value should not be modified")
+	}
+	if record.ID != "d-1" || record.License.Class != "B" || record.Nickname != nil || record.secret != "untouched" {
+		t.Fatalf("untagged fields should not be modified: %+v", record)
+	}
+
+	// Cipher texts are bound to the record
+	copied := *record
+	if _, err := codec.Decrypt(&copied, "d-2"); err == nil {
+		t.Fatalf("expected error with a different record identifier")
+	}
+
+	// Cipher texts are bound to the field
+	swapped := *record
+	swapped.License.Number, swapped.Previous = record.Previous.Number, &license{Number: record.License.Number}
+	if _, err := codec.Decrypt(&swapped, record.ID); err == nil {
+		t.Fatalf("expected error with swapped fields")
+	}
+
+	stale, err := codec.Decrypt(record, record.ID)
+	if err != nil || stale {
+		t.Fatalf("unexpected result: %t %v", stale, err)
+	}
+	if record.Email != "john@example.com" || *record.Phone != phone || string(record.License.Number) != "AB-123-CD" || string(record.Previous.Number) != "XY-987-ZT" {
+		t.Fatalf("unexpected decrypted record: %+v", record)
+	}
+}
+
+func TestInvalidRecords(t *testing.T) {
+	codec := newTestCodec(t)
+
+	if err := codec.Encrypt(driverRecord{}, "d-1"); err != ErrNotStructPointer {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	invalid := &struct {
+		Age int `encrypt:"true"`
+	}{Age: 42}
+	if err := codec.Encrypt(invalid, "d-1"); err != ErrUnsupportedType {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestField(t *testing.T) {
+	codec := newTestCodec(t)
+
+	email := "john@example.com"
+	stored, err := codec.Field(&email, "d-1", "Email").Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var scanned string
+	field := codec.Field(&scanned, "d-1", "Email")
+	if err := field.Scan(stored); err != nil || scanned != email || field.Stale {
+		t.Fatalf("unexpected scan: %q %t %v", scanned, field.Stale, err)
+	}
+	if err := codec.Field(&scanned, "d-1", "Phone").Scan(stored); err == nil {
+		t.Fatalf("expected error with a different field")
+	}
+
+	// Record encrypted by the codec can be scanned from the column
+	record := &driverRecord{ID: "d-1", License: license{Number: []byte("AB-123-CD")}}
+	if err := codec.Encrypt(record, record.ID); err != nil {
+		t.Fatal(err)
+	}
+	var number []byte
+	if err := codec.Field(&number, "d-1", "License.Number").Scan(record.License.Number); err != nil || string(number) != "AB-123-CD" {
+		t.Fatalf("unexpected scan: %q %v", number, err)
+	}
+
+	// NULL
+	var phone *string
+	if stored, err := codec.Field(&phone, "d-1", "Phone").Value(); err != nil || stored != nil {
+		t.Fatalf("unexpected value: %v %v", stored, err)
+	}
+	if err := codec.Field(&phone, "d-1", "Phone").Scan(nil); err != nil || phone != nil {
+		t.Fatalf("unexpected scan: %v %v", phone, err)
+	}
+}
+
+func TestStale(t *testing.T) {
+	block, err := aes.NewCipher(bytes.Repeat([]byte("b"), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := newTestCodec(t)
+	current := New(value.NewPrefixTransformers(nil,
+		value.PrefixTransformer{Prefix: []byte("k2:"), Transformer: aestransformer.NewGCMTransformer(block)},
+		value.PrefixTransformer{Prefix: []byte(""), Transformer: previous.(*codec).transformer},
+	))
+
+	record := &driverRecord{ID: "d-1", Email: "john@example.com"}
+	if err := previous.Encrypt(record, record.ID); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := current.Decrypt(record, record.ID)
+	if err != nil || !stale || record.Email != "john@example.com" {
+		t.Fatalf("unexpected result: %t %v", stale, err)
+	}
+}
+
+// failingTransformer fails on the given field path
+type failingTransformer struct {
+	value.Transformer
+	path string
+}
+
+func (t *failingTransformer) TransformToStorage(data []byte, context value.Context) ([]byte, error) {
+	if bytes.HasPrefix(context.AuthenticatedData(), []byte(t.path+"\x00")) {
+		return nil, errors.New("unavailable")
+	}
+	return t.Transformer.TransformToStorage(data, context)
+}
+
+func TestPartialFailure(t *testing.T) {
+	base := newTestCodec(t)
+	phone := "+33600000000"
+	record := &driverRecord{
+		ID:       "d-1",
+		Email:    "john@example.com",
+		Phone:    &phone,
+		License:  license{Number: []byte("AB-123-CD")},
+		Previous: &license{Number: []byte("XY-987-ZT")},
+	}
+	expected := *record
+
+	// Last field fails, previous ones are not encrypted
+	failing := New(&failingTransformer{Transformer: base.(*codec).transformer, path: "Previous.Number"})
+	if err := failing.Encrypt(record, record.ID); err == nil {
+		t.Fatalf("expected error from the transformer")
+	}
+	if record.Email != expected.Email || record.Phone != expected.Phone || string(record.License.Number) != "AB-123-CD" || string(record.Previous.Number) != "XY-987-ZT" {
+		t.Fatalf("record should be unchanged: %+v", record)
+	}
+
+	// Last field is corrupted, previous ones are not decrypted
+	if err := base.Encrypt(record, record.ID); err != nil {
+		t.Fatal(err)
+	}
+	encrypted := *record
+	record.Previous.Number = []byte("corrupted")
+	if _, err := base.Decrypt(record, record.ID); err == nil {
+		t.Fatalf("expected error with a corrupted field")
+	}
+	if record.Email != encrypted.Email || record.Phone != encrypted.Phone || !bytes.Equal(record.License.Number, encrypted.License.Number) {
+		t.Fatalf("record should be unchanged: %+v", record)
+	}
+}
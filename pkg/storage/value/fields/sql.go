@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package fields
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+)
+
+// Field encrypts a *string, **string or *[]byte as a database/sql argument, and decrypts
+// the column into it when scanned. The column holds the raw cipher text.
+//
+//	db.Exec("UPDATE drivers SET email = $1 WHERE id = $2", codec.Field(&d.Email, d.ID, "Email"), d.ID)
+//	row.Scan(codec.Field(&d.Email, d.ID, "Email"))
+type Field struct {
+	transformer value.Transformer
+	context     value.Context
+	target      interface{}
+
+	// Stale is set by Scan when the value should be encrypted again
+	Stale bool
+}
+
+var (
+	_ driver.Valuer = &Field{}
+	_ sql.Scanner   = &Field{}
+)
+
+// Value encrypts the target, a nil *string is stored as NULL
+func (f *Field) Value() (driver.Value, error) {
+	var plain []byte
+	switch target := f.target.(type) {
+	case *string:
+		plain = []byte(*target)
+	case **string:
+		if *target == nil {
+			return nil, nil
+		}
+		plain = []byte(**target)
+	case *[]byte:
+		if *target == nil {
+			return nil, nil
+		}
+		plain = *target
+	default:
+		return nil, ErrUnsupportedType
+	}
+
+	out, err := f.transformer.TransformToStorage(plain, f.context)
+	if err != nil {
+		return nil, fmt.Errorf("fields: unable to encrypt value: %v", err)
+	}
+	return out, nil
+}
+
+// Scan decrypts the column into the target, NULL gives the zero value
+func (f *Field) Scan(src interface{}) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		return f.assign(nil, true)
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("fields: unable to scan %T as encrypted value", src)
+	}
+
+	out, stale, err := f.transformer.TransformFromStorage(data, f.context)
+	if err != nil {
+		return fmt.Errorf("fields: unable to decrypt value: %v", err)
+	}
+	f.Stale = stale
+
+	return f.assign(out, false)
+}
+
+func (f *Field) assign(data []byte, null bool) error {
+	switch target := f.target.(type) {
+	case *string:
+		*target = string(data)
+	case **string:
+		if null {
+			*target = nil
+			return nil
+		}
+		s := string(data)
+		*target = &s
+	case *[]byte:
+		if null {
+			*target = nil
+			return nil
+		}
+		// Scanned buffers are reused by the driver
+		*target = append([]byte{}, data...)
+	default:
+		return ErrUnsupportedType
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+// Package rewrap re-encrypts stale values of a data source with the current
+// transformer, typically after a key rotation.
+package rewrap
+
+import (
+	"context"
+	"errors"
+
+	"github.com/scraly/go.common/pkg/storage/value"
+)
+
+var (
+	// ErrConflict is raised by Source.Update when the value changed since it was read
+	ErrConflict = errors.New("rewrap: Value changed since it was read")
+	// ErrInvalidBatchSize is raised when the batch size is not positive
+	ErrInvalidBatchSize = errors.New("rewrap: Batch size must be positive")
+)
+
+// Record is a stored value
+type Record struct {
+	Key   string
+	Value []byte
+	// Context is the transformer context, defaults to the key
+	Context value.Context
+}
+
+// Source is the iterator over a data source: SQL table, cache keyspace,
+// keystore backend...
+type Source interface {
+	// List returns up to limit records whose key is greater than after, in
+	// key order. An empty result ends the iteration.
+	List(ctx context.Context, after string, limit int) ([]*Record, error)
+	// Update replaces the record value, it must return ErrConflict if the
+	// stored value is not record.Value anymore
+	Update(ctx context.Context, record *Record, value []byte) error
+}
+
+// Checkpointer persists the job cursor, so that an interrupted job resumes
+// where it stopped
+type Checkpointer interface {
+	// Load returns the last processed key, empty if the job must start over
+	Load(ctx context.Context, name string) (string, error)
+	// Save records the last processed key
+	Save(ctx context.Context, name, cursor string) error
+}
+
+// Progress reports a job run
+type Progress struct {
+	Scanned   int64
+	Rewrapped int64
+	Conflicts int64
+	Failed    int64
+	// Cursor is the last processed key
+	Cursor string
+}
+
+// Job rewrites stale values of a source
+type Job interface {
+	// Run processes the source from the last checkpoint until its end or
+	// context cancellation. The checkpoint is reset once the end is reached.
+	Run(ctx context.Context) (*Progress, error)
+}
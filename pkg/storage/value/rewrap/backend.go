@@ -0,0 +1,211 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package rewrap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/scraly/go.common/pkg/keystore/backends"
+	"github.com/scraly/go.common/pkg/storage/value"
+)
+
+type backendSource struct {
+	store  backends.AtomicBackend
+	prefix string
+}
+
+// BackendSource iterates the values stored under prefix in a keystore backend,
+// record keys are the full backend keys.
+func BackendSource(backend backends.AtomicBackend, prefix string) Source {
+	return &backendSource{
+		store:  backend,
+		prefix: strings.Trim(prefix, "/"),
+	}
+}
+
+func (s *backendSource) List(ctx context.Context, after string, limit int) ([]*Record, error) {
+	return s.list(ctx, after, limit, func(key string, value []byte) (*Record, error) {
+		return &Record{Key: key, Value: value}, nil
+	})
+}
+
+func (s *backendSource) Update(ctx context.Context, record *Record, value []byte) error {
+	return s.swap(ctx, record.Key, record.Value, value)
+}
+
+// list returns up to limit records built from the backend values, values
+// without record are skipped
+func (s *backendSource) list(ctx context.Context, after string, limit int, record func(key string, value []byte) (*Record, error)) ([]*Record, error) {
+	names, err := s.store.List(ctx, s.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		if key := fmt.Sprintf("%s/%s", s.prefix, name); key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var result []*Record
+	for _, key := range keys {
+		if len(result) == limit {
+			break
+		}
+
+		value, err := s.store.Get(ctx, key)
+		switch {
+		case err == backends.ErrKeyNotFound:
+			// Deleted since listed
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		r, err := record(key, value)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			result = append(result, r)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *backendSource) swap(ctx context.Context, key string, old, value []byte) error {
+	swapped, err := s.store.CompareAndSwap(ctx, key, old, value)
+	switch {
+	case err != nil:
+		return err
+	case !swapped:
+		return ErrConflict
+	}
+
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+type keystoreSource struct {
+	backendSource
+}
+
+// KeystoreSource iterates the private keys stored by a keystore in its backend,
+// record values are the transformed private JWK of each key holder. Use the
+// keystore private keys transformer to rewrap them, keys stored without private
+// part are skipped.
+func KeystoreSource(backend backends.AtomicBackend) Source {
+	return &keystoreSource{
+		backendSource: backendSource{
+			store:  backend,
+			prefix: "jwk",
+		},
+	}
+}
+
+func (s *keystoreSource) List(ctx context.Context, after string, limit int) ([]*Record, error) {
+	return s.list(ctx, after, limit, func(key string, holder []byte) (*Record, error) {
+		_, private, err := decodeHolder(holder)
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("rewrap: Unable to decode key holder %s: %v", key, err)
+		case len(private) == 0:
+			return nil, nil
+		}
+
+		// Same context as the keystore
+		return &Record{Key: key, Value: private, Context: value.DefaultContext(key)}, nil
+	})
+}
+
+func (s *keystoreSource) Update(ctx context.Context, record *Record, private []byte) error {
+	holder, err := s.store.Get(ctx, record.Key)
+	switch {
+	case err == backends.ErrKeyNotFound:
+		return ErrConflict
+	case err != nil:
+		return err
+	}
+
+	fields, current, err := decodeHolder(holder)
+	if err != nil {
+		return fmt.Errorf("rewrap: Unable to decode key holder %s: %v", record.Key, err)
+	}
+	if !bytes.Equal(current, record.Value) {
+		return ErrConflict
+	}
+
+	// Other holder fields are kept as is
+	if fields["private"], err = json.Marshal(private); err != nil {
+		return err
+	}
+	updated, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	return s.swap(ctx, record.Key, holder, updated)
+}
+
+// decodeHolder returns the key holder fields and its transformed private JWK
+func decodeHolder(holder []byte) (map[string]json.RawMessage, []byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(holder, &fields); err != nil {
+		return nil, nil, err
+	}
+
+	var private []byte
+	if raw, ok := fields["private"]; ok {
+		if err := json.Unmarshal(raw, &private); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return fields, private, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type backendCheckpointer struct {
+	store backends.Backend
+}
+
+// BackendCheckpointer persists job cursors under rewrap/<name> in a keystore backend.
+func BackendCheckpointer(backend backends.Backend) Checkpointer {
+	return &backendCheckpointer{
+		store: backend,
+	}
+}
+
+func (c *backendCheckpointer) Load(ctx context.Context, name string) (string, error) {
+	cursor, err := c.store.Get(ctx, fmt.Sprintf("rewrap/%s", name))
+	switch {
+	case err == backends.ErrKeyNotFound:
+		return "", nil
+	case err != nil:
+		return "", err
+	}
+
+	return string(bytes.TrimSpace(cursor)), nil
+}
+
+func (c *backendCheckpointer) Save(ctx context.Context, name, cursor string) error {
+	return c.store.Set(ctx, fmt.Sprintf("rewrap/%s", name), []byte(cursor))
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package rewrap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/scraly/go.common/pkg/log"
+	"github.com/scraly/go.common/pkg/storage/value"
+
+	"go.uber.org/zap"
+)
+
+type rewrapJob struct {
+	name        string
+	source      Source
+	transformer value.Transformer
+	dopts       *Options
+}
+
+// New returns a job rewriting stale values of the source with the transformer,
+// name identifies the job checkpoint and metrics.
+func New(name string, source Source, transformer value.Transformer, opts ...Option) (Job, error) {
+	// Default Options
+	options := &Options{
+		BatchSize: DefaultBatchSize,
+	}
+
+	// Overrides with option
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	// A batch must advance the cursor
+	if options.BatchSize <= 0 {
+		return nil, ErrInvalidBatchSize
+	}
+
+	return &rewrapJob{
+		name:        name,
+		source:      source,
+		transformer: transformer,
+		dopts:       options,
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (j *rewrapJob) Run(ctx context.Context) (*Progress, error) {
+	progress := &Progress{}
+
+	// Resume from checkpoint
+	if j.dopts.Checkpointer != nil {
+		cursor, err := j.dopts.Checkpointer.Load(ctx, j.name)
+		if err != nil {
+			return progress, fmt.Errorf("rewrap: Unable to load checkpoint: %v", err)
+		}
+		progress.Cursor = cursor
+	}
+
+	for {
+		records, err := j.source.List(ctx, progress.Cursor, j.dopts.BatchSize)
+		if err != nil {
+			return progress, fmt.Errorf("rewrap: Unable to list records: %v", err)
+		}
+		if len(records) == 0 {
+			break
+		}
+
+		for _, record := range records {
+			if err := j.wait(ctx); err != nil {
+				return progress, err
+			}
+			j.process(ctx, record, progress)
+			progress.Cursor = record.Key
+		}
+
+		if err := j.checkpoint(ctx, progress.Cursor); err != nil {
+			return progress, err
+		}
+	}
+
+	// Next run starts over
+	if err := j.checkpoint(ctx, ""); err != nil {
+		return progress, err
+	}
+
+	return progress, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// process rewrites the record if stale, failures are counted and skipped
+func (j *rewrapJob) process(ctx context.Context, record *Record, progress *Progress) {
+	progress.Scanned++
+	recordsScanned.WithLabelValues(j.name).Inc()
+
+	tctx := record.Context
+	if tctx == nil {
+		tctx = value.DefaultContext(record.Key)
+	}
+
+	data, stale, err := j.transformer.TransformFromStorage(record.Value, tctx)
+	if err == nil && !stale {
+		return
+	}
+
+	var out []byte
+	if err == nil {
+		out, err = j.transformer.TransformToStorage(data, tctx)
+	}
+	if err == nil {
+		err = j.source.Update(ctx, record, out)
+	}
+
+	switch {
+	case err == nil:
+		progress.Rewrapped++
+		recordsRewrapped.WithLabelValues(j.name).Inc()
+	case err == ErrConflict:
+		// Concurrent writer already used the current transformer
+		progress.Conflicts++
+		recordsConflicts.WithLabelValues(j.name).Inc()
+	default:
+		progress.Failed++
+		recordsFailed.WithLabelValues(j.name).Inc()
+		log.For(ctx).Warn("Unable to rewrap record", zap.String("job", j.name), zap.String("key", record.Key), zap.Error(err))
+	}
+}
+
+// wait blocks until the limiter allows the next record
+func (j *rewrapJob) wait(ctx context.Context) error {
+	if j.dopts.Limiter == nil {
+		return ctx.Err()
+	}
+
+	for {
+		res, err := j.dopts.Limiter.Allow(ctx, j.name)
+		if err != nil {
+			return fmt.Errorf("rewrap: Unable to check rate limit: %v", err)
+		}
+		if res.Allowed {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(res.RetryAfter):
+		}
+	}
+}
+
+func (j *rewrapJob) checkpoint(ctx context.Context, cursor string) error {
+	if j.dopts.Checkpointer == nil {
+		return nil
+	}
+
+	if err := j.dopts.Checkpointer.Save(ctx, j.name, cursor); err != nil {
+		return fmt.Errorf("rewrap: Unable to save checkpoint: %v", err)
+	}
+
+	return nil
+}
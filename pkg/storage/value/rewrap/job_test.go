@@ -0,0 +1,218 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package rewrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/scraly/go.common/pkg/keystore"
+	"github.com/scraly/go.common/pkg/keystore/backends"
+	"github.com/scraly/go.common/pkg/keystore/backends/inmemory"
+	"github.com/scraly/go.common/pkg/keystore/key"
+	"github.com/scraly/go.common/pkg/ratelimit"
+	"github.com/scraly/go.common/pkg/storage/value"
+	"github.com/scraly/go.common/pkg/storage/value/encrypt/secretbox"
+
+	"github.com/stretchr/testify/require"
+)
+
+func transformers() (old, current value.Transformer) {
+	var key1, key2 [32]byte
+	copy(key1[:], "0123456789abcdef0123456789abcdef")
+	copy(key2[:], "fedcba9876543210fedcba9876543210")
+
+	k1 := value.PrefixTransformer{Prefix: []byte("k1:"), Transformer: secretbox.NewSecretboxTransformer(key1)}
+	k2 := value.PrefixTransformer{Prefix: []byte("k2:"), Transformer: secretbox.NewSecretboxTransformer(key2)}
+
+	return value.NewPrefixTransformers(nil, k1), value.NewPrefixTransformers(nil, k2, k1)
+}
+
+func setup(t *testing.T, count int) (backends.AtomicBackend, value.Transformer) {
+	ctx := context.Background()
+	backend, err := inmemory.New()
+	require.NoError(t, err)
+
+	old, current := transformers()
+	for i := 0; i < count; i++ {
+		key := fmt.Sprintf("secrets/%03d", i)
+		out, err := old.TransformToStorage([]byte(fmt.Sprintf("value-%d", i)), value.DefaultContext(key))
+		require.NoError(t, err)
+		require.NoError(t, backend.Set(ctx, key, out))
+	}
+
+	return backend.(backends.AtomicBackend), current
+}
+
+// failingSource fails after the given number of List calls
+type failingSource struct {
+	Source
+	lists int
+}
+
+func (s *failingSource) List(ctx context.Context, after string, limit int) ([]*Record, error) {
+	if s.lists == 0 {
+		return nil, errors.New("unavailable")
+	}
+	s.lists--
+	return s.Source.List(ctx, after, limit)
+}
+
+// conflictSource simulates concurrent writers
+type conflictSource struct {
+	Source
+}
+
+func (s *conflictSource) Update(context.Context, *Record, []byte) error {
+	return ErrConflict
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	backend, current := setup(t, 25)
+
+	limiter, err := ratelimit.NewTokenBucket(ratelimit.PerSecond(1000))
+	require.NoError(t, err)
+
+	job, err := New("test", BackendSource(backend, "secrets"), current, WithBatchSize(10), WithLimiter(limiter))
+	require.NoError(t, err)
+	progress, err := job.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &Progress{Scanned: 25, Rewrapped: 25, Cursor: "secrets/024"}, progress)
+
+	// All values use the current key
+	for i := 0; i < 25; i++ {
+		key := fmt.Sprintf("secrets/%03d", i)
+		stored, err := backend.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "k2:", string(stored[:3]))
+
+		out, stale, err := current.TransformFromStorage(stored, value.DefaultContext(key))
+		require.NoError(t, err)
+		require.False(t, stale)
+		require.Equal(t, fmt.Sprintf("value-%d", i), string(out))
+	}
+
+	// Nothing left to do
+	progress, err = job.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(25), progress.Scanned)
+	require.Equal(t, int64(0), progress.Rewrapped)
+}
+
+func TestResume(t *testing.T) {
+	ctx := context.Background()
+	backend, current := setup(t, 25)
+	checkpointer := BackendCheckpointer(backend)
+
+	// Interrupted after the first batch
+	source := &failingSource{Source: BackendSource(backend, "secrets"), lists: 1}
+	job, err := New("test", source, current, WithBatchSize(10), WithCheckpointer(checkpointer))
+	require.NoError(t, err)
+	progress, err := job.Run(ctx)
+	require.Error(t, err)
+	require.Equal(t, int64(10), progress.Rewrapped)
+
+	cursor, err := checkpointer.Load(ctx, "test")
+	require.NoError(t, err)
+	require.Equal(t, "secrets/009", cursor)
+
+	// Resumed run only processes remaining records
+	job, err = New("test", BackendSource(backend, "secrets"), current, WithBatchSize(10), WithCheckpointer(checkpointer))
+	require.NoError(t, err)
+	progress, err = job.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(15), progress.Scanned)
+	require.Equal(t, int64(15), progress.Rewrapped)
+
+	// Checkpoint is reset at the end
+	cursor, err = checkpointer.Load(ctx, "test")
+	require.NoError(t, err)
+	require.Empty(t, cursor)
+}
+
+func TestConflictsAndFailures(t *testing.T) {
+	ctx := context.Background()
+	backend, current := setup(t, 3)
+	require.NoError(t, backend.Set(ctx, "secrets/corrupted", []byte("k1:invalid")))
+
+	job, err := New("test", &conflictSource{Source: BackendSource(backend, "secrets")}, current)
+	require.NoError(t, err)
+	progress, err := job.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &Progress{Scanned: 4, Conflicts: 3, Failed: 1, Cursor: "secrets/corrupted"}, progress)
+
+	// Source detects concurrent writes
+	source := BackendSource(backend, "secrets")
+	records, err := source.List(ctx, "", 1)
+	require.NoError(t, err)
+	require.NoError(t, backend.Set(ctx, records[0].Key, []byte("updated")))
+	require.Equal(t, ErrConflict, source.Update(ctx, records[0], []byte("rewrapped")))
+}
+
+func TestInvalidBatchSize(t *testing.T) {
+	backend, current := setup(t, 0)
+
+	for _, size := range []int{0, -1} {
+		_, err := New("test", BackendSource(backend, "secrets"), current, WithBatchSize(size))
+		require.Equal(t, ErrInvalidBatchSize, err, "Error should be as expected")
+	}
+}
+
+func TestKeystoreSource(t *testing.T) {
+	ctx := context.Background()
+	backend, err := inmemory.New()
+	require.NoError(t, err)
+	old, current := transformers()
+
+	// Keys stored with the old transformer, and a public only key
+	ks, err := keystore.New(backend, keystore.WithPrivateKeys(old))
+	require.NoError(t, err)
+	var keys []key.Key
+	for i := 0; i < 3; i++ {
+		k, err := ks.Generate(ctx, key.Ed25519)
+		require.NoError(t, err)
+		keys = append(keys, k)
+	}
+	require.NoError(t, ks.Add(ctx, keys...))
+	public, err := keystore.New(backend)
+	require.NoError(t, err)
+	k, err := public.Generate(ctx, key.Ed25519)
+	require.NoError(t, err)
+	require.NoError(t, public.Add(ctx, k))
+
+	job, err := New("keystore", KeystoreSource(backend.(backends.AtomicBackend)), current, WithBatchSize(2))
+	require.NoError(t, err)
+	progress, err := job.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), progress.Scanned, "Public only keys should be skipped")
+	require.Equal(t, int64(3), progress.Rewrapped)
+
+	// Private keys are reloaded with the current transformer only
+	var key2 [32]byte
+	copy(key2[:], "fedcba9876543210fedcba9876543210")
+	k2 := value.PrefixTransformer{Prefix: []byte("k2:"), Transformer: secretbox.NewSecretboxTransformer(key2)}
+	reloaded, err := keystore.New(backend, keystore.WithPrivateKeys(value.NewPrefixTransformers(nil, k2)))
+	require.NoError(t, err)
+	require.NoError(t, reloaded.Add(ctx))
+	for _, k := range keys {
+		loaded, err := reloaded.Get(ctx, k.ID())
+		require.NoError(t, err)
+		require.True(t, loaded.HasPrivate(), "Rewrapped private key should be decoded")
+	}
+
+	// Nothing left to do
+	progress, err = job.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), progress.Rewrapped)
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package rewrap
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	recordsScanned = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rewrap",
+		Name:      "records_scanned_total",
+		Help:      "Number of records read by rewrap jobs.",
+	}, []string{"job"})
+
+	recordsRewrapped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rewrap",
+		Name:      "records_rewrapped_total",
+		Help:      "Number of stale records rewritten by rewrap jobs.",
+	}, []string{"job"})
+
+	recordsConflicts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rewrap",
+		Name:      "records_conflicts_total",
+		Help:      "Number of stale records updated concurrently during rewrap.",
+	}, []string{"job"})
+
+	recordsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rewrap",
+		Name:      "records_failed_total",
+		Help:      "Number of records rewrap jobs failed to process.",
+	}, []string{"job"})
+)
+
+func init() {
+	prometheus.MustRegister(recordsScanned, recordsRewrapped, recordsConflicts, recordsFailed)
+}
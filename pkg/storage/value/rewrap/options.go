@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package rewrap
+
+import (
+	"github.com/scraly/go.common/pkg/ratelimit"
+)
+
+// DefaultBatchSize is the default number of records listed at once
+const DefaultBatchSize = 100
+
+// Options contains all values that are needed for rewrap job.
+type Options struct {
+	// BatchSize is the number of records listed at once, the checkpoint is
+	// saved after each batch
+	BatchSize int
+	// Limiter throttles processed records, no limit when nil
+	Limiter ratelimit.Limiter
+	// Checkpointer persists the cursor, the job always starts over when nil
+	Checkpointer Checkpointer
+}
+
+// Option configures the rewrap job.
+type Option func(*Options)
+
+// WithBatchSize sets the number of records listed at once, it must be positive.
+func WithBatchSize(size int) Option {
+	return func(o *Options) {
+		o.BatchSize = size
+	}
+}
+
+// WithLimiter throttles processed records with the limiter.
+func WithLimiter(limiter ratelimit.Limiter) Option {
+	return func(o *Options) {
+		o.Limiter = limiter
+	}
+}
+
+// WithCheckpointer makes the job resumable.
+func WithCheckpointer(checkpointer Checkpointer) Option {
+	return func(o *Options) {
+		o.Checkpointer = checkpointer
+	}
+}
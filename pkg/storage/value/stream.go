@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package value
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// StreamTransformer is the streaming counterpart of Transformer, for values too large to be held in memory.
+type StreamTransformer interface {
+	// ReaderFromStorage returns a reader of the data untransformed from its underlying storage representation.
+	// Stale is true if the object on disk is stale and should be rewritten. Integrity errors may be reported by
+	// the returned reader, the data read before such an error must not be trusted.
+	ReaderFromStorage(r io.Reader, context Context) (out io.Reader, stale bool, err error)
+	// WriterToStorage returns a writer transforming the data into its storage form on w. Close must be called
+	// once all data has been written, it does not close w.
+	WriterToStorage(w io.Writer, context Context) (io.WriteCloser, error)
+}
+
+type identityStreamTransformer struct{}
+
+// IdentityStreamTransformer performs no transformation of the provided stream.
+var IdentityStreamTransformer StreamTransformer = identityStreamTransformer{}
+
+func (identityStreamTransformer) ReaderFromStorage(r io.Reader, context Context) (io.Reader, bool, error) {
+	return r, false, nil
+}
+
+func (identityStreamTransformer) WriterToStorage(w io.Writer, context Context) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// PrefixStreamTransformer holds a stream transformer interface and the prefix that the transformation is located under.
+type PrefixStreamTransformer struct {
+	Prefix      []byte
+	Transformer StreamTransformer
+}
+
+type prefixStreamTransformers struct {
+	transformers []PrefixStreamTransformer
+	maxPrefix    int
+	err          error
+}
+
+var _ StreamTransformer = &prefixStreamTransformers{}
+
+// NewPrefixStreamTransformers selects stream transformers with the same prefix rules as NewPrefixTransformers,
+// so that prefixes can be shared by both forms of a transformation. Unlike NewPrefixTransformers, an error
+// raised by an empty prefix transformer is returned, the consumed stream could not be handed to the next one.
+func NewPrefixStreamTransformers(err error, transformers ...PrefixStreamTransformer) StreamTransformer {
+	if err == nil {
+		err = fmt.Errorf("the provided value does not match any of the supported transformers")
+	}
+	maxPrefix := 0
+	for _, transformer := range transformers {
+		if len(transformer.Prefix) > maxPrefix {
+			maxPrefix = len(transformer.Prefix)
+		}
+	}
+	return &prefixStreamTransformers{
+		transformers: transformers,
+		maxPrefix:    maxPrefix,
+		err:          err,
+	}
+}
+
+// ReaderFromStorage peeks the stream to find the first transformer with a matching prefix, strips the prefix
+// and returns its reader. It will always mark any transformation as stale that is not using the first transformer.
+func (t *prefixStreamTransformers) ReaderFromStorage(r io.Reader, context Context) (io.Reader, bool, error) {
+	br := bufio.NewReaderSize(r, t.maxPrefix)
+
+	// A short stream may still match a shorter prefix
+	head, err := br.Peek(t.maxPrefix)
+	if err != nil && err != io.EOF {
+		return nil, false, err
+	}
+
+	for i, transformer := range t.transformers {
+		if bytes.HasPrefix(head, transformer.Prefix) {
+			if _, err := br.Discard(len(transformer.Prefix)); err != nil {
+				return nil, false, err
+			}
+			result, stale, err := transformer.Transformer.ReaderFromStorage(br, context)
+			return result, stale || i != 0, err
+		}
+	}
+	return nil, false, t.err
+}
+
+// WriterToStorage writes the prefix of the first transformer and returns its writer.
+func (t *prefixStreamTransformers) WriterToStorage(w io.Writer, context Context) (io.WriteCloser, error) {
+	transformer := t.transformers[0]
+	if _, err := w.Write(transformer.Prefix); err != nil {
+		return nil, err
+	}
+	return transformer.Transformer.WriterToStorage(w, context)
+}
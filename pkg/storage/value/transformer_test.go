@@ -13,6 +13,7 @@ package value
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"testing"
 )
 
@@ -93,3 +94,90 @@ func TestPrefixTo(t *testing.T) {
 		}
 	}
 }
+
+func TestPrefixStreamIdentity(t *testing.T) {
+	testErr := fmt.Errorf("test error")
+	p := NewPrefixStreamTransformers(testErr,
+		PrefixStreamTransformer{Prefix: []byte("first:"), Transformer: IdentityStreamTransformer},
+		PrefixStreamTransformer{Prefix: []byte("second:"), Transformer: IdentityStreamTransformer},
+	)
+
+	out := &bytes.Buffer{}
+	w, err := p.WriterToStorage(out, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil || out.String() != "first:value" {
+		t.Fatalf("unexpected out: %q %#v", out.String(), err)
+	}
+
+	testCases := []struct {
+		input  string
+		expect string
+		stale  bool
+		err    error
+	}{
+		{"first:value", "value", false, nil},
+		{"second:value", "value", true, nil},
+		{"second:", "", true, nil},
+		{"third:value", "", false, testErr},
+		{"fir", "", false, testErr},
+	}
+	for i, test := range testCases {
+		r, stale, err := p.ReaderFromStorage(bytes.NewReader([]byte(test.input)), nil)
+		if err != test.err || stale != test.stale {
+			t.Errorf("%d: unexpected out: %t %#v", i, stale, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil || string(got) != test.expect {
+			t.Errorf("%d: unexpected value: %q %#v", i, string(got), err)
+		}
+	}
+}
+
+type appendTransformer struct {
+	suffix byte
+	stale  bool
+}
+
+func (t *appendTransformer) TransformFromStorage(from []byte, context Context) ([]byte, bool, error) {
+	if len(from) == 0 || from[len(from)-1] != t.suffix {
+		return nil, false, fmt.Errorf("missing suffix %c", t.suffix)
+	}
+	return from[:len(from)-1], t.stale, nil
+}
+
+func (t *appendTransformer) TransformToStorage(to []byte, context Context) ([]byte, error) {
+	return append(append([]byte{}, to...), t.suffix), nil
+}
+
+func TestChain(t *testing.T) {
+	transformErr := fmt.Errorf("test error")
+	p := NewChainTransformer(&appendTransformer{suffix: 'a'}, &appendTransformer{suffix: 'b', stale: true})
+
+	out, err := p.TransformToStorage([]byte("value"), nil)
+	if err != nil || string(out) != "valueab" {
+		t.Fatalf("unexpected out: %q %#v", string(out), err)
+	}
+
+	got, stale, err := p.TransformFromStorage(out, nil)
+	if err != nil || !stale || string(got) != "value" {
+		t.Fatalf("unexpected out: %q %t %#v", string(got), stale, err)
+	}
+
+	if _, _, err := p.TransformFromStorage([]byte("valueba"), nil); err == nil {
+		t.Fatalf("expected error on reversed transformations")
+	}
+
+	p = NewChainTransformer(&appendTransformer{suffix: 'a'}, &testTransformer{err: transformErr})
+	if _, err := p.TransformToStorage([]byte("value"), nil); err != transformErr {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+}
@@ -19,3 +19,9 @@ import (
 func SystemCertPool() (*x509.CertPool, error) {
 	return x509.NewCertPool(), nil
 }
+
+// CertPool returns an X.509 certificate pool from `caFile`, the certificate file.
+// The pool only holds the CA file certificates if `exclusivePool` is set.
+func CertPool(caFile string, exclusivePool bool) (*x509.CertPool, error) {
+	return certPool(caFile, exclusivePool)
+}
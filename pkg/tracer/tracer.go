@@ -31,6 +31,15 @@ func SetTracer(initializedTracer opentracing.Tracer) {
 	tracer = initializedTracer
 }
 
+// Tracer returns the tracer instance defined by SetTracer, or the opentracing
+// global tracer if none has been set.
+func Tracer() opentracing.Tracer {
+	if tracer == nil {
+		return opentracing.GlobalTracer()
+	}
+	return tracer
+}
+
 // Sampling Server URL
 var samplingServerURL string
 
@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/scraly/go.common/pkg/ern"
+	"github.com/scraly/go.common/pkg/log"
+	"github.com/scraly/go.common/pkg/ratelimit"
+	"github.com/scraly/go.common/pkg/web/request"
+	"go.uber.org/zap"
+)
+
+// KeyFunc extracts the rate limiting key from the request
+type KeyFunc func(r *http.Request) (string, error)
+
+// RemoteIP uses the request remote IP, taking into account proxy headers, as key
+func RemoteIP(r *http.Request) (string, error) {
+	return fmt.Sprintf("ip:%s", request.RemoteIP(r)), nil
+}
+
+// TenantFromHeader uses the tenant of the ERN given in the header as key
+func TenantFromHeader(header string) KeyFunc {
+	return func(r *http.Request) (string, error) {
+		id, err := ern.Parse(r.Header.Get(header))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("tenant:%s", id.Tenant), nil
+	}
+}
+
+// NewMiddleware is used to limit the request rate per key. Requests are let
+// through when the limiter is unavailable.
+func NewMiddleware(limiter ratelimit.Limiter, keyFunc KeyFunc) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key, err := keyFunc(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			res, err := limiter.Allow(r.Context(), key)
+			if err != nil {
+				log.For(r.Context()).Error("Unable to check request rate", zap.String("key", key), zap.Error(err))
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(res.Limit, 10))
+			w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(res.Remaining, 10))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(seconds(res.ResetAfter.Seconds()), 10))
+
+			if !res.Allowed {
+				w.Header().Set("Retry-After", strconv.FormatInt(seconds(res.RetryAfter.Seconds()), 10))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func seconds(s float64) int64 {
+	return int64(math.Ceil(s))
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) Continental Corporation - All Rights Reserved
+ *
+ * This file is a part of Entry project.
+ * ITS France - Entry squad members
+ *
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/scraly/go.common/pkg/ratelimit"
+	middleware "github.com/scraly/go.common/pkg/web/middleware/ratelimit"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	limiter, err := ratelimit.NewTokenBucket(ratelimit.PerMinute(1))
+	require.NoError(t, err)
+
+	h := middleware.NewMiddleware(limiter, middleware.TenantFromHeader("X-Tenant"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+	call := func(tenant string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Tenant", tenant)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := call("ern:12:account:123")
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = call("ern:12:account:456")
+	require.Equal(t, http.StatusTooManyRequests, w.Code, "Tenant should be limited")
+	require.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	w = call("ern:13:account:123")
+	require.Equal(t, http.StatusNoContent, w.Code, "Tenants should be independent")
+
+	w = call("invalid")
+	require.Equal(t, http.StatusBadRequest, w.Code, "Invalid ERN should be rejected")
+}